@@ -7,3 +7,17 @@ import (
 func getUserSettingCacheKey(userID int32, key string) string {
 	return fmt.Sprintf("%d-%s", userID, key)
 }
+
+func getMemoCacheKey(id int32) string {
+	return fmt.Sprintf("memo-%d", id)
+}
+
+// MemoCacheTag returns the cache tag for entries derived from the memo with the given ID,
+// such as list pages it appears on, so that they are invalidated when the memo changes.
+func MemoCacheTag(id int32) string {
+	return fmt.Sprintf("memo:%d", id)
+}
+
+func getTagCountCacheKey(userID int32) string {
+	return fmt.Sprintf("tags-%d", userID)
+}
@@ -0,0 +1,104 @@
+package cache
+
+import (
+	"sync"
+)
+
+// AdmissionPolicy decides which keys are worth caching once the cache is full.
+// Implementations must be safe for concurrent use.
+type AdmissionPolicy interface {
+	// Record notes an access to key, whether it was a hit, a miss or a write.
+	Record(key string)
+	// Admit reports whether candidate should be stored at the expense of victim,
+	// the key that would be evicted to make room for it.
+	Admit(candidate, victim string) bool
+}
+
+const (
+	// sketchDepth is the number of rows in the count-min sketch.
+	sketchDepth = 4
+	// sketchMaxCount caps each counter; with aging, small counters are enough to rank keys.
+	sketchMaxCount = 15
+)
+
+// TinyLFU is an AdmissionPolicy that admits a new key only if it has been accessed
+// more often than the key it would evict, so that keys requested once, such as
+// scraper traffic, cannot push out genuinely hot ones. Access frequencies are
+// estimated with a count-min sketch that is periodically halved, so that old
+// popularity decays.
+type TinyLFU struct {
+	mu        sync.Mutex
+	counters  [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// NewTinyLFU creates a TinyLFU policy sized for a cache of about capacity keys.
+func NewTinyLFU(capacity int) *TinyLFU {
+	width := 64
+	for width < capacity*4 {
+		width <<= 1
+	}
+	t := &TinyLFU{
+		mask:    uint64(width - 1),
+		resetAt: max(capacity, 1) * 10,
+	}
+	for i := range t.counters {
+		t.counters[i] = make([]uint8, width)
+	}
+	return t
+}
+
+// Record increments the estimated frequency of key, aging every counter
+// once enough accesses have been recorded.
+func (t *TinyLFU) Record(key string) {
+	h := fnv64a(key)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := range t.counters {
+		if counter := &t.counters[i][t.index(h, i)]; *counter < sketchMaxCount {
+			*counter++
+		}
+	}
+	t.additions++
+	if t.additions >= t.resetAt {
+		t.age()
+	}
+}
+
+// Admit reports whether candidate has been accessed more often than victim.
+func (t *TinyLFU) Admit(candidate, victim string) bool {
+	candidateHash, victimHash := fnv64a(candidate), fnv64a(victim)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.estimate(candidateHash) > t.estimate(victimHash)
+}
+
+// estimate returns the smallest counter for h, the count-min frequency estimate.
+// The caller must hold t.mu.
+func (t *TinyLFU) estimate(h uint64) uint8 {
+	estimate := uint8(sketchMaxCount)
+	for i := range t.counters {
+		estimate = min(estimate, t.counters[i][t.index(h, i)])
+	}
+	return estimate
+}
+
+// index returns the column of row i for h, using double hashing over its two halves.
+func (t *TinyLFU) index(h uint64, i int) uint64 {
+	h1, h2 := h&0xffffffff, h>>32
+	return (h1 + uint64(i)*h2) & t.mask
+}
+
+// age halves every counter. The caller must hold t.mu.
+func (t *TinyLFU) age() {
+	for i := range t.counters {
+		for j := range t.counters[i] {
+			t.counters[i][j] >>= 1
+		}
+	}
+	t.additions /= 2
+}
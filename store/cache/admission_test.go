@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+// zipfHitRate replays a Zipfian access pattern, filling the cache on every miss,
+// and returns the fraction of reads that hit.
+func zipfHitRate(cache *Cache) float64 {
+	ctx := context.Background()
+	zipf := rand.NewZipf(rand.New(rand.NewPCG(1, 2)), 1.1, 1, 100_000)
+
+	const accesses = 200_000
+	hits := 0
+	for i := 0; i < accesses; i++ {
+		key := strconv.FormatUint(zipf.Uint64(), 10)
+		if _, ok := cache.Get(ctx, key); ok {
+			hits++
+			continue
+		}
+		cache.Set(ctx, key, key)
+	}
+	return float64(hits) / accesses
+}
+
+func TestTinyLFUImprovesZipfHitRate(t *testing.T) {
+	const capacity = 500
+	lru := NewWithCapacity(capacity, WithShards(1))
+	defer lru.Close()
+	tinyLFU := NewWithCapacity(capacity, WithShards(1), WithAdmissionPolicy(NewTinyLFU(capacity)))
+	defer tinyLFU.Close()
+
+	lruRate, tinyLFURate := zipfHitRate(lru), zipfHitRate(tinyLFU)
+	t.Logf("LRU hit rate %.3f, TinyLFU hit rate %.3f", lruRate, tinyLFURate)
+	if tinyLFURate <= lruRate {
+		t.Errorf("Expected admission to improve the hit rate, LRU %.3f, TinyLFU %.3f", lruRate, tinyLFURate)
+	}
+	if tinyLFU.Size() > capacity {
+		t.Errorf("Expected the capacity to hold, size is %d", tinyLFU.Size())
+	}
+}
+
+func TestTinyLFUAdmit(t *testing.T) {
+	policy := NewTinyLFU(100)
+	for i := 0; i < 5; i++ {
+		policy.Record("hot")
+	}
+	policy.Record("cold")
+
+	if policy.Admit("cold", "hot") {
+		t.Errorf("Expected a cold key not to displace a hot one")
+	}
+	if !policy.Admit("hot", "cold") {
+		t.Errorf("Expected a hot key to displace a cold one")
+	}
+
+	// Aging halves old popularity once enough accesses have been recorded.
+	for i := 0; i < policy.resetAt; i++ {
+		policy.Record("other" + strconv.Itoa(i%10))
+	}
+	if estimate := policy.estimate(fnv64a("hot")); estimate >= 5 {
+		t.Errorf("Expected the hot key's frequency to decay, got %d", estimate)
+	}
+}
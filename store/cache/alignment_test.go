@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// TestAtomic64Alignment guards the 64-bit fields updated with sync/atomic functions,
+// which panic on 32-bit platforms such as ARM unless the field is 64-bit aligned. Only
+// the first word of an allocated struct is guaranteed to be, so every such field must
+// sit in the run of 64-bit fields at the front of its struct; a smaller field added
+// before one would misalign it on 32-bit platforms even though offsets look fine on
+// 64-bit ones. Fields of the atomic.Int64 and atomic.Uint64 types align themselves and
+// need no entry.
+func TestAtomic64Alignment(t *testing.T) {
+	var c Cache
+	var s counters
+	tests := []struct {
+		field  string
+		typ    reflect.Type
+		offset uintptr
+	}{
+		{"itemCount", reflect.TypeFor[Cache](), unsafe.Offsetof(c.itemCount)},
+		{"bytes", reflect.TypeFor[Cache](), unsafe.Offsetof(c.bytes)},
+		{"droppedEvents", reflect.TypeFor[Cache](), unsafe.Offsetof(c.droppedEvents)},
+		{"hits", reflect.TypeFor[counters](), unsafe.Offsetof(s.hits)},
+		{"misses", reflect.TypeFor[counters](), unsafe.Offsetof(s.misses)},
+		{"evictions", reflect.TypeFor[counters](), unsafe.Offsetof(s.evictions)},
+		{"suppressedLogs", reflect.TypeFor[counters](), unsafe.Offsetof(s.suppressedLogs)},
+		{"loaderCalls", reflect.TypeFor[counters](), unsafe.Offsetof(s.loaderCalls)},
+		{"loaderErrors", reflect.TypeFor[counters](), unsafe.Offsetof(s.loaderErrors)},
+		{"loaderNanos", reflect.TypeFor[counters](), unsafe.Offsetof(s.loaderNanos)},
+		{"loaderLatency", reflect.TypeFor[counters](), unsafe.Offsetof(s.loaderLatency)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ.Name()+"."+tt.field, func(t *testing.T) {
+			if tt.offset%8 != 0 {
+				t.Errorf("Expected %s.%s to be 8-byte aligned, offset is %d", tt.typ.Name(), tt.field, tt.offset)
+			}
+			for i := 0; i < tt.typ.NumField(); i++ {
+				field := tt.typ.Field(i)
+				if field.Name == tt.field {
+					return
+				}
+				if !is64BitWords(field.Type) {
+					t.Fatalf("Expected %s.%s to precede %s %s, which may misalign it on 32-bit platforms", tt.typ.Name(), tt.field, field.Name, field.Type)
+				}
+			}
+			t.Fatalf("%s has no field %s", tt.typ.Name(), tt.field)
+		})
+	}
+}
+
+// is64BitWords reports whether t is made of 64-bit integers only, so that a field
+// following it stays 64-bit aligned on every platform.
+func is64BitWords(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int64, reflect.Uint64:
+		return true
+	case reflect.Array:
+		return is64BitWords(t.Elem())
+	}
+	return false
+}
@@ -0,0 +1,475 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AOFSync is the fsync policy of the append-only log; see WithAOF.
+type AOFSync int
+
+const (
+	// AOFSyncEverySec flushes and fsyncs the log once per second, so a crash loses at
+	// most about a second of writes.
+	AOFSyncEverySec AOFSync = iota
+	// AOFSyncAlways fsyncs the log after every record, before the write returns.
+	// It is the safest and by far the slowest policy: the record is encoded, written
+	// and fsynced under the lock of the shard being written, so every write to that
+	// shard waits for the disk, and the lock of the log makes writes to other shards
+	// wait in turn.
+	AOFSyncAlways
+	// AOFSyncNone flushes the log to the operating system once per second and leaves
+	// fsyncing to it.
+	AOFSyncNone
+)
+
+const (
+	// aofFlushInterval is how often the log is flushed, fsynced under AOFSyncEverySec
+	// and checked for rewriting.
+	aofFlushInterval = time.Second
+	// aofRewriteMinSize is the size below which the log is never rewritten automatically.
+	aofRewriteMinSize = 1 << 20
+	// aofQueueLimit is the number of queued records that makes the log drain its queue
+	// before the next flush.
+	aofQueueLimit = 1024
+)
+
+// aofOp is the operation an append-only log record replays.
+type aofOp string
+
+const (
+	aofSet    aofOp = "set"
+	aofDelete aofOp = "del"
+	aofClear  aofOp = "clear"
+)
+
+// aofRecord is one line of the append-only log. A set record carries the entry the
+// same way a snapshot does, with absolute times, so replay keeps remaining TTLs.
+type aofRecord struct {
+	Op aofOp `json:"op"`
+	snapshotEntry
+}
+
+// aofPending is a record in the queue of the log. The value of a set record is kept as
+// stored, and only encoded once the record leaves the queue.
+type aofPending struct {
+	record aofRecord
+	value  any
+}
+
+// aofLog is the append-only log of a cache. Records are queued under the lock of the
+// shard they concern, so the log orders the writes to each key as the cache did, and
+// encoded and written once the queue is drained, outside of shard locks except under
+// AOFSyncAlways.
+type aofLog struct {
+	path   string
+	policy AOFSync
+	codec  Codec
+	// kick asks aofLoop to drain a full queue.
+	kick chan struct{}
+
+	// drainMu lets a single drain run at a time, so that records reach the log in the
+	// order they were queued. It is taken before mu.
+	drainMu sync.Mutex
+
+	// mu guards the fields below. It is taken after shard locks, never before.
+	mu    sync.Mutex
+	queue []aofPending
+	file  *os.File
+	w     *bufio.Writer
+	// size is the length of the log, and base its length after the last rewrite.
+	size int64
+	base int64
+	// rewrite collects the records appended while RewriteAOF runs; nil otherwise.
+	rewrite *bytes.Buffer
+	closed  bool
+}
+
+// openAOF replays the append-only log, if there is one, and starts logging to a
+// compacted copy of it. A read-only cache leaves the log alone: replaying it would
+// write to the cache, and compacting it would empty the log of the writer it belongs to.
+func (c *Cache) openAOF() error {
+	if skip, _ := c.checkWritable(); skip {
+		c.logger.Warn("ignored cache append-only log of a read-only cache", "path", c.config.AOFPath)
+		return nil
+	}
+	codec, err := c.codec()
+	if err != nil {
+		return err
+	}
+	if err := c.replayAOF(codec); err != nil {
+		return err
+	}
+	c.aof = &aofLog{path: c.config.AOFPath, policy: c.config.AOFSync, codec: codec, kick: make(chan struct{}, 1)}
+	if err := c.RewriteAOF(); err != nil {
+		c.aof = nil
+		return err
+	}
+	c.refreshWG.Add(1)
+	go c.aofLoop()
+	return nil
+}
+
+// replayAOF rebuilds the cache from the records of the append-only log. A truncated
+// or corrupted record, as a crash in the middle of a write leaves behind, ends the
+// replay; the records before it are kept. A value that fails to decrypt fails it.
+func (c *Cache) replayAOF(codec Codec) error {
+	f, err := os.Open(c.config.AOFPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to open cache append-only log")
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var record aofRecord
+		if err := decoder.Decode(&record); err != nil {
+			if err != io.EOF {
+				c.logger.Warn("stopped replaying cache append-only log at a bad record", "path", c.config.AOFPath, "err", err)
+			}
+			return nil
+		}
+
+		switch record.Op {
+		case aofClear:
+			c.clear(ctx, false) // Not ClearSilent, which a read-only cache skips
+		case aofDelete:
+			s := c.shardFor(record.Key)
+			s.mu.Lock()
+			if itm, ok := s.items[record.Key]; ok {
+				c.removeLocked(s, itm)
+			}
+			s.mu.Unlock()
+		case aofSet:
+			if err := c.validateKey(record.Key); err != nil {
+				c.logger.Warn("skipped cache entry with invalid key in append-only log", "key", record.Key, "err", err)
+				continue
+			}
+			var value any
+			if err := codec.Unmarshal(record.Value, &value); err != nil {
+				if errors.Is(err, ErrDecrypt) {
+					return errors.Wrapf(err, "failed to replay key %q from cache append-only log", record.Key)
+				}
+				c.logger.Warn("failed to decode cache entry from append-only log", "key", record.Key, "err", err)
+				continue
+			}
+			itm := c.newItem(record.Key, value, c.capExpiration(record.Expiration))
+			itm.staleUntil = record.StaleUntil
+			itm.tags = record.Tags
+			itm.version = record.Version
+			c.insert(itm)
+		}
+	}
+}
+
+// RewriteAOF compacts the append-only log by replacing it with the entries currently
+// live, while writes keep being logged. It runs on its own once the log has doubled in
+// size since the last rewrite; calling it directly forces one. It does nothing without
+// WithAOF or while another rewrite is running.
+func (c *Cache) RewriteAOF() error {
+	a := c.aof
+	if a == nil {
+		return nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(a.path), filepath.Base(a.path)+".rewrite-*")
+	if err != nil {
+		return errors.Wrap(err, "failed to rewrite cache append-only log")
+	}
+	defer func() {
+		// tmp is cleared once it has become the log.
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	a.mu.Lock()
+	if a.closed || a.rewrite != nil {
+		closed := a.closed
+		a.mu.Unlock()
+		if closed {
+			return ErrClosed
+		}
+		return nil
+	}
+	a.rewrite = new(bytes.Buffer)
+	a.mu.Unlock()
+
+	size, err := c.writeAOFRewrite(tmp)
+	// Records queued during the rewrite are drained into pending; the few queued after
+	// this drain go to the rewritten log.
+	c.drainAOF()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	pending := a.rewrite
+	a.rewrite = nil
+	if err != nil {
+		return err
+	}
+	if a.closed {
+		return ErrClosed
+	}
+	// Records logged during the rewrite go after the dump, which they postdate.
+	if _, err := tmp.Write(pending.Bytes()); err != nil {
+		return errors.Wrap(err, "failed to rewrite cache append-only log")
+	}
+	if err := tmp.Sync(); err != nil {
+		return errors.Wrap(err, "failed to rewrite cache append-only log")
+	}
+	if err := os.Rename(tmp.Name(), a.path); err != nil {
+		return errors.Wrap(err, "failed to replace cache append-only log")
+	}
+	syncDir(filepath.Dir(a.path))
+	if a.file != nil {
+		a.w.Flush() // Everything in it is in the rewritten log already
+		a.file.Close()
+	}
+	// The renamed file stays open, positioned at its end.
+	a.file = tmp
+	a.w = bufio.NewWriter(tmp)
+	tmp = nil
+	a.size = size + int64(pending.Len())
+	a.base = a.size
+	return nil
+}
+
+// writeAOFRewrite writes a set record for every live entry to w, one shard at a time,
+// and returns the number of bytes written.
+func (c *Cache) writeAOFRewrite(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var size int64
+	var records []aofPending
+	for _, s := range c.shards {
+		now := c.now()
+		records = records[:0]
+		s.mu.Lock()
+		for _, itm := range s.items {
+			if itm.live(now) && !itm.negative {
+				records = append(records, newAOFSet(itm))
+			}
+		}
+		s.mu.Unlock()
+		for _, record := range records {
+			n, err := bw.Write(c.encodeAOFPending(record))
+			size += int64(n)
+			if err != nil {
+				return 0, errors.Wrap(err, "failed to rewrite cache append-only log")
+			}
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return 0, errors.Wrap(err, "failed to rewrite cache append-only log")
+	}
+	return size, nil
+}
+
+// syncDir fsyncs a directory so that a rename in it survives a crash. Errors are
+// ignored, as not every platform supports it.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// aofLoop flushes the append-only log and rewrites it once it has grown too much, until Close.
+func (c *Cache) aofLoop() {
+	defer c.refreshWG.Done()
+	ticker := time.NewTicker(aofFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.aof.kick:
+			c.drainAOF()
+		case <-ticker.C:
+			c.drainAOF()
+			if c.aof.flush() {
+				if err := c.RewriteAOF(); err != nil {
+					c.logger.Warn("failed to rewrite cache append-only log", "path", c.aof.path, "err", err)
+				}
+			}
+		case <-c.refreshCtx.Done():
+			return
+		}
+	}
+}
+
+// flush writes buffered records to the log, fsyncing it under AOFSyncEverySec, and
+// reports whether the log has grown enough to be rewritten.
+func (a *aofLog) flush() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return false
+	}
+	a.w.Flush()
+	if a.policy == AOFSyncEverySec {
+		a.file.Sync()
+	}
+	return a.rewrite == nil && a.size >= max(aofRewriteMinSize, 2*a.base)
+}
+
+// close flushes, fsyncs and closes the log. Records appended afterwards are dropped.
+func (a *aofLog) close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	a.closed = true
+	if err := a.w.Flush(); err != nil {
+		a.file.Close()
+		return errors.Wrap(err, "failed to flush cache append-only log")
+	}
+	if a.policy != AOFSyncNone {
+		a.file.Sync()
+	}
+	return a.file.Close()
+}
+
+// enqueue queues a record and reports whether the queue is due to be drained.
+func (a *aofLog) enqueue(record aofPending) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return false
+	}
+	a.queue = append(a.queue, record)
+	return len(a.queue) >= aofQueueLimit
+}
+
+// append adds encoded records to the log.
+func (a *aofLog) append(line []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil
+	}
+	n, err := a.w.Write(line)
+	a.size += int64(n)
+	if a.rewrite != nil {
+		a.rewrite.Write(line)
+	}
+	if err == nil && a.policy == AOFSyncAlways {
+		if err = a.w.Flush(); err == nil {
+			err = a.file.Sync()
+		}
+	}
+	return err
+}
+
+// newAOFSet returns a set record for itm, whose value is encoded when it is drained.
+func newAOFSet(itm *item) aofPending {
+	return aofPending{value: itm.value, record: aofRecord{Op: aofSet, snapshotEntry: snapshotEntry{
+		Key:        itm.key,
+		Expiration: itm.expiration,
+		StaleUntil: itm.staleUntil,
+		Tags:       itm.tags,
+		Version:    itm.version,
+	}}}
+}
+
+// encodeAOFPending encodes a queued record as one line of the log. A value the codec
+// cannot encode is recorded as a delete, so that replay does not bring back an older value.
+func (c *Cache) encodeAOFPending(pending aofPending) []byte {
+	if pending.record.Op == aofSet {
+		value, err := c.aof.codec.Marshal(c.decompress(pending.value))
+		if err != nil {
+			c.logger.Warn("failed to encode cache entry for append-only log", "key", pending.record.Key, "err", err)
+			return encodeAOFRecord(aofRecord{Op: aofDelete, snapshotEntry: snapshotEntry{Key: pending.record.Key}})
+		}
+		pending.record.Value = value
+	}
+	return encodeAOFRecord(pending.record)
+}
+
+// encodeAOFRecord encodes a record as one line of the log.
+func encodeAOFRecord(record aofRecord) []byte {
+	line, _ := json.Marshal(record) // Only the value can fail, and it is encoded already
+	return append(line, '\n')
+}
+
+// logSet records that itm was stored. Negative entries are not persisted, so storing
+// one is recorded as deleting the key. The caller must hold the lock of the item's shard.
+func (c *Cache) logSet(itm *item) {
+	if c.aof == nil {
+		return
+	}
+	if itm.negative {
+		c.logDelete(itm.key)
+		return
+	}
+	c.logAOF(newAOFSet(itm))
+}
+
+// logDelete records that key was removed. The caller must hold the lock of its shard.
+func (c *Cache) logDelete(key string) {
+	if c.aof == nil {
+		return
+	}
+	c.logAOF(aofPending{record: aofRecord{Op: aofDelete, snapshotEntry: snapshotEntry{Key: key}}})
+}
+
+// logClear records that the cache was emptied. Clear calls it holding every shard
+// lock, which orders the record against every other write.
+func (c *Cache) logClear() {
+	if c.aof == nil {
+		return
+	}
+	c.logAOF(aofPending{record: aofRecord{Op: aofClear}})
+}
+
+// logAOF queues a record for the log. Under AOFSyncAlways it drains the queue, so that
+// the record is synced before the write returns; otherwise aofLoop drains it.
+func (c *Cache) logAOF(record aofPending) {
+	full := c.aof.enqueue(record)
+	if c.aof.policy == AOFSyncAlways {
+		c.drainAOF()
+		return
+	}
+	if full {
+		select {
+		case c.aof.kick <- struct{}{}:
+		default: // A drain is pending already
+		}
+	}
+}
+
+// drainAOF encodes the queued records and appends them to the log, logging write
+// failures. Writes keep being queued while it encodes.
+func (c *Cache) drainAOF() {
+	a := c.aof
+	a.drainMu.Lock()
+	defer a.drainMu.Unlock()
+
+	a.mu.Lock()
+	queue := a.queue
+	a.queue = nil
+	a.mu.Unlock()
+	if len(queue) == 0 {
+		return
+	}
+
+	var lines bytes.Buffer
+	for _, record := range queue {
+		lines.Write(c.encodeAOFPending(record))
+	}
+	if err := a.append(lines.Bytes()); err != nil {
+		c.logger.Warn("failed to write cache append-only log", "path", a.path, "err", err)
+	}
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCacheAOFRecovery(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	path := filepath.Join(t.TempDir(), "cache.aof")
+
+	cache := NewDefault(WithClock(clock.Now), WithAOF(path), WithAOFSync(AOFSyncAlways))
+	cache.SetWithTTL(ctx, "memo:1", "first", time.Hour)
+	cache.SetWithTTL(ctx, "memo:2", "second", 10*time.Minute)
+	cache.Set(ctx, "memo:3", "forever")
+	cache.SetWithTTL(ctx, "memo:4", "short", time.Minute)
+	cache.Delete(ctx, "memo:3")
+	cache.SetWithTTL(ctx, "memo:1", "updated", time.Hour)
+	cache.Touch(ctx, "memo:2", 30*time.Minute)
+	cache.SetWithTags(ctx, "memo:5", "tagged", "user:1")
+	clock.Advance(2 * time.Minute)
+
+	// Simulate a crash: open a new cache on the log without closing the old one.
+	recovered := NewDefault(WithClock(clock.Now), WithAOF(path))
+	defer recovered.Close()
+	defer cache.Close()
+
+	if v, ttl, ok := recovered.GetWithTTL(ctx, "memo:1"); !ok || v != "updated" || ttl != 58*time.Minute {
+		t.Errorf("Expected memo:1 to be recovered with 58m left, got %v, %v, %v", v, ttl, ok)
+	}
+	if v, ttl, ok := recovered.GetWithTTL(ctx, "memo:2"); !ok || v != "second" || ttl != 28*time.Minute {
+		t.Errorf("Expected the Touch of memo:2 to be recovered with 28m left, got %v, %v, %v", v, ttl, ok)
+	}
+	if _, ok := recovered.Get(ctx, "memo:3"); ok {
+		t.Errorf("Expected the deleted memo:3 to stay deleted")
+	}
+	if _, ok := recovered.Get(ctx, "memo:4"); ok {
+		t.Errorf("Expected memo:4 to have expired during the downtime")
+	}
+	if recovered.Size() != 3 {
+		t.Errorf("Expected 3 recovered entries, got %d", recovered.Size())
+	}
+	if n := recovered.InvalidateTag(ctx, "user:1"); n != 1 {
+		t.Errorf("Expected the tags of memo:5 to be recovered, invalidated %d entries", n)
+	}
+}
+
+func TestCacheAOFRewrite(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.aof")
+
+	cache := NewDefault(WithAOF(path), WithAOFSync(AOFSyncAlways))
+	for i := 0; i < 100; i++ {
+		cache.Set(ctx, "counter", fmt.Sprintf("value %d", i))
+	}
+	cache.Set(ctx, "other", "kept")
+	cache.Clear(ctx)
+	cache.Set(ctx, "after", "clear")
+	before, _ := os.Stat(path)
+
+	if err := cache.RewriteAOF(); err != nil {
+		t.Fatalf("RewriteAOF failed: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() {
+		t.Errorf("Expected the rewrite to compact the log, %d bytes before and %d after", before.Size(), after.Size())
+	}
+	cache.Set(ctx, "written", "after rewrite")
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restarted := NewDefault(WithAOF(path))
+	defer restarted.Close()
+	if restarted.Size() != 2 {
+		t.Errorf("Expected 2 entries after restart, got %d", restarted.Size())
+	}
+	for key, want := range map[string]string{"after": "clear", "written": "after rewrite"} {
+		if v, ok := restarted.Get(ctx, key); !ok || v != want {
+			t.Errorf("Expected %s to be %q, got %v, %v", key, want, v, ok)
+		}
+	}
+}
+
+func TestCacheAOFQueuedRecords(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "cache.aof")
+
+	// Enough writes to fill the queue several times, racing on the same keys.
+	cache := NewDefault(WithAOF(path), WithAOFSync(AOFSyncEverySec))
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < aofQueueLimit; i++ {
+				key := fmt.Sprintf("memo:%d", i%100)
+				if i%7 == 0 {
+					cache.Delete(ctx, key)
+				} else {
+					cache.Set(ctx, key, fmt.Sprintf("writer %d, write %d", w, i))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	want := make(map[string]any)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("memo:%d", i)
+		if v, ok := cache.Get(ctx, key); ok {
+			want[key] = v
+		}
+	}
+	// Close drains the records not yet written.
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	restarted := NewDefault(WithAOF(path))
+	defer restarted.Close()
+	if restarted.Size() != int64(len(want)) {
+		t.Errorf("Expected %d entries after restart, got %d", len(want), restarted.Size())
+	}
+	for key, value := range want {
+		if v, ok := restarted.Get(ctx, key); !ok || v != value {
+			t.Errorf("Expected %s to replay as %v, got %v, %v", key, value, v, ok)
+		}
+	}
+}
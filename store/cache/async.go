@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+)
+
+// Result is the outcome of a load started by GetOrSetAsync.
+type Result struct {
+	Value any
+	Err   error
+}
+
+// GetOrSetAsync is like GetOrSet, but does not wait for the loader. On a hit it returns
+// the cached value with ok set and a nil channel. On a miss it starts loading key in
+// the background, sharing the load with concurrent GetOrSet calls for the key, and
+// returns a channel that delivers the result once and is then closed, so that the caller
+// can wait for it or render a placeholder and move on; the value is cached either way.
+// The load waits for a slot under MaxConcurrentLoads and stops when ctx is done, so
+// pass a context detached with context.WithoutCancel to let it outlive a request.
+// Errors that prevent the load from starting, such as ErrClosed, are delivered on the channel too.
+func (c *Cache) GetOrSetAsync(ctx context.Context, key string, loader func(context.Context) (any, error)) (value any, ok bool, pending <-chan Result) {
+	if value, ok := c.Get(ctx, key); ok {
+		return value, true, nil
+	}
+	result := make(chan Result, 1)
+	if err := ctx.Err(); err != nil {
+		result <- Result{Err: err}
+		close(result)
+		return nil, false, result
+	}
+	// Claim the load now, so that callers after this one share it.
+	cl, owner, err := c.claimLoad(ctx, key)
+	if err != nil {
+		result <- Result{Err: err}
+		close(result)
+		return nil, false, result
+	}
+	go func() {
+		defer close(result)
+		var r Result
+		if owner {
+			r.Value, r.Err = c.runLoad(ctx, key, c.config.DefaultTTL, cl, loader)
+		} else {
+			r.Value, r.Err = c.awaitLoad(ctx, cl)
+		}
+		result <- r
+	}()
+	return nil, false, result
+}
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheGetOrSetAsync(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	release := make(chan struct{})
+	calls := 0
+	loader := func(context.Context) (any, error) {
+		calls++
+		<-release
+		return "loaded", nil
+	}
+
+	value, ok, pending := cache.GetOrSetAsync(ctx, "key", loader)
+	if ok || value != nil || pending == nil {
+		t.Fatalf("Expected an immediate miss with a pending result, got %v, %v, %v", value, ok, pending)
+	}
+	if _, ok := cache.Get(ctx, "key"); ok {
+		t.Fatalf("Expected the key to be missing while the load runs")
+	}
+
+	// A concurrent GetOrSet shares the load.
+	shared := make(chan any)
+	go func() {
+		value, _ := cache.GetOrSet(ctx, "key", loader)
+		shared <- value
+	}()
+	close(release)
+
+	select {
+	case result := <-pending:
+		if result.Err != nil || result.Value != "loaded" {
+			t.Errorf("Expected loaded, got %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the load")
+	}
+	if _, open := <-pending; open {
+		t.Errorf("Expected the channel to be closed after the result")
+	}
+	if value := <-shared; value != "loaded" || calls != 1 {
+		t.Errorf("Expected one shared load, got %v after %d calls", value, calls)
+	}
+
+	value, ok, pending = cache.GetOrSetAsync(ctx, "key", loader)
+	if !ok || value != "loaded" || pending != nil {
+		t.Errorf("Expected an immediate hit, got %v, %v, %v", value, ok, pending)
+	}
+}
+
+func TestCacheGetOrSetAsyncRespectsLoadLimit(t *testing.T) {
+	cache := NewDefault(WithMaxConcurrentLoads(1))
+	defer cache.Close()
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	_, _, busy := cache.GetOrSetAsync(context.Background(), "busy", func(context.Context) (any, error) {
+		close(started)
+		<-release
+		return "busy", nil
+	})
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, _, pending := cache.GetOrSetAsync(ctx, "key", func(context.Context) (any, error) {
+		t.Errorf("Expected the loader not to run without a free slot")
+		return nil, nil
+	})
+	cancel()
+	if result := <-pending; !errors.Is(result.Err, context.Canceled) {
+		t.Errorf("Expected the canceled load to fail with context.Canceled, got %+v", result)
+	}
+	if _, ok := cache.Get(context.Background(), "key"); ok {
+		t.Errorf("Expected the canceled load to cache nothing")
+	}
+	release <- struct{}{}
+	if result := <-busy; result.Value != "busy" {
+		t.Errorf("Expected busy, got %+v", result)
+	}
+}
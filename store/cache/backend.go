@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Backend is the error-reporting contract shared by the in-memory Cache and
+// network-backed caches such as RedisCache. Unlike the Cache convenience methods,
+// every call can fail, so a backend outage is never mistaken for a miss.
+// Every call must return once its context is done; wrap network backends in a
+// TimeoutBackend so that callers without a deadline still get bounded latency.
+type Backend interface {
+	// Fetch retrieves a value. A miss is reported as (nil, false, nil).
+	Fetch(ctx context.Context, key string) (any, bool, error)
+
+	// Put stores a value. A non-positive TTL stores the value without expiration.
+	Put(ctx context.Context, key string, value any, ttl time.Duration) error
+
+	// Remove deletes a value. Removing a missing key is not an error.
+	Remove(ctx context.Context, key string) error
+
+	// FetchMulti retrieves several values. The returned map only contains the keys that were present.
+	FetchMulti(ctx context.Context, keys []string) (map[string]any, error)
+
+	// PutMulti stores several values with the same TTL.
+	PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error
+
+	// RemoveMulti deletes several values.
+	RemoveMulti(ctx context.Context, keys []string) error
+
+	// Close releases the resources held by the backend.
+	Close() error
+}
+
+var _ Backend = (*Cache)(nil)
+
+// Fetch retrieves a value from the cache.
+func (c *Cache) Fetch(ctx context.Context, key string) (any, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	value, ok := c.Get(ctx, key)
+	return value, ok, nil
+}
+
+// Put adds a value to the cache with a custom TTL.
+func (c *Cache) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return c.SetWithTTL(ctx, key, value, ttl)
+}
+
+// Remove removes a value from the cache.
+func (c *Cache) Remove(ctx context.Context, key string) error {
+	return c.Delete(ctx, key)
+}
+
+// FetchMulti retrieves several values from the cache.
+func (c *Cache) FetchMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.GetMulti(ctx, keys), nil
+}
+
+// PutMulti adds several values to the cache with a custom TTL.
+func (c *Cache) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	withTTL := make(map[string]ValueWithTTL, len(items))
+	for key, value := range items {
+		withTTL[key] = ValueWithTTL{Value: value, TTL: ttl}
+	}
+	return c.SetMultiWithTTL(ctx, withTTL)
+}
+
+// RemoveMulti removes several values from the cache.
+func (c *Cache) RemoveMulti(ctx context.Context, keys []string) error {
+	return c.DeleteMulti(ctx, keys)
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheAsBackend(t *testing.T) {
+	ctx := context.Background()
+	var backend Backend = NewDefault()
+	defer backend.Close()
+
+	if err := backend.Put(ctx, "key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if val, ok, err := backend.Fetch(ctx, "key1"); err != nil || !ok || val != "value1" {
+		t.Errorf("Expected 'value1', got %v, exists: %v, err: %v", val, ok, err)
+	}
+
+	if err := backend.PutMulti(ctx, map[string]any{"key2": 2, "key3": 3}, 0); err != nil {
+		t.Fatalf("PutMulti failed: %v", err)
+	}
+	values, err := backend.FetchMulti(ctx, []string{"key1", "key2", "key3", "missing"})
+	if err != nil || len(values) != 3 {
+		t.Errorf("Unexpected FetchMulti result %v, err: %v", values, err)
+	}
+
+	if err := backend.RemoveMulti(ctx, []string{"key1", "key2"}); err != nil {
+		t.Fatalf("RemoveMulti failed: %v", err)
+	}
+	if err := backend.Remove(ctx, "key3"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok, err := backend.Fetch(ctx, "key3"); ok || err != nil {
+		t.Errorf("Expected miss after Remove, exists: %v, err: %v", ok, err)
+	}
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// GetMulti retrieves several values at once, taking each shard lock at most once.
+// The returned map only contains the keys that were present.
+// If ctx is already done, GetMulti reports every key as missing.
+func (c *Cache) GetMulti(ctx context.Context, keys []string) map[string]any {
+	result := make(map[string]any, len(keys))
+	if ctx.Err() != nil {
+		return result
+	}
+	now := c.now()
+
+	var evicted []evictedItem
+	var missed []string
+	for i, group := range c.groupByShard(keys) {
+		if len(group) == 0 {
+			continue
+		}
+		s := c.shards[i]
+		s.mu.Lock()
+		for _, key := range group {
+			var value any
+			var ok bool
+			value, ok, evicted = c.getLocked(s, key, now, evicted)
+			if ok {
+				result[key] = value
+			} else if c.config.OnMiss != nil {
+				missed = append(missed, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+	for key, value := range result {
+		result[key] = c.readValue(value)
+	}
+
+	c.notifyEvicted(evicted)
+	for _, key := range missed {
+		c.notifyMiss(key)
+	}
+	return result
+}
+
+// SetMulti adds several values with the default TTL, taking each shard lock at most once.
+func (c *Cache) SetMulti(ctx context.Context, items map[string]any) error {
+	return c.PutMulti(ctx, items, c.config.DefaultTTL)
+}
+
+// ValueWithTTL pairs a value with its own TTL for SetMultiWithTTL.
+type ValueWithTTL struct {
+	Value any
+	// TTL is the time-to-live of the value; a non-positive TTL means no expiration.
+	TTL time.Duration
+}
+
+// SetMultiWithTTL adds several values, each with its own TTL, taking each shard lock
+// at most once. If any key is rejected by the key validator, or any value is larger
+// than MaxValueBytes, nothing is written.
+func (c *Cache) SetMultiWithTTL(ctx context.Context, items map[string]ValueWithTTL) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	for key := range items {
+		if err := c.validateKey(key); err != nil {
+			return err
+		}
+	}
+	built := make(map[string]*item, len(items))
+	for key, entry := range items {
+		itm := c.newItem(key, entry.Value, c.expiresAt(entry.TTL))
+		if err := c.checkValueSize(itm); err != nil {
+			return err
+		}
+		built[key] = itm
+	}
+
+	groups := make([][]string, len(c.shards))
+	for key := range items {
+		i := c.shardIndex(key)
+		groups[i] = append(groups[i], key)
+	}
+
+	var evicted []evictedItem
+	for i, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		s := c.shards[i]
+		s.mu.Lock()
+		for _, key := range group {
+			evicted = c.setLocked(s, built[key], evicted)
+		}
+		s.mu.Unlock()
+		evicted = c.evictOverflow(s, evicted)
+	}
+
+	c.notifyEvicted(evicted)
+	return nil
+}
+
+// DeleteMulti removes several values, taking each shard lock at most once.
+func (c *Cache) DeleteMulti(ctx context.Context, keys []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+
+	var evicted []evictedItem
+	for i, group := range c.groupByShard(keys) {
+		if len(group) == 0 {
+			continue
+		}
+		s := c.shards[i]
+		s.mu.Lock()
+		for _, key := range group {
+			evicted = c.deleteLocked(s, key, evicted)
+		}
+		s.mu.Unlock()
+	}
+
+	c.notifyEvicted(evicted)
+	return nil
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheBatchOperations(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.Set(ctx, "key1", "old")
+	cache.SetMulti(ctx, map[string]any{
+		"key1": "value1",
+		"key2": "value2",
+		"key3": "value3",
+	})
+	if cache.Size() != 3 {
+		t.Errorf("Expected size 3 after overwrite, got %d", cache.Size())
+	}
+
+	got := cache.GetMulti(ctx, []string{"key1", "key2", "missing"})
+	if len(got) != 2 || got["key1"] != "value1" || got["key2"] != "value2" {
+		t.Errorf("Unexpected GetMulti result %v", got)
+	}
+	if _, ok := got["missing"]; ok {
+		t.Errorf("GetMulti should omit missing keys")
+	}
+
+	cache.DeleteMulti(ctx, []string{"key1", "key3", "missing"})
+	if cache.Size() != 1 {
+		t.Errorf("Expected size 1 after DeleteMulti, got %d", cache.Size())
+	}
+	if _, ok := cache.Get(ctx, "key2"); !ok {
+		t.Errorf("Key 'key2' should survive DeleteMulti")
+	}
+}
+
+func TestCacheSetMultiWithTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.Set(ctx, "short", "old")
+	err := cache.SetMultiWithTTL(ctx, map[string]ValueWithTTL{
+		"short":   {Value: "a", TTL: time.Second},
+		"long":    {Value: "b", TTL: time.Hour},
+		"forever": {Value: "c"},
+	})
+	if err != nil {
+		t.Fatalf("SetMultiWithTTL failed: %v", err)
+	}
+	if cache.Size() != 3 || cache.Stats().ItemCount != 3 {
+		t.Errorf("Expected 3 items after overwrite, got %d", cache.Size())
+	}
+
+	for key, want := range map[string]time.Duration{"short": time.Second, "long": time.Hour, "forever": NoExpiration} {
+		if _, ttl, ok := cache.GetWithTTL(ctx, key); !ok || ttl != want {
+			t.Errorf("Expected %s to have TTL %v, got %v (exists: %v)", key, want, ttl, ok)
+		}
+	}
+
+	clock.Advance(time.Minute)
+	if _, ok := cache.Get(ctx, "short"); ok {
+		t.Errorf("Key 'short' should have expired")
+	}
+	if got := cache.GetMulti(ctx, []string{"long", "forever"}); len(got) != 2 {
+		t.Errorf("Expected 'long' and 'forever' to survive, got %v", got)
+	}
+}
+
+func benchmarkKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%d", i)
+	}
+	return keys
+}
+
+func BenchmarkGetLoop(b *testing.B) {
+	ctx := context.Background()
+	cache := NewWithCapacity(0)
+	defer cache.Close()
+	keys := benchmarkKeys(1000)
+	for _, key := range keys {
+		cache.Set(ctx, key, key)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, key := range keys {
+			cache.Get(ctx, key)
+		}
+	}
+}
+
+func BenchmarkGetMulti(b *testing.B) {
+	ctx := context.Background()
+	cache := NewWithCapacity(0)
+	defer cache.Close()
+	keys := benchmarkKeys(1000)
+	for _, key := range keys {
+		cache.Set(ctx, key, key)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.GetMulti(ctx, keys)
+	}
+}
+
+func BenchmarkSetWithTTLLoop(b *testing.B) {
+	ctx := context.Background()
+	cache := NewWithCapacity(0)
+	defer cache.Close()
+	keys := benchmarkKeys(1000)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j, key := range keys {
+			cache.SetWithTTL(ctx, key, key, time.Duration(j+1)*time.Second)
+		}
+	}
+}
+
+func BenchmarkSetMultiWithTTL(b *testing.B) {
+	ctx := context.Background()
+	cache := NewWithCapacity(0)
+	defer cache.Close()
+	keys := benchmarkKeys(1000)
+	items := make(map[string]ValueWithTTL, len(keys))
+	for j, key := range keys {
+		items[key] = ValueWithTTL{Value: key, TTL: time.Duration(j+1) * time.Second}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.SetMultiWithTTL(ctx, items)
+	}
+}
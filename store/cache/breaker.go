@@ -0,0 +1,261 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every call through to the backend.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects every call with ErrCircuitOpen.
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe call through to decide whether to close again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerConfig contains options for configuring a CircuitBreaker.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker.
+	// Zero means 5.
+	FailureThreshold int
+
+	// Window, if positive, only counts failures as consecutive if each follows the
+	// previous within Window, so sporadic errors never open the breaker.
+	Window time.Duration
+
+	// Cooldown is how long the breaker stays open before letting a probe through.
+	// Zero means 30 seconds.
+	Cooldown time.Duration
+
+	// Timeout, if positive, bounds every backend call; a call that runs out of time
+	// counts as a failure.
+	Timeout time.Duration
+
+	// Clock returns the current time. Nil means time.Now.
+	Clock func() time.Time
+}
+
+// BreakerStats is a point-in-time view of a CircuitBreaker.
+type BreakerStats struct {
+	// State is the current state of the breaker.
+	State BreakerState
+	// ConsecutiveFailures is the length of the current run of failures.
+	ConsecutiveFailures int
+	// Opens is the number of times the breaker has opened, including after a failed probe.
+	Opens int64
+	// Rejected is the number of calls rejected with ErrCircuitOpen.
+	Rejected int64
+}
+
+// CircuitBreaker is a Backend that stops calling a failing backend, such as a
+// RedisCache, so that an outage fails fast instead of piling up timeouts.
+// After FailureThreshold consecutive failures it opens and rejects calls with
+// ErrCircuitOpen; after Cooldown it lets one probe through and closes again if
+// the probe succeeds. A miss is not a failure, and neither is a call whose own
+// context was canceled. TieredCache treats ErrCircuitOpen from its second tier
+// as a miss on reads and skips the second tier on writes.
+type CircuitBreaker struct {
+	backend Backend
+	config  BreakerConfig
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	// lastFailure is when the last failure was recorded, for Window.
+	lastFailure time.Time
+	// openedAt is when the breaker last opened, for Cooldown.
+	openedAt time.Time
+	probing  bool
+	opens    int64
+	rejected int64
+}
+
+var _ Backend = (*CircuitBreaker)(nil)
+
+// NewCircuitBreaker wraps backend with a circuit breaker.
+func NewCircuitBreaker(backend Backend, config BreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	return &CircuitBreaker{backend: backend, config: config}
+}
+
+// Stats returns a snapshot of the breaker state and counters.
+func (b *CircuitBreaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return BreakerStats{
+		State:               b.currentStateLocked(),
+		ConsecutiveFailures: b.failures,
+		Opens:               b.opens,
+		Rejected:            b.rejected,
+	}
+}
+
+// Fetch retrieves a value from the backend.
+func (b *CircuitBreaker) Fetch(ctx context.Context, key string) (value any, ok bool, err error) {
+	err = b.do(ctx, func(ctx context.Context) error {
+		value, ok, err = b.backend.Fetch(ctx, key)
+		return err
+	})
+	return value, ok, err
+}
+
+// Put stores a value in the backend.
+func (b *CircuitBreaker) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return b.do(ctx, func(ctx context.Context) error {
+		return b.backend.Put(ctx, key, value, ttl)
+	})
+}
+
+// Remove deletes a value from the backend.
+func (b *CircuitBreaker) Remove(ctx context.Context, key string) error {
+	return b.do(ctx, func(ctx context.Context) error {
+		return b.backend.Remove(ctx, key)
+	})
+}
+
+// FetchMulti retrieves several values from the backend.
+func (b *CircuitBreaker) FetchMulti(ctx context.Context, keys []string) (values map[string]any, err error) {
+	err = b.do(ctx, func(ctx context.Context) error {
+		values, err = b.backend.FetchMulti(ctx, keys)
+		return err
+	})
+	return values, err
+}
+
+// PutMulti stores several values in the backend.
+func (b *CircuitBreaker) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	return b.do(ctx, func(ctx context.Context) error {
+		return b.backend.PutMulti(ctx, items, ttl)
+	})
+}
+
+// RemoveMulti deletes several values from the backend.
+func (b *CircuitBreaker) RemoveMulti(ctx context.Context, keys []string) error {
+	return b.do(ctx, func(ctx context.Context) error {
+		return b.backend.RemoveMulti(ctx, keys)
+	})
+}
+
+// Close closes the backend, whatever the state of the breaker.
+func (b *CircuitBreaker) Close() error {
+	return b.backend.Close()
+}
+
+// do runs call if the breaker allows it and records the outcome.
+func (b *CircuitBreaker) do(ctx context.Context, call func(context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !b.allow() {
+		return ErrCircuitOpen
+	}
+	callCtx := ctx
+	if b.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, b.config.Timeout)
+		defer cancel()
+	}
+	err := call(callCtx)
+	if err != nil && ctx.Err() != nil {
+		// The caller giving up says nothing about the health of the backend.
+		b.abandon()
+	} else {
+		b.record(err == nil)
+	}
+	return err
+}
+
+// allow reports whether a call may reach the backend, claiming the probe
+// if the breaker is half-open.
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.currentStateLocked() {
+	case BreakerClosed:
+		return true
+	case BreakerHalfOpen:
+		if !b.probing {
+			b.probing = true
+			b.state = BreakerHalfOpen
+			return true
+		}
+	}
+	b.rejected++
+	return false
+}
+
+// record updates the breaker with the outcome of a call.
+func (b *CircuitBreaker) record(ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if ok {
+		b.state = BreakerClosed
+		b.failures = 0
+		return
+	}
+
+	now := b.now()
+	if b.config.Window > 0 && b.failures > 0 && now.Sub(b.lastFailure) > b.config.Window {
+		b.failures = 0
+	}
+	b.failures++
+	b.lastFailure = now
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		if b.state != BreakerOpen {
+			b.opens++
+			b.openedAt = now
+		}
+		b.state = BreakerOpen
+	}
+}
+
+// abandon frees the probe of a call that its caller gave up on, leaving the
+// breaker open so that the next call probes again.
+func (b *CircuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.probing {
+		b.probing = false
+		b.state = BreakerOpen
+	}
+}
+
+// currentStateLocked returns the state, reporting an open breaker whose cooldown
+// has passed as half-open. The caller must hold b.mu.
+func (b *CircuitBreaker) currentStateLocked() BreakerState {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.config.Cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+func (b *CircuitBreaker) now() time.Time {
+	if b.config.Clock != nil {
+		return b.config.Clock()
+	}
+	return time.Now()
+}
@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyBackend is a Backend that fails every call while down.
+type flakyBackend struct {
+	Backend
+	down  atomic.Bool
+	calls atomic.Int64
+}
+
+var errBackendDown = errors.New("connection refused")
+
+func (f *flakyBackend) Fetch(ctx context.Context, key string) (any, bool, error) {
+	f.calls.Add(1)
+	if f.down.Load() {
+		return nil, false, errBackendDown
+	}
+	return f.Backend.Fetch(ctx, key)
+}
+
+func (f *flakyBackend) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	f.calls.Add(1)
+	if f.down.Load() {
+		return errBackendDown
+	}
+	return f.Backend.Put(ctx, key, value, ttl)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	l2 := &flakyBackend{Backend: NewDefault()}
+	breaker := NewCircuitBreaker(l2, BreakerConfig{FailureThreshold: 3, Cooldown: time.Minute, Clock: clock.Now})
+	tiered := NewTiered(NewDefault(), breaker, TieredConfig{})
+	defer tiered.Close()
+
+	// While closed, failures reach the caller.
+	l2.down.Store(true)
+	for i := 0; i < 3; i++ {
+		if _, _, err := tiered.Fetch(ctx, "memo:1"); !errors.Is(err, errBackendDown) {
+			t.Fatalf("Expected the backend error, got %v", err)
+		}
+	}
+	if stats := breaker.Stats(); stats.State != BreakerOpen || stats.Opens != 1 {
+		t.Fatalf("Expected the breaker to open after 3 failures, got %+v", stats)
+	}
+
+	// While open, L2 is bypassed: reads miss and writes only reach L1.
+	calls := l2.calls.Load()
+	if _, ok, err := tiered.Fetch(ctx, "memo:1"); ok || err != nil {
+		t.Errorf("Expected an L1-only miss, got exists: %v, err: %v", ok, err)
+	}
+	if err := tiered.Put(ctx, "memo:2", "value", 0); err != nil {
+		t.Errorf("Expected Put to skip L2, got %v", err)
+	}
+	if val, ok, _ := tiered.Fetch(ctx, "memo:2"); !ok || val != "value" {
+		t.Errorf("Expected the write to reach L1, got %v", val)
+	}
+	if l2.calls.Load() != calls {
+		t.Errorf("Expected no calls to reach L2 while open, got %d", l2.calls.Load()-calls)
+	}
+	if breaker.Stats().Rejected != 2 {
+		t.Errorf("Expected 2 rejected calls, got %d", breaker.Stats().Rejected)
+	}
+
+	// A failed probe reopens the breaker for another cooldown.
+	clock.Advance(time.Minute)
+	if breaker.Stats().State != BreakerHalfOpen {
+		t.Fatalf("Expected the breaker to be half-open after the cooldown, got %v", breaker.Stats().State)
+	}
+	breaker.Fetch(ctx, "memo:1")
+	if stats := breaker.Stats(); stats.State != BreakerOpen || stats.Opens != 2 {
+		t.Fatalf("Expected a failed probe to reopen the breaker, got %+v", stats)
+	}
+
+	// A successful probe closes it again.
+	l2.down.Store(false)
+	clock.Advance(time.Minute)
+	if err := tiered.Put(ctx, "memo:3", "value", 0); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if stats := breaker.Stats(); stats.State != BreakerClosed || stats.ConsecutiveFailures != 0 {
+		t.Errorf("Expected a successful probe to close the breaker, got %+v", stats)
+	}
+	if _, ok, _ := l2.Backend.Fetch(ctx, "memo:3"); !ok {
+		t.Errorf("Expected writes to reach L2 once closed")
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	backend := &flakyBackend{Backend: NewDefault()}
+	backend.down.Store(true)
+	breaker := NewCircuitBreaker(backend, BreakerConfig{FailureThreshold: 2, Window: time.Second, Clock: clock.Now})
+	defer breaker.Close()
+
+	// Sporadic failures further apart than the window never open the breaker.
+	for i := 0; i < 5; i++ {
+		breaker.Fetch(ctx, "key")
+		clock.Advance(2 * time.Second)
+	}
+	if state := breaker.Stats().State; state != BreakerClosed {
+		t.Errorf("Expected sporadic failures to keep the breaker closed, got %v", state)
+	}
+
+	breaker.Fetch(ctx, "key")
+	breaker.Fetch(ctx, "key")
+	if state := breaker.Stats().State; state != BreakerOpen {
+		t.Errorf("Expected failures within the window to open the breaker, got %v", state)
+	}
+}
+
+func TestCircuitBreakerTimeout(t *testing.T) {
+	backend := &slowBackend{Backend: NewDefault()}
+	breaker := NewCircuitBreaker(backend, BreakerConfig{FailureThreshold: 1, Timeout: 5 * time.Millisecond})
+	defer breaker.Close()
+
+	if _, _, err := breaker.Fetch(context.Background(), "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the call to time out, got %v", err)
+	}
+	if state := breaker.Stats().State; state != BreakerOpen {
+		t.Errorf("Expected a timeout to count as a failure, got %v", state)
+	}
+
+	// A caller giving up does not count against the backend.
+	breaker = NewCircuitBreaker(backend, BreakerConfig{FailureThreshold: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	breaker.Fetch(ctx, "key")
+	if state := breaker.Stats().State; state != BreakerClosed {
+		t.Errorf("Expected a canceled caller not to open the breaker, got %v", state)
+	}
+}
+
+// slowBackend is a Backend whose Fetch, Put and Remove block until their context is done.
+type slowBackend struct {
+	Backend
+}
+
+func (s *slowBackend) Fetch(ctx context.Context, _ string) (any, bool, error) {
+	<-ctx.Done()
+	return nil, false, ctx.Err()
+}
+
+func (s *slowBackend) Put(ctx context.Context, _ string, _ any, _ time.Duration) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *slowBackend) Remove(ctx context.Context, _ string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *slowBackend) RemoveMulti(ctx context.Context, _ []string) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// Invalidation is a message on an InvalidationBus telling the nodes of a cluster to
+// drop their first-tier copies of some keys.
+type Invalidation struct {
+	// Keys are the invalidated keys.
+	Keys []string `json:"keys"`
+	// Node is the NodeID of the TieredCache that published the message, so that it
+	// can ignore its own messages.
+	Node string `json:"node"`
+}
+
+// InvalidationBus carries invalidations between the TieredCaches of a cluster that
+// share a second tier. Delivery may be at least once and in any order: applying an
+// invalidation only drops local copies, so a duplicate or late one costs at most an
+// extra read of the second tier.
+type InvalidationBus interface {
+	// Publish sends msg to every subscriber, including the publisher's own.
+	Publish(ctx context.Context, msg Invalidation) error
+
+	// Subscribe calls handle with every message published from now on until the
+	// returned function is called. handle must not block for long.
+	Subscribe(ctx context.Context, handle func(Invalidation)) (unsubscribe func(), err error)
+}
+
+// RedisBus is an InvalidationBus over a Redis pub/sub channel. Messages published
+// while a subscriber is reconnecting are lost to it, so keep L1TTL short enough to
+// bound the staleness that can cause.
+type RedisBus struct {
+	client  *redis.Client
+	channel string
+	logger  *slog.Logger
+}
+
+var _ InvalidationBus = (*RedisBus)(nil)
+
+// InvalidationBus returns a RedisBus that publishes on channel through the connection
+// pool of r. Every node of a cluster must use the same channel.
+func (r *RedisCache) InvalidationBus(channel string) *RedisBus {
+	return &RedisBus{client: r.client, channel: channel, logger: r.logger}
+}
+
+// Publish sends msg on the channel.
+func (b *RedisBus) Publish(ctx context.Context, msg Invalidation) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode invalidation")
+	}
+	if err := b.client.Publish(ctx, b.channel, data).Err(); err != nil {
+		return redisError(err, "failed to publish invalidation on redis channel %q", b.channel)
+	}
+	return nil
+}
+
+// Subscribe subscribes to the channel and calls handle from a single goroutine with
+// every message received, skipping messages it cannot decode. It returns once Redis
+// has confirmed the subscription.
+func (b *RedisBus) Subscribe(ctx context.Context, handle func(Invalidation)) (func(), error) {
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, redisError(err, "failed to subscribe to redis channel %q", b.channel)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for message := range pubsub.Channel() {
+			var msg Invalidation
+			if err := json.Unmarshal([]byte(message.Payload), &msg); err != nil {
+				b.logger.Warn("failed to decode invalidation", "channel", b.channel, "err", err)
+				continue
+			}
+			handle(msg)
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			pubsub.Close() // Closes the channel, ending the goroutine
+			wg.Wait()
+		})
+	}, nil
+}
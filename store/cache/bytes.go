@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"time"
+)
+
+// SetBytes stores an already serialized value, such as a pre-rendered response, with
+// the given TTL like SetWithTTL. The slice is copied, so the caller may reuse it.
+// The entry counts its length against MaxBytes and is compressed as usual above
+// CompressThreshold.
+func (c *Cache) SetBytes(ctx context.Context, key string, b []byte, ttl time.Duration) error {
+	return c.SetWithTTL(ctx, key, bytes.Clone(b), ttl)
+}
+
+// GetBytes retrieves a value stored by SetBytes, or any other []byte value. It reports
+// false if the key is missing or holds a value of another type. The returned slice is
+// shared with the cache and must not be modified.
+func (c *Cache) GetBytes(ctx context.Context, key string) ([]byte, bool) {
+	value, ok := c.Get(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	b, ok := value.([]byte)
+	return b, ok
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheSetBytes(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	rendered := []byte(`{"memos":[{"id":1,"content":"hello"}]}`)
+	if err := cache.SetBytes(ctx, "render:memos", rendered, time.Minute); err != nil {
+		t.Fatalf("SetBytes failed: %v", err)
+	}
+	if b, ok := cache.GetBytes(ctx, "render:memos"); !ok || !bytes.Equal(b, rendered) {
+		t.Errorf("Expected the bytes to round-trip unchanged, got %q, %v", b, ok)
+	}
+
+	// Reusing the input buffer does not change the stored value.
+	want := bytes.Clone(rendered)
+	copy(rendered, "XXXX")
+	if b, _ := cache.GetBytes(ctx, "render:memos"); !bytes.Equal(b, want) {
+		t.Errorf("Expected the stored bytes to survive mutation of the input, got %q", b)
+	}
+
+	if got := cache.Stats().Bytes; got < int64(len(want)) {
+		t.Errorf("Expected the bytes to count against the byte total, got %d", got)
+	}
+	cache.Set(ctx, "other", "not bytes")
+	if _, ok := cache.GetBytes(ctx, "other"); ok {
+		t.Errorf("Expected GetBytes to report false for a value that is not []byte")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, ok := cache.GetBytes(ctx, "render:memos"); ok {
+		t.Errorf("Expected the bytes to expire with their TTL")
+	}
+}
@@ -2,29 +2,35 @@ package cache
 
 import (
 	"context"
+	"log/slog"
+	"math/rand/v2"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/pkg/errors"
 )
 
 // Interface defines the operations a cache must support.
 type Interface interface {
 	// Set adds a value to the cache with the default TTL.
-	Set(ctx context.Context, key string, value any)
+	Set(ctx context.Context, key string, value any) error
 
 	// SetWithTTL adds a value to the cache with a custom TTL.
-	SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration)
+	// A non-positive TTL stores the value without expiration.
+	SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error
 
 	// Get retrieves a value from the cache.
 	Get(ctx context.Context, key string) (any, bool)
 
 	// Delete removes a value from the cache.
-	Delete(ctx context.Context, key string)
+	Delete(ctx context.Context, key string) error
 
 	// Clear removes all values from the cache.
-	Clear(ctx context.Context)
+	Clear(ctx context.Context) error
 
-	// Size returns the number of items in the cache.
+	// Size returns the number of live items in the cache.
 	Size() int64
 
 	// Close stops all background tasks and releases resources.
@@ -33,9 +39,110 @@ type Interface interface {
 
 // item represents a cached value with metadata.
 type item struct {
+	key        string
 	value      any
-	expiration time.Time
-	size       int // Approximate size in bytes
+	expiration time.Time     // Zero means the item never expires
+	staleUntil time.Time     // End of the grace window after expiration; zero means no grace
+	ttl        time.Duration // Lifetime granted at the last write or Touch; see SlidingExpiration
+	size       int64         // Approximate size in bytes
+	tags       []string      // Tags for group invalidation; see SetWithTags
+	tenant     string        // Tenant charged for the item; see TenantOf
+	negative   bool          // Records a known miss; see SetNotFound
+	err        error         // Loader error cached by GetOrSetWithErrorTTL; set only on negative items
+	gen        *generation   // Generation the item was stored in; see BumpGeneration
+	version    uint64        // Version set by SetWithVersion; zero for unversioned writes
+
+	// createdAt, lastAccess and accessCount are diagnostics reported by Inspect;
+	// they play no part in eviction. createdAt also bounds sliding expiration under MaxTTL.
+	createdAt   time.Time
+	lastAccess  time.Time
+	accessCount int64
+
+	// heapPos is the 1-based position of the item in its shard's expiry heap,
+	// or zero if it is not in one; see SweepExpiryHeap.
+	heapPos int
+
+	// prev and next link the item into the LRU list.
+	prev *item
+	next *item
+}
+
+// expired reports whether the item is past its expiration at the given time,
+// or belongs to a retired generation.
+func (i *item) expired(now time.Time) bool {
+	return (!i.expiration.IsZero() && now.After(i.expiration)) || i.retired()
+}
+
+// setExpiration moves the expiration of the item, keeping the length of its grace
+// window. The caller must hold the shard lock and keep the shard's volatile count.
+func (i *item) setExpiration(expiration time.Time) {
+	if !i.staleUntil.IsZero() {
+		if expiration.IsZero() {
+			i.staleUntil = time.Time{}
+		} else {
+			i.staleUntil = expiration.Add(i.staleUntil.Sub(i.expiration))
+		}
+	}
+	i.expiration = expiration
+}
+
+// recordAccess notes that the item was read at now. The caller must hold the shard lock.
+func (i *item) recordAccess(now time.Time) {
+	i.lastAccess = now
+	i.accessCount++
+}
+
+// live reports whether the item holds a real value that has not expired.
+func (i *item) live(now time.Time) bool {
+	return !i.negative && !i.expired(now)
+}
+
+// volatile reports whether the item can stop being live while it is stored.
+func (i *item) volatile() bool {
+	return i.negative || !i.expiration.IsZero()
+}
+
+// dead reports whether the item is past both its expiration and its grace window,
+// so that it can no longer be served at all. Items of a retired generation are dead
+// whatever their grace window.
+func (i *item) dead(now time.Time) bool {
+	return i.retired() || (i.expired(now) && (i.staleUntil.IsZero() || now.After(i.staleUntil)))
+}
+
+// EvictReason describes why an item left the cache.
+type EvictReason int
+
+const (
+	// EvictReasonDeleted means the item was removed explicitly.
+	EvictReasonDeleted EvictReason = iota
+	// EvictReasonExpired means the item outlived its TTL.
+	EvictReasonExpired
+	// EvictReasonCapacity means the item was evicted to make room for others.
+	EvictReasonCapacity
+	// EvictReasonCleared means the whole cache was cleared.
+	EvictReasonCleared
+)
+
+// String returns the name of the eviction reason.
+func (r EvictReason) String() string {
+	switch r {
+	case EvictReasonDeleted:
+		return "deleted"
+	case EvictReasonExpired:
+		return "expired"
+	case EvictReasonCapacity:
+		return "capacity"
+	case EvictReasonCleared:
+		return "cleared"
+	}
+	return "unknown"
+}
+
+// evictedItem is a removed key/value pair whose callback runs after the lock is released.
+type evictedItem struct {
+	key    string
+	value  any
+	reason EvictReason
 }
 
 // Config contains options for configuring a cache.
@@ -44,13 +151,224 @@ type Config struct {
 	DefaultTTL time.Duration
 
 	// CleanupInterval is how often the cache runs cleanup.
+	// A non-positive interval disables the background janitor, unless
+	// SweepMaxInterval is set.
 	CleanupInterval time.Duration
 
+	// SweepMinInterval and SweepMaxInterval, if SweepMaxInterval is positive, make the
+	// janitor interval adaptive: it starts at CleanupInterval, halves after a sweep that
+	// finds many expired items and doubles after one that finds almost none, within these
+	// bounds, and each delay is jittered by up to 10% so that many cache instances do not
+	// sweep in lockstep. Zero SweepMinInterval means SweepMaxInterval/16.
+	SweepMinInterval time.Duration
+	SweepMaxInterval time.Duration
+
+	// SweepStrategy selects how the janitor finds expired items.
+	// The zero value is SweepFullScan.
+	SweepStrategy SweepStrategy
+
 	// MaxItems is the maximum number of items allowed in the cache.
 	MaxItems int
 
+	// MaxBytes is the maximum approximate total size of values allowed in the cache.
+	// With a Weigher it bounds the total weight instead. Zero disables the byte budget.
+	MaxBytes int64
+
+	// MaxValueBytes, if positive, rejects any entry whose size as counted for MaxBytes
+	// exceeds it, before admission and eviction run, so that one huge value cannot
+	// flush the cache. Writes that report errors return ErrValueTooLarge.
+	MaxValueBytes int64
+
+	// MaxKeysPerTag, if positive, caps the number of entries carrying any one tag, and
+	// MaxTagsPerEntry the number of tags one entry carries, so that a caller tagging
+	// too much cannot turn the tag index into an unbounded map and InvalidateTag into
+	// a huge scan. SetWithTags returns ErrTagLimit for an entry over either cap and
+	// leaves the cache unchanged. Expired entries hold their tags until they are
+	// removed, lazily or by the janitor. See WithTagLimits.
+	MaxKeysPerTag   int
+	MaxTagsPerEntry int
+
+	// ReadOnly turns writes into no-ops while reads keep working, for a replica that
+	// must never change a cache it shares with the primary; see WithReadOnly.
+	ReadOnly bool
+
+	// ReadOnlyPolicy is what writes do while ReadOnly is set. The default drops them silently.
+	ReadOnlyPolicy ReadOnlyPolicy
+
+	// EvictionBatch, if positive, bounds the entries a single write evicts for capacity,
+	// so that a large write does not pay for one long burst of evictions. This lets the
+	// cache exceed its limits for a while: later writes and the janitor evict the rest
+	// in batches of the same size until it is back within them.
+	EvictionBatch int
+
+	// Weigher, if set, assigns each entry a cost that replaces its estimated size,
+	// so MaxBytes and Stats.Bytes count total weight. Eviction stays least recently
+	// used first until the entries fit the budget.
+	Weigher func(key string, value any) int64
+
+	// Shards is the number of independently locked partitions of the key space.
+	// It is rounded up to a power of two, so that a key's shard is picked with a
+	// bit mask. A non-positive count picks a default derived from GOMAXPROCS and MaxItems.
+	Shards int
+
+	// NegativeTTL is how long a LoadingCache remembers that its loader returned
+	// ErrNotFound for a key. Zero disables negative caching, so every lookup
+	// of a missing key reaches the loader.
+	NegativeTTL time.Duration
+
+	// StaleIfError turns the TTL of loaded values into a soft TTL and keeps them for
+	// StaleIfError longer, up to a hard TTL. Past the soft TTL a LoadingCache reloads
+	// the value, and if the loader fails it serves the stale value instead of the
+	// error; past the hard TTL the error is returned. Zero disables serving stale values.
+	StaleIfError time.Duration
+
+	// Generation is the number of the generation new entries are written in; see
+	// BumpGeneration. It only labels the generation, since entries are not persisted
+	// across processes, and defaults to zero.
+	Generation int
+
+	// CopyOnGet makes reads hand out copies of the stored values made by Copy,
+	// so that callers mutating them cannot corrupt the cache or each other; see WithCopyOnGet.
+	CopyOnGet bool
+
+	// Copy copies values for CopyOnGet. Nil means DeepCopy.
+	Copy func(value any) any
+
+	// MaxTTL caps the TTL of every entry, including entries stored without one,
+	// as a hard ceiling on staleness. Zero means no cap.
+	MaxTTL time.Duration
+
+	// TTLJitter randomizes each entry's TTL by up to ±TTLJitter of its nominal value,
+	// so that entries written together do not all expire together.
+	// Zero disables jitter.
+	TTLJitter float64
+
+	// CompressThreshold is the length above which []byte values are stored
+	// gzip-compressed and transparently decompressed on read.
+	// Zero disables compression.
+	CompressThreshold int
+
+	// Admission, if set, decides whether a new key may evict another one
+	// once the cache is full. Nil admits every key, which is plain LRU.
+	Admission AdmissionPolicy
+
+	// TenantOf, if set, assigns every key to a tenant, such as the workspace in its
+	// prefix, and makes eviction fair between tenants: the cache evicts from the tenant
+	// most over its fair share first, rather than the globally least recently used
+	// entry, so that one busy tenant cannot flush the entries of quiet ones.
+	// Shares are counted in items, or in bytes for a cache bounded by MaxBytes only.
+	// It is called under a shard lock on every write, so it must be cheap and must not
+	// call back into the cache.
+	TenantOf func(key string) string
+
+	// TenantWeights weighs the fair share of each tenant; a tenant's share of the cache
+	// is its weight over the total weight of the tenants holding entries. Tenants not
+	// listed weigh 1.
+	TenantWeights map[string]float64
+
+	// KeyValidator, if set, vets the key of every write. A write whose key it
+	// rejects returns its error and leaves the cache unchanged.
+	KeyValidator func(key string) error
+
+	// HighWaterMark is the fraction of MaxItems or MaxBytes at which OnHighWaterMark
+	// is called, as an early warning before the cache starts evicting.
+	HighWaterMark float64
+
+	// OnHighWaterMark is called with the current usage and the limit when usage
+	// rises to HighWaterMark of either limit. It fires again only after usage has
+	// dropped back below the mark. It runs outside the cache lock.
+	OnHighWaterMark func(current, max int64)
+
+	// SlidingExpiration makes every read that returns a value restart its TTL, so
+	// entries in use stay cached while idle ones expire. MaxTTL, if set, still bounds
+	// how long after its last write an entry may live.
+	SlidingExpiration bool
+
+	// MaxConcurrentLoads caps the number of loader calls running at once across
+	// the cache; callers over the limit wait for a free slot or their context.
+	// Zero means no limit.
+	MaxConcurrentLoads int
+
+	// DisablePanicRecovery lets panics in user-supplied functions, such as loaders,
+	// callbacks, key validators, Sizers and Weighers, propagate instead of being recovered.
+	// By default a panic becomes an error where the function returns one,
+	// and is logged otherwise.
+	DisablePanicRecovery bool
+
+	// DebugChecks enables Verify and makes the janitor run it after every sweep,
+	// logging any inconsistency it finds as an error. It costs nothing while
+	// disabled; see WithDebugChecks.
+	DebugChecks bool
+
+	// EvictionLogRate caps the eviction debug logs written per second; the rest are
+	// counted in Stats.SuppressedEvictionLogs and summarized in a single line.
+	// Zero means 100 per second and a negative rate lifts the cap.
+	EvictionLogRate int
+
+	// MemoryHighWater, if positive, makes a background routine shed least recently used
+	// entries whenever MemoryUsage reports more than MemoryHighWater bytes, trading hit
+	// rate for stability on small instances.
+	MemoryHighWater uint64
+
+	// MemoryLowWater is the usage the memory-pressure routine sheds entries towards.
+	// Zero means 90% of MemoryHighWater.
+	MemoryLowWater uint64
+
+	// MemoryCheckInterval is how often MemoryUsage is polled. Zero means every second.
+	MemoryCheckInterval time.Duration
+
+	// MemoryUsage reports the memory in use in bytes. Nil means the live heap
+	// as of the last garbage collection, read from runtime/metrics.
+	MemoryUsage func() uint64
+
+	// Logger receives debug logs for evictions and warnings for recoverable errors,
+	// such as a panicking eviction callback. Nil discards all logs.
+	Logger *slog.Logger
+
+	// Clock returns the current time for expiry decisions. Nil means time.Now;
+	// tests can inject a fake clock to control expiry without sleeping.
+	Clock func() time.Time
+
+	// RandSource, if set, is the source of the randomized decisions of the cache, such
+	// as TTL jitter and the shards a sampled sweep starts from, so that tests can make
+	// them reproducible. Nil means the randomly seeded global source of math/rand/v2.
+	// See WithRandSource.
+	RandSource rand.Source
+
+	// Codec serializes values for SaveSnapshot, LoadSnapshot and the append-only log.
+	// Nil means JSONCodec.
+	Codec Codec
+
+	// AOFPath, if set, is the path of an append-only log that records every write,
+	// so that a restarted cache replays it to recover its entries, even after a crash.
+	// See WithAOF.
+	AOFPath string
+
+	// AOFSync is the fsync policy of the append-only log. The zero value is AOFSyncEverySec.
+	AOFSync AOFSync
+
+	// EncryptionKey, if set, encrypts snapshot values with AES-GCM on top of Codec;
+	// see EncryptedCodec. Entries kept in memory are never encrypted.
+	EncryptionKey []byte
+
+	// Hasher maps keys to shards by the low bits of the hash, masked to the shard count.
+	// Nil means the built-in FNV-1a hash.
+	Hasher func(key string) uint64
+
+	// Warmup, if set, preloads hot keys when Warm is called.
+	Warmup func(ctx context.Context, c *Cache) error
+
+	// OnMiss, if set, is called with the key of every read that misses, including reads
+	// of expired entries. It is purely observational and runs outside the cache lock,
+	// so it should be cheap, for example sampling keys for an anomaly detector.
+	OnMiss func(key string)
+
 	// OnEviction is called when an item is evicted from the cache.
 	OnEviction func(key string, value any)
+
+	// OnEvict is called with the reason whenever an item leaves the cache.
+	// Like OnEviction, it runs outside the cache lock, so it may call back into the cache.
+	OnEvict func(key string, value any, reason EvictReason)
 }
 
 // DefaultConfig returns a default configuration for the cache.
@@ -63,264 +381,744 @@ func DefaultConfig() Config {
 	}
 }
 
+// Stats is a point-in-time view of the cache counters.
+type Stats struct {
+	// Hits is the number of Get calls that found a live value,
+	// plus Lookup calls that found a negative entry.
+	Hits int64
+	// Misses is the number of Get calls that found nothing or an expired value.
+	Misses int64
+	// Evictions is the number of items removed by TTL expiry or capacity pressure.
+	Evictions int64
+	// ItemCount is the number of items Get would currently return.
+	ItemCount int64
+	// Bytes is the approximate total size of the stored values, or their total
+	// weight with a Weigher, including expired ones that have not been swept yet.
+	Bytes int64
+	// SuppressedEvictionLogs is the number of eviction debug logs dropped by EvictionLogRate.
+	SuppressedEvictionLogs int64
+}
+
 // Cache is a thread-safe in-memory cache with TTL and memory management.
+// Keys are spread over independently locked shards to reduce contention,
+// while the item and byte limits apply to the cache as a whole.
 type Cache struct {
-	data       sync.Map
+	// 64-bit atomic counters are kept at the front of the struct so they stay
+	// 8-byte aligned on 32-bit platforms.
+	itemCount int64 // Use atomic operations to track item count
+	bytes     int64
+	// droppedEvents counts events not delivered to slow subscribers.
+	droppedEvents int64
+
+	// counters holds the resettable statistics; see ResetStats.
+	counters atomic.Pointer[counters]
+
+	// aboveItemMark and aboveByteMark are set while usage is above the high-water mark
+	// of the item and byte limit respectively, so that the callback fires once per crossing.
+	aboveItemMark int32
+	aboveByteMark int32
+	// closed is set by Close; see ErrClosed.
+	closed int32
+	// disabled is set while the cache passes requests through; see SetEnabled.
+	disabled int32
+	// retiring counts the generation bumps whose items the janitor has not yet swept.
+	retiring int32
+
+	// generation is the generation new items are stored in; see BumpGeneration.
+	generation atomic.Pointer[generation]
+
+	// aof is the append-only log, or nil without WithAOF.
+	aof *aofLog
+
+	// tenants tracks the usage of each tenant for fair eviction; nil without TenantOf.
+	tenants *tenantUsage
+
+	// tagKeys counts the keys carrying each tag across shards; nil without MaxKeysPerTag.
+	tagKeys *tagCounts
+
+	// rand makes the randomized decisions of the cache; see RandSource.
+	rand *rand.Rand
+
+	shards []*shard
+	// shardMask selects a shard from a key hash; the shard count is a power of two.
+	shardMask uint64
+	// overflowCursor rotates the shard that overflow eviction starts from.
+	overflowCursor uint32
+
+	// loadMu guards loads, the in-flight GetOrSet calls by key,
+	// and the registration of refresh-ahead goroutines.
+	loadMu sync.Mutex
+	loads  map[string]*call
+	// loadSlots is a semaphore bounding concurrent loader calls; nil means unbounded.
+	loadSlots chan struct{}
+
+	// events fans cache operations out to subscribers; see Subscribe.
+	events eventBus
+
+	// evictionLogs rate-limits eviction debug logs; see EvictionLogRate.
+	evictionLogs logLimiter
+
+	// refreshCtx is canceled by Close to stop the refresh-ahead and memory-pressure
+	// goroutines, which refreshWG tracks.
+	refreshCtx  context.Context
+	stopRefresh context.CancelFunc
+	refreshWG   sync.WaitGroup
+
 	config     Config
-	itemCount  int64 // Use atomic operations to track item count
+	logger     *slog.Logger
 	stopChan   chan struct{}
 	closedChan chan struct{}
+	closeOnce  sync.Once
 }
 
-// New creates a new memory cache with the given configuration.
-func New(config Config) *Cache {
+// New creates a new memory cache with the given configuration and options.
+func New(config Config, opts ...Option) *Cache {
+	for _, opt := range opts {
+		opt(&config)
+	}
+	shardCount := config.Shards
+	if shardCount <= 0 {
+		shardCount = defaultShardCount(config)
+	}
+	shardCount = nextPowerOfTwo(shardCount)
+	refreshCtx, stopRefresh := context.WithCancel(context.Background())
 	c := &Cache{
-		config:     config,
-		stopChan:   make(chan struct{}),
-		closedChan: make(chan struct{}),
+		shards:      make([]*shard, shardCount),
+		shardMask:   uint64(shardCount - 1),
+		events:      eventBus{subscribers: make(map[chan CacheEvent]struct{})},
+		refreshCtx:  refreshCtx,
+		stopRefresh: stopRefresh,
+		loads:       make(map[string]*call),
+		config:      config,
+		logger:      config.Logger,
+		stopChan:    make(chan struct{}),
+		closedChan:  make(chan struct{}),
+	}
+	c.rand = newRand(config.RandSource)
+	c.counters.Store(new(counters))
+	c.generation.Store(&generation{n: int64(config.Generation)})
+	if config.TenantOf != nil {
+		c.tenants = &tenantUsage{usage: make(map[string]int64)}
+	}
+	if config.MaxKeysPerTag > 0 {
+		c.tagKeys = &tagCounts{keys: make(map[string]int)}
+	}
+	if config.MaxConcurrentLoads > 0 {
+		c.loadSlots = make(chan struct{}, config.MaxConcurrentLoads)
+	}
+	if c.logger == nil {
+		c.logger = slog.New(slog.DiscardHandler)
+	}
+	for i := range c.shards {
+		c.shards[i] = newShard()
+		if config.SweepStrategy == SweepExpiryHeap {
+			c.shards[i].expiry = &expiryHeap{}
+		}
 	}
 
+	if config.AOFPath != "" {
+		if err := c.openAOF(); err != nil {
+			c.logger.Error("failed to open cache append-only log, continuing without it", "path", config.AOFPath, "err", err)
+		}
+	}
+	if config.MemoryHighWater > 0 {
+		c.refreshWG.Add(1)
+		go c.memoryPressureLoop()
+	}
 	go c.cleanupLoop()
 	return c
 }
 
-// NewDefault creates a new memory cache with default configuration.
-func NewDefault() *Cache {
-	return New(DefaultConfig())
+// NewDefault creates a new memory cache with default configuration and the given options.
+func NewDefault(opts ...Option) *Cache {
+	return New(DefaultConfig(), opts...)
+}
+
+// NewWithCapacity creates a new memory cache that holds at most maxItems items,
+// evicting the least recently used item once the limit is exceeded.
+func NewWithCapacity(maxItems int, opts ...Option) *Cache {
+	config := DefaultConfig()
+	config.MaxItems = maxItems
+	return New(config, opts...)
+}
+
+// NewWithMaxBytes creates a new memory cache whose values total at most maxBytes,
+// evicting the least recently used items once the budget is exceeded.
+// Sizes are approximate; see Sizer.
+func NewWithMaxBytes(maxBytes int64, opts ...Option) *Cache {
+	config := DefaultConfig()
+	config.MaxItems = 0
+	config.MaxBytes = maxBytes
+	return New(config, opts...)
 }
 
 // Set adds a value to the cache with the default TTL.
-func (c *Cache) Set(ctx context.Context, key string, value any) {
-	c.SetWithTTL(ctx, key, value, c.config.DefaultTTL)
+// A nil value is stored like any other, so a later Get returns (nil, true), unlike the
+// (nil, false) of a miss; use Delete to remove a key.
+func (c *Cache) Set(ctx context.Context, key string, value any) error {
+	return c.SetWithTTL(ctx, key, value, c.config.DefaultTTL)
 }
 
 // SetWithTTL adds a value to the cache with a custom TTL.
-func (c *Cache) SetWithTTL(_ context.Context, key string, value any, ttl time.Duration) {
-	// Estimate size of the item (very rough approximation).
-	size := estimateSize(value)
+// A non-positive TTL stores the value without expiration.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	if err := c.validateKey(key); err != nil {
+		return err
+	}
 
-	// Check if item already exists to avoid double counting.
-	if _, exists := c.data.Load(key); exists {
-		c.data.Delete(key)
-	} else {
-		// Only increment if this is a new key.
-		atomic.AddInt64(&c.itemCount, 1)
+	itm := c.newItem(key, value, c.expiresAt(ttl))
+	if err := c.checkValueSize(itm); err != nil {
+		return err
 	}
+	c.insert(itm)
+	return nil
+}
 
-	c.data.Store(key, item{
-		value:      value,
-		expiration: time.Now().Add(ttl),
-		size:       size,
-	})
+// SetWithDeadline adds a value to the cache that expires at deadline, such as a token
+// valid until a server-provided timestamp, sparing callers the deadline.Sub(now) dance.
+// No jitter is applied, but MaxTTL still caps the deadline. A deadline that is not in the
+// future stores nothing and deletes any value key held, since it would already be expired.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) SetWithDeadline(ctx context.Context, key string, value any, deadline time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	if err := c.validateKey(key); err != nil {
+		return err
+	}
+	if !deadline.After(c.now()) {
+		return c.Delete(ctx, key)
+	}
+
+	itm := c.newItem(key, value, c.capExpiration(deadline))
+	if err := c.checkValueSize(itm); err != nil {
+		return err
+	}
+	c.insert(itm)
+	return nil
+}
+
+// SetIfAbsent adds a value with the default TTL only if key holds no live value,
+// and reports whether it did. Unlike GetOrSet it takes a value the caller already has,
+// and it never overwrites a value stored concurrently by someone else.
+// If ctx is already done or key is rejected by the key validator,
+// the cache is left unchanged and false is returned.
+func (c *Cache) SetIfAbsent(ctx context.Context, key string, value any) bool {
+	if ctx.Err() != nil || c.checkOpen() != nil || c.validateKey(key) != nil {
+		return false
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return false
+	}
+	itm := c.newItem(key, value, c.expiresAt(c.config.DefaultTTL))
+	if c.checkValueSize(itm) != nil {
+		return false
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	if existing, ok := s.items[key]; ok && existing.live(c.now()) {
+		s.mu.Unlock()
+		return false
+	}
+	evicted := c.setLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
+	return true
+}
+
+// ReplaceIfPresent updates the value of key only if it holds a live value, and reports
+// whether it did. The value keeps its TTL, grace window and tags, so a background job can
+// refresh a key that is still in use without resurrecting one that was evicted.
+// If ctx is already done, the cache is left unchanged and false is returned.
+func (c *Cache) ReplaceIfPresent(ctx context.Context, key string, value any) bool {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return false
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return false
+	}
+	itm := c.newItem(key, value, time.Time{})
+	if c.checkValueSize(itm) != nil {
+		return false
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	existing, ok := s.items[key]
+	if !ok || !existing.live(c.now()) {
+		s.mu.Unlock()
+		return false
+	}
+	itm.expiration, itm.staleUntil, itm.ttl, itm.tags = existing.expiration, existing.staleUntil, existing.ttl, existing.tags
+	evicted := c.setLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
+	return true
+}
+
+// CompareAndSwap replaces the value of key with newValue only if key holds a live value
+// equal to oldValue according to eq, and reports whether it did. A nil eq compares with
+// reflect.DeepEqual. The value keeps its TTL, grace window and tags. Callers can retry
+// in a loop of Get and CompareAndSwap to update a value without losing concurrent updates.
+// eq runs under the shard lock, so it must not call back into the cache.
+// If ctx is already done, the cache is left unchanged and false is returned.
+func (c *Cache) CompareAndSwap(ctx context.Context, key string, oldValue, newValue any, eq func(a, b any) bool) bool {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return false
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return false
+	}
+	if eq == nil {
+		eq = reflect.DeepEqual
+	}
+	itm := c.newItem(key, newValue, time.Time{})
+	if c.checkValueSize(itm) != nil {
+		return false
+	}
 
-	// If we're over the max items, clean up old items.
-	if c.config.MaxItems > 0 && atomic.LoadInt64(&c.itemCount) > int64(c.config.MaxItems) {
-		c.cleanupOldest()
+	s := c.shardFor(key)
+	s.mu.Lock()
+	existing, ok := s.items[key]
+	if !ok || !existing.live(c.now()) || !c.matches(func() bool { return eq(c.decompress(existing.value), oldValue) }) {
+		s.mu.Unlock()
+		return false
 	}
+	itm.expiration, itm.staleUntil, itm.ttl, itm.tags = existing.expiration, existing.staleUntil, existing.ttl, existing.tags
+	evicted := c.setLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
+	return true
+}
+
+// insert stores an item, replacing any existing item with the same key,
+// and evicts whatever no longer fits. It must be called without holding any shard lock.
+func (c *Cache) insert(itm *item) {
+	s := c.shardFor(itm.key)
+	s.mu.Lock()
+	evicted := c.setLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
 }
 
 // Get retrieves a value from the cache.
-func (c *Cache) Get(_ context.Context, key string) (any, bool) {
-	value, ok := c.data.Load(key)
-	if !ok {
+// If ctx is already done, Get reports a miss without touching the cache or its counters.
+func (c *Cache) Get(ctx context.Context, key string) (any, bool) {
+	if ctx.Err() != nil {
 		return nil, false
 	}
 
-	itm, ok := value.(item)
+	s := c.shardFor(key)
+	s.mu.Lock()
+	value, ok, evicted := c.getLocked(s, key, c.now(), nil)
+	s.mu.Unlock()
+
+	c.notifyEvicted(evicted)
 	if !ok {
-		// If the value is not of type item, it means it was corrupted or not set correctly.
-		c.data.Delete(key)
-		return nil, false
+		c.notifyMiss(key)
 	}
-	if time.Now().After(itm.expiration) {
-		c.data.Delete(key)
-		atomic.AddInt64(&c.itemCount, -1)
+	return c.readValue(value), ok
+}
 
-		if c.config.OnEviction != nil {
-			c.config.OnEviction(key, itm.value)
-		}
+// Delete removes a value from the cache.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	evicted := c.deleteLocked(s, key, nil)
+	s.mu.Unlock()
+
+	c.notifyEvicted(evicted)
+	return nil
+}
 
+// GetAndDelete retrieves a value and removes it from the cache in one step, so that
+// among concurrent callers for the same key exactly one receives it. This suits
+// one-time tokens that must not be consumed twice. A read-only cache, which cannot
+// consume the value, reports a miss.
+// If ctx is already done, GetAndDelete reports a miss without touching the cache or its counters.
+func (c *Cache) GetAndDelete(ctx context.Context, key string) (any, bool) {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return nil, false
+	}
+	if skip, _ := c.checkWritable(); skip {
 		return nil, false
 	}
 
-	return itm.value, true
+	s := c.shardFor(key)
+	s.mu.Lock()
+	value, ok, evicted := c.getLocked(s, key, c.now(), nil)
+	if ok {
+		evicted = c.deleteLocked(s, key, evicted)
+	}
+	s.mu.Unlock()
+
+	c.notifyEvicted(evicted)
+	if !ok {
+		c.notifyMiss(key)
+	}
+	return c.decompress(value), ok
 }
 
-// Delete removes a value from the cache.
-func (c *Cache) Delete(_ context.Context, key string) {
-	if value, loaded := c.data.LoadAndDelete(key); loaded {
-		atomic.AddInt64(&c.itemCount, -1)
+// Clear removes all values from the cache, firing the eviction callbacks
+// with EvictReasonCleared for each of them.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) Clear(ctx context.Context) error {
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	return c.clear(ctx, true)
+}
 
-		if c.config.OnEviction != nil {
-			if itm, ok := value.(item); ok {
-				c.config.OnEviction(key, itm.value)
-			}
-		}
+// ClearSilent removes all values from the cache without firing eviction callbacks.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) ClearSilent(ctx context.Context) error {
+	if err := c.checkOpen(); err != nil {
+		return err
 	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	return c.clear(ctx, false)
 }
 
-// Clear removes all values from the cache.
-func (c *Cache) Clear(_ context.Context) {
-	if c.config.OnEviction != nil {
-		c.data.Range(func(key, value any) bool {
-			itm, ok := value.(item)
-			if !ok {
-				return true
-			}
-			if keyStr, ok := key.(string); ok {
-				c.config.OnEviction(keyStr, itm.value)
+// clear empties every shard at once. All shard locks are taken in index order
+// so concurrent writers observe either the full cache or an empty one.
+func (c *Cache) clear(ctx context.Context, notify bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	for _, s := range c.shards {
+		s.mu.Lock()
+	}
+	var evicted []evictedItem
+	for _, s := range c.shards {
+		if notify && (c.config.OnEviction != nil || c.config.OnEvict != nil || c.events.active()) {
+			for _, itm := range s.items {
+				evicted = append(evicted, evictedItem{itm.key, itm.value, EvictReasonCleared})
 			}
-			return true
-		})
+		}
+		for _, itm := range s.items {
+			atomic.AddInt64(&c.bytes, -itm.size)
+		}
+		atomic.AddInt64(&c.itemCount, -int64(len(s.items)))
+		s.reset()
+	}
+	c.resetTenants()
+	c.resetTagCounts()
+	c.logClear()
+	for _, s := range c.shards {
+		s.mu.Unlock()
 	}
 
-	c.data = sync.Map{}
-	atomic.StoreInt64(&c.itemCount, 0)
+	c.notifyEvicted(evicted)
+	return nil
 }
 
-// Size returns the number of items in the cache.
+// Size returns the number of items Get would currently return. Items that have
+// expired but have not been swept yet are not counted, so this scans the shards
+// holding items with a TTL, and every shard after BumpGeneration until the janitor
+// has swept the retired generation.
 func (c *Cache) Size() int64 {
-	return atomic.LoadInt64(&c.itemCount)
+	now := c.now()
+	retiring := atomic.LoadInt32(&c.retiring) != 0
+
+	var n int64
+	for _, s := range c.shards {
+		s.mu.Lock()
+		n += int64(s.liveLen(now, retiring))
+		s.mu.Unlock()
+	}
+	return n
 }
 
-// Close stops the cache cleanup goroutine.
+// Stats returns a snapshot of the cache counters.
+func (c *Cache) Stats() Stats {
+	counters := c.counters.Load()
+	return Stats{
+		Hits:      atomic.LoadInt64(&counters.hits),
+		Misses:    atomic.LoadInt64(&counters.misses),
+		Evictions: atomic.LoadInt64(&counters.evictions),
+		ItemCount: c.Size(),
+		Bytes:     atomic.LoadInt64(&c.bytes),
+
+		SuppressedEvictionLogs: atomic.LoadInt64(&counters.suppressedLogs),
+	}
+}
+
+// checkValueSize returns ErrValueTooLarge if itm exceeds MaxValueBytes.
+func (c *Cache) checkValueSize(itm *item) error {
+	if limit := c.config.MaxValueBytes; limit > 0 && itm.size > limit {
+		return errors.Wrapf(ErrValueTooLarge, "key %q is %d bytes, limit is %d", itm.key, itm.size, limit)
+	}
+	return nil
+}
+
+// Close stops the cache cleanup and refresh-ahead goroutines and waits for them
+// to exit. It is safe to call more than once, also concurrently; later calls are no-ops.
+// After Close, every write leaves the cache unchanged. Set, SetWithTTL, SetWithDeadline,
+// SetWithGrace, SetWithTags, SetWithVersion, SetNotFound, SetMulti, SetMultiWithTTL,
+// Delete, DeleteMulti, Clear, ClearSilent, Increment, Decrement and LoadSnapshot return
+// ErrClosed, as do GetOrSet on a miss and RefreshAhead; SetIfAbsent, ReplaceIfPresent,
+// CompareAndSwap and Touch report false, GetAndDelete reports a miss, and TouchMulti,
+// InvalidateTag, DeletePrefix and DeleteFunc report zero. Reads such as Get keep serving
+// the entries present at Close. Expired entries are no longer swept in the background.
 func (c *Cache) Close() error {
-	select {
-	case <-c.stopChan:
-		// Already closed
-		return nil
-	default:
+	var err error
+	c.closeOnce.Do(func() {
+		atomic.StoreInt32(&c.closed, 1)
 		close(c.stopChan)
+		c.loadMu.Lock()
+		c.stopRefresh()
+		c.loadMu.Unlock()
+		c.refreshWG.Wait()
+		c.events.closeAll()
 		<-c.closedChan // Wait for cleanup goroutine to exit
-		return nil
+		if c.aof != nil {
+			c.drainAOF()
+			err = c.aof.close()
+		}
+	})
+	return err
+}
+
+// checkOpen returns ErrClosed once Close has been called.
+func (c *Cache) checkOpen() error {
+	if atomic.LoadInt32(&c.closed) != 0 {
+		return ErrClosed
 	}
+	return nil
 }
 
-// cleanupLoop periodically cleans up expired items.
+// cleanupLoop periodically sweeps expired items; see SweepStrategy.
 func (c *Cache) cleanupLoop() {
-	ticker := time.NewTicker(c.config.CleanupInterval)
+	if c.config.CleanupInterval <= 0 && c.config.SweepMaxInterval <= 0 {
+		close(c.closedChan)
+		<-c.stopChan
+		return
+	}
+
+	interval := c.initialSweepInterval()
+	timer := time.NewTimer(c.sweepDelay(interval))
 	defer func() {
-		ticker.Stop()
+		timer.Stop()
 		close(c.closedChan)
 	}()
 
 	for {
 		select {
-		case <-ticker.C:
-			c.cleanup()
+		case <-timer.C:
+			interval = c.sweep(interval)
+			timer.Reset(c.sweepDelay(interval))
 		case <-c.stopChan:
 			return
 		}
 	}
 }
 
-// cleanup removes expired items.
-func (c *Cache) cleanup() {
-	evicted := make(map[string]any)
-	count := 0
+// cleanup removes expired items, one shard at a time, and returns the fraction of
+// the items it checked that had expired.
+func (c *Cache) cleanup() float64 {
+	now := c.now()
 
-	c.data.Range(func(key, value any) bool {
-		itm, ok := value.(item)
-		if !ok {
-			return true
-		}
-		if time.Now().After(itm.expiration) {
-			c.data.Delete(key)
-			count++
-
-			if c.config.OnEviction != nil {
-				if keyStr, ok := key.(string); ok {
-					evicted[keyStr] = itm.value
-				}
+	var evicted []evictedItem
+	checked := 0
+	for _, s := range c.shards {
+		s.mu.Lock()
+		checked += len(s.items)
+		for _, itm := range s.items {
+			if itm.dead(now) {
+				evicted = c.dropLocked(s, itm, EvictReasonExpired, evicted)
 			}
 		}
-		return true
-	})
+		s.mu.Unlock()
+	}
+
+	if len(evicted) > 0 {
+		atomic.AddInt64(&c.counters.Load().evictions, int64(len(evicted)))
 
-	if count > 0 {
-		atomic.AddInt64(&c.itemCount, -int64(count))
+		// Call eviction callbacks outside the lock to avoid blocking other operations
+		c.notifyEvicted(evicted)
+	}
+	if checked == 0 {
+		return 0
+	}
+	return float64(len(evicted)) / float64(checked)
+}
 
-		// Call eviction callbacks outside the loop to avoid blocking the range
-		if c.config.OnEviction != nil {
-			for k, v := range evicted {
-				c.config.OnEviction(k, v)
+// notifyEvicted runs the eviction callbacks for each removed item, after checking
+// the high-water mark. Every write calls it, even without removals.
+// It must be called without holding any shard lock.
+func (c *Cache) notifyEvicted(evicted []evictedItem) {
+	if c.config.OnHighWaterMark != nil {
+		c.checkHighWaterMark(&c.aboveItemMark, atomic.LoadInt64(&c.itemCount), int64(c.config.MaxItems))
+		c.checkHighWaterMark(&c.aboveByteMark, atomic.LoadInt64(&c.bytes), c.config.MaxBytes)
+	}
+	if c.events.active() {
+		for _, e := range evicted {
+			eventType := EventEvict
+			if e.reason == EvictReasonDeleted {
+				eventType = EventDelete
 			}
+			c.publish(CacheEvent{Type: eventType, Key: e.key, Reason: e.reason})
 		}
 	}
+	if len(evicted) > 0 && c.logger.Enabled(context.Background(), slog.LevelDebug) {
+		c.logEvictions(evicted)
+	}
+	if c.config.OnEviction == nil && c.config.OnEvict == nil {
+		return
+	}
+	for _, e := range evicted {
+		e.value = c.decompress(e.value)
+		c.runEvictionCallbacks(e)
+	}
 }
 
-// cleanupOldest removes the oldest items if we're over the max items.
-func (c *Cache) cleanupOldest() {
-	// Remove 20% of max items at once
-	threshold := max(c.config.MaxItems/5, 1)
-
-	currentCount := atomic.LoadInt64(&c.itemCount)
-
-	// If we're not over the threshold, don't do anything
-	if currentCount <= int64(c.config.MaxItems) {
+// notifyMiss calls OnMiss for a key that missed. A panicking callback is
+// logged and recovered, so it never affects the read that missed.
+func (c *Cache) notifyMiss(key string) {
+	if c.config.OnMiss == nil {
 		return
 	}
+	defer c.recoverPanic("miss callback", nil)
+	c.config.OnMiss(key)
+}
 
-	// Find the oldest items
-	type keyExpPair struct {
-		key        string
-		value      any
-		expiration time.Time
+// runEvictionCallbacks calls the eviction callbacks for one removed item.
+// A panicking callback is logged and recovered, so the cache operation
+// that removed the item still completes.
+func (c *Cache) runEvictionCallbacks(e evictedItem) {
+	defer c.recoverPanic("eviction callback", nil)
+	if c.config.OnEviction != nil {
+		c.config.OnEviction(e.key, e.value)
 	}
-	candidates := make([]keyExpPair, 0, threshold)
-
-	c.data.Range(func(key, value any) bool {
-		itm, ok := value.(item)
-		if !ok {
-			return true
-		}
-		if keyStr, ok := key.(string); ok && len(candidates) < threshold {
-			candidates = append(candidates, keyExpPair{keyStr, itm.value, itm.expiration})
-			return true
-		}
-
-		// Find the newest item in candidates
-		newestIdx := 0
-		for i := 1; i < len(candidates); i++ {
-			if candidates[i].expiration.After(candidates[newestIdx].expiration) {
-				newestIdx = i
-			}
-		}
+	if c.config.OnEvict != nil {
+		c.config.OnEvict(e.key, e.value, e.reason)
+	}
+}
 
-		// Replace it if this item is older
-		if itm.expiration.Before(candidates[newestIdx].expiration) {
-			candidates[newestIdx] = keyExpPair{key.(string), itm.value, itm.expiration}
+// checkHighWaterMark calls OnHighWaterMark when current rises to the high-water mark
+// of limit, and re-arms it once current drops back below. above tracks which side
+// of the mark the cache was on, so concurrent writers fire the callback only once.
+func (c *Cache) checkHighWaterMark(above *int32, current, limit int64) {
+	if limit <= 0 {
+		return
+	}
+	if float64(current) >= c.config.HighWaterMark*float64(limit) {
+		if atomic.CompareAndSwapInt32(above, 0, 1) {
+			func() {
+				defer c.recoverPanic("high-water mark callback", nil)
+				c.config.OnHighWaterMark(current, limit)
+			}()
 		}
+	} else {
+		atomic.CompareAndSwapInt32(above, 1, 0)
+	}
+}
 
-		return true
-	})
+// validateKey runs the configured key validator, if any.
+// Reads are not validated: a rejected key can never be stored, so it always misses.
+func (c *Cache) validateKey(key string) (err error) {
+	if c.config.KeyValidator == nil {
+		return nil
+	}
+	defer c.recoverPanic("key validator", &err)
+	return c.config.KeyValidator(key)
+}
 
-	// Delete the oldest items
-	deletedCount := 0
-	for _, candidate := range candidates {
-		c.data.Delete(candidate.key)
-		deletedCount++
+// newItem builds an item holding value, compressing it if configured.
+func (c *Cache) newItem(key string, value any, expiration time.Time) *item {
+	var size int64
+	if c.config.Weigher != nil {
+		// Weigh the value as the caller sees it, not its compressed form.
+		size = c.sizeOf(key, value)
+	}
+	value = c.compress(value)
+	if c.config.Weigher == nil {
+		// Estimate size of the item (very rough approximation).
+		size = c.sizeOf(key, value)
+	}
+	itm := allocItem()
+	itm.key, itm.value, itm.expiration, itm.size = key, value, expiration, size
+	return itm
+}
 
-		if c.config.OnEviction != nil {
-			c.config.OnEviction(candidate.key, candidate.value)
-		}
+// now returns the current time according to the configured clock.
+func (c *Cache) now() time.Time {
+	if c.config.Clock != nil {
+		return c.config.Clock()
 	}
+	return time.Now()
+}
 
-	// Update count
-	if deletedCount > 0 {
-		atomic.AddInt64(&c.itemCount, -int64(deletedCount))
+// expirationFor returns the expiration time for a TTL starting at now.
+// A non-positive TTL yields the zero time, meaning no expiration.
+func expirationFor(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
 	}
+	return now.Add(ttl)
+}
+
+// Sizer is implemented by values that know their own approximate size in bytes.
+// It lets custom types report an accurate size for the byte budget.
+type Sizer interface {
+	Size() int64
 }
 
 // estimateSize attempts to estimate the memory footprint of a value.
-func estimateSize(value any) int {
+func estimateSize(value any) int64 {
 	switch v := value.(type) {
+	case Sizer:
+		return v.Size()
 	case string:
-		return len(v) + 24 // base size + string overhead
+		return int64(len(v))
 	case []byte:
-		return len(v) + 24 // base size + slice overhead
+		return int64(len(v))
+	case compressedValue:
+		return int64(len(v))
 	case map[string]any:
-		return len(v) * 64 // rough estimate
+		return int64(len(v)) * 64 // rough estimate
 	default:
 		return 64 // default conservative estimate
 	}
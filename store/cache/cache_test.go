@@ -2,8 +2,13 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -13,7 +18,8 @@ func TestCacheBasicOperations(t *testing.T) {
 	config := DefaultConfig()
 	config.DefaultTTL = 100 * time.Millisecond
 	config.CleanupInterval = 50 * time.Millisecond
-	cache := New(config)
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now))
 	defer cache.Close()
 
 	// Test Set and Get
@@ -35,7 +41,7 @@ func TestCacheBasicOperations(t *testing.T) {
 	}
 
 	// Test automatic expiration
-	time.Sleep(150 * time.Millisecond)
+	clock.Advance(150 * time.Millisecond)
 	if _, ok := cache.Get(ctx, "key1"); ok {
 		t.Errorf("Key 'key1' should have expired")
 	}
@@ -45,7 +51,7 @@ func TestCacheBasicOperations(t *testing.T) {
 	}
 
 	// Wait for key2 to expire
-	time.Sleep(100 * time.Millisecond)
+	clock.Advance(100 * time.Millisecond)
 	if _, ok := cache.Get(ctx, "key2"); ok {
 		t.Errorf("Key 'key2' should have expired")
 	}
@@ -179,7 +185,8 @@ func TestEvictionCallback(t *testing.T) {
 		evictedMu.Unlock()
 	}
 
-	cache := New(config)
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now))
 	defer cache.Close()
 
 	// Add items
@@ -197,13 +204,1006 @@ func TestEvictionCallback(t *testing.T) {
 	}
 	evictedMu.Unlock()
 
-	// Wait for automatic expiration
-	time.Sleep(60 * time.Millisecond)
+	// Wait for the janitor to notice the expiration
+	clock.Advance(60 * time.Millisecond)
 
 	// Verify TTL expiration triggered callback
+	eventually(t, func() bool {
+		evictedMu.Lock()
+		defer evictedMu.Unlock()
+		return evicted["key2"] == "value2"
+	}, "Eviction callback not triggered for TTL expiration")
+}
+
+func TestCacheTTLExpiration(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.CleanupInterval = 0
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "short", "value", 50*time.Millisecond)
+	cache.SetWithTTL(ctx, "forever", "value", 0)
+	if cache.Size() != 2 {
+		t.Fatalf("Expected size 2, got %d", cache.Size())
+	}
+
+	// A value is still live at the exact instant its TTL runs out.
+	clock.Advance(50 * time.Millisecond)
+	if _, ok := cache.Get(ctx, "short"); !ok {
+		t.Errorf("Key 'short' should be live until its TTL has fully elapsed")
+	}
+	clock.Advance(time.Nanosecond)
+
+	// Lazy eviction on access treats the expired key as a miss.
+	if val, ok := cache.Get(ctx, "short"); ok || val != nil {
+		t.Errorf("Expected miss for expired key, got %v, exists: %v", val, ok)
+	}
+	if cache.Size() != 1 {
+		t.Errorf("Expected size 1 after lazy eviction, got %d", cache.Size())
+	}
+
+	// A non-positive TTL never expires.
+	if _, ok := cache.Get(ctx, "forever"); !ok {
+		t.Errorf("Key 'forever' should not expire")
+	}
+}
+
+func TestCacheJanitorSweepsExpired(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.CleanupInterval = 10 * time.Millisecond
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now))
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("key%d", i), i, 50*time.Millisecond)
+	}
+
+	// The janitor reads the same clock, so nothing is swept before the TTL elapses.
+	cache.cleanup()
+	if cache.Size() != 10 {
+		t.Errorf("Expected no keys to be swept yet, size is %d", cache.Size())
+	}
+
+	// The janitor reclaims expired keys without any access.
+	clock.Advance(51 * time.Millisecond)
+	eventually(t, func() bool { return cache.Size() == 0 }, "Expected janitor to sweep all keys")
+}
+
+func TestCacheCloseStopsJanitor(t *testing.T) {
+	config := DefaultConfig()
+	config.CleanupInterval = time.Millisecond
+	cache := New(config)
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	select {
+	case <-cache.closedChan:
+	default:
+		t.Errorf("Janitor goroutine should have exited after Close")
+	}
+
+	// A cache without a janitor closes cleanly too.
+	config.CleanupInterval = 0
+	if err := New(config).Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+}
+
+func TestCacheAfterClose(t *testing.T) {
+	ctx := context.Background()
+	before := runtime.NumGoroutine()
+	config := DefaultConfig()
+	config.CleanupInterval = time.Millisecond
+	cache := New(config)
+	cache.SetWithTags(ctx, "kept", "value", "tag")
+	stop, err := cache.RefreshAhead(ctx, "refreshed", time.Minute, 0.5, func(context.Context) (any, error) {
+		return "value", nil
+	})
+	if err != nil {
+		t.Fatalf("RefreshAhead failed: %v", err)
+	}
+	defer stop()
+
+	// Closing twice, even concurrently, is safe.
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cache.Close(); err != nil {
+				t.Errorf("Close returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	// Writes are rejected, reads keep serving what was cached.
+	if err := cache.Set(ctx, "new", "value"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from Set, got %v", err)
+	}
+	if err := cache.Delete(ctx, "kept"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from Delete, got %v", err)
+	}
+	if _, err := cache.GetOrSet(ctx, "new", func(context.Context) (any, error) { return "value", nil }); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from GetOrSet, got %v", err)
+	}
+	loader := func(context.Context) (any, error) { return "value", nil }
+	for name, write := range map[string]func() error{
+		"SetWithTTL":      func() error { return cache.SetWithTTL(ctx, "new", "value", time.Minute) },
+		"SetWithDeadline": func() error { return cache.SetWithDeadline(ctx, "new", "value", time.Now().Add(time.Minute)) },
+		"SetWithGrace":    func() error { return cache.SetWithGrace(ctx, "new", "value", time.Minute, time.Minute) },
+		"SetWithTags":     func() error { return cache.SetWithTags(ctx, "new", "value", "tag") },
+		"SetWithVersion":  func() error { return cache.SetWithVersion(ctx, "new", "value", 1) },
+		"SetNotFound":     func() error { return cache.SetNotFound(ctx, "new", time.Minute) },
+		"SetMulti":        func() error { return cache.SetMulti(ctx, map[string]any{"new": "value"}) },
+		"SetMultiWithTTL": func() error {
+			return cache.SetMultiWithTTL(ctx, map[string]ValueWithTTL{"new": {Value: "value", TTL: time.Minute}})
+		},
+		"DeleteMulti": func() error { return cache.DeleteMulti(ctx, []string{"kept"}) },
+		"Clear":       func() error { return cache.Clear(ctx) },
+		"ClearSilent": func() error { return cache.ClearSilent(ctx) },
+		"Increment": func() error {
+			_, err := cache.Increment(ctx, "new", 1)
+			return err
+		},
+		"LoadSnapshot": func() error { return cache.LoadSnapshot(strings.NewReader(`{"key":"new","value":"InZhbHVlIg=="}`)) },
+		"RefreshAhead": func() error {
+			_, err := cache.RefreshAhead(ctx, "new", time.Minute, 0.5, loader)
+			return err
+		},
+	} {
+		if err := write(); !errors.Is(err, ErrClosed) {
+			t.Errorf("Expected ErrClosed from %s, got %v", name, err)
+		}
+	}
+	for name, write := range map[string]func() bool{
+		"SetIfAbsent":      func() bool { return cache.SetIfAbsent(ctx, "new", "value") },
+		"ReplaceIfPresent": func() bool { return cache.ReplaceIfPresent(ctx, "kept", "replaced") },
+		"CompareAndSwap":   func() bool { return cache.CompareAndSwap(ctx, "kept", "value", "swapped", nil) },
+		"Touch":            func() bool { return cache.Touch(ctx, "kept", time.Hour) },
+		"GetAndDelete": func() bool {
+			_, ok := cache.GetAndDelete(ctx, "kept")
+			return ok
+		},
+	} {
+		if write() {
+			t.Errorf("Expected %s to report false after Close", name)
+		}
+	}
+	for name, write := range map[string]func() int{
+		"TouchMulti":    func() int { return cache.TouchMulti(ctx, []string{"kept"}, time.Hour) },
+		"InvalidateTag": func() int { return cache.InvalidateTag(ctx, "tag") },
+		"DeletePrefix":  func() int { return cache.DeletePrefix(ctx, "kept") },
+		"DeleteFunc":    func() int { return cache.DeleteFunc(ctx, func(string, any) bool { return true }) },
+	} {
+		if n := write(); n != 0 {
+			t.Errorf("Expected %s to report zero after Close, got %d", name, n)
+		}
+	}
+	if val, ok := cache.Get(ctx, "kept"); !ok || val != "value" {
+		t.Errorf("Expected Get to keep serving 'kept', got %v", val)
+	}
+	if _, ok := cache.Get(ctx, "new"); ok {
+		t.Errorf("Key 'new' should not have been written after Close")
+	}
+
+	eventually(t, func() bool { return runtime.NumGoroutine() <= before }, "Background goroutines should exit after Close")
+}
+
+func TestCacheStats(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.CleanupInterval = 0
+	config.MaxItems = 0
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.Set(ctx, "key1", "value1")
+	cache.Set(ctx, "key2", "value2")
+	cache.SetWithTTL(ctx, "key3", "value3", 10*time.Millisecond)
+
+	cache.Get(ctx, "key1")    // hit
+	cache.Get(ctx, "key2")    // hit
+	cache.Get(ctx, "missing") // miss
+	cache.Delete(ctx, "key1") // explicit delete is not an eviction
+	cache.Get(ctx, "key1")    // miss
+
+	clock.Advance(20 * time.Millisecond)
+	cache.Get(ctx, "key3") // miss and TTL eviction
+
+	want := Stats{Hits: 2, Misses: 3, Evictions: 1, ItemCount: 1, Bytes: int64(len("value2"))}
+	if got := cache.Stats(); got != want {
+		t.Errorf("Expected stats %+v, got %+v", want, got)
+	}
+}
+
+func TestCacheResetStats(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(1)
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "value")
+	cache.Set(ctx, "memo:2", "value") // evicts memo:1
+	cache.Get(ctx, "memo:1")
+	cache.Get(ctx, "memo:2")
+	cache.GetOrSet(ctx, "memo:3", func(context.Context) (any, error) { return nil, errors.New("boom") })
+
+	cache.ResetStats()
+	want := Stats{ItemCount: 1, Bytes: int64(len("value"))}
+	if got := cache.Stats(); got != want {
+		t.Errorf("Expected counters to reset and gauges to survive, got %+v", got)
+	}
+	if got := cache.LoaderStats(); got != (LoaderStats{}) {
+		t.Errorf("Expected loader stats to reset, got %+v", got)
+	}
+	if val, ok := cache.Get(ctx, "memo:2"); !ok || val != "value" {
+		t.Errorf("Expected ResetStats to keep the cached data")
+	}
+	if got := cache.Stats().Hits; got != 1 {
+		t.Errorf("Expected counting to resume after a reset, got %d hits", got)
+	}
+
+	// Resetting while other goroutines read must be race-free.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				cache.Get(ctx, "memo:2")
+				if j%100 == 0 {
+					cache.ResetStats()
+				}
+				cache.Stats()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(3, WithShards(1))
+	defer cache.Close()
+
+	cache.Set(ctx, "a", 1)
+	cache.Set(ctx, "b", 2)
+	cache.Set(ctx, "c", 3)
+
+	// Reading "a" makes "b" the least recently used key.
+	if _, ok := cache.Get(ctx, "a"); !ok {
+		t.Fatalf("Key 'a' should be in the cache")
+	}
+	cache.Set(ctx, "d", 4)
+
+	if _, ok := cache.Get(ctx, "b"); ok {
+		t.Errorf("Key 'b' should have been evicted as least recently used")
+	}
+	for _, key := range []string{"a", "c", "d"} {
+		if _, ok := cache.Get(ctx, key); !ok {
+			t.Errorf("Key '%s' should be in the cache", key)
+		}
+	}
+	if cache.Size() != 3 {
+		t.Errorf("Expected size 3, got %d", cache.Size())
+	}
+	if evictions := cache.Stats().Evictions; evictions != 1 {
+		t.Errorf("Expected 1 eviction, got %d", evictions)
+	}
+
+	// Overwriting an existing key does not grow the cache.
+	cache.Set(ctx, "c", 30)
+	if cache.Size() != 3 {
+		t.Errorf("Expected size 3 after overwrite, got %d", cache.Size())
+	}
+}
+
+func TestCacheLRUFillPastCapacity(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(100, WithShards(1))
+	defer cache.Close()
+
+	for i := 0; i < 150; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+
+	if cache.Size() != 100 {
+		t.Errorf("Expected size 100, got %d", cache.Size())
+	}
+	// The oldest untouched keys are the ones gone.
+	for i := 0; i < 50; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("key%d", i)); ok {
+			t.Errorf("Key 'key%d' should have been evicted", i)
+		}
+	}
+	for i := 50; i < 150; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("key%d", i)); !ok {
+			t.Errorf("Key 'key%d' should be in the cache", i)
+		}
+	}
+}
+
+func TestOnEvictReasons(t *testing.T) {
+	ctx := context.Background()
+	reasons := make(map[string]EvictReason)
+	evictedMu := sync.Mutex{}
+
+	config := DefaultConfig()
+	config.MaxItems = 2
+	config.CleanupInterval = 0
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now), WithOnEvict(func(key string, _ any, reason EvictReason) {
+		evictedMu.Lock()
+		reasons[key] = reason
+		evictedMu.Unlock()
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "deleted", 1)
+	cache.Delete(ctx, "deleted")
+
+	cache.SetWithTTL(ctx, "expired", 2, 10*time.Millisecond)
+	clock.Advance(20 * time.Millisecond)
+	cache.Get(ctx, "expired")
+
+	cache.Set(ctx, "old", 3)
+	cache.Set(ctx, "new1", 4)
+	cache.Set(ctx, "new2", 5)
+
 	evictedMu.Lock()
-	if evicted["key2"] != "value2" {
-		t.Errorf("Eviction callback not triggered for TTL expiration")
+	defer evictedMu.Unlock()
+	want := map[string]EvictReason{
+		"deleted": EvictReasonDeleted,
+		"expired": EvictReasonExpired,
+		"old":     EvictReasonCapacity,
+	}
+	for key, reason := range want {
+		if got, ok := reasons[key]; !ok || got != reason {
+			t.Errorf("Expected reason %v for '%s', got %v (fired: %v)", reason, key, got, ok)
+		}
+	}
+	if len(reasons) != len(want) {
+		t.Errorf("Expected %d evictions, got %v", len(want), reasons)
+	}
+}
+
+func TestOnEvictRunsOutsideLock(t *testing.T) {
+	ctx := context.Background()
+	var cache *Cache
+	cache = NewDefault(WithOnEvict(func(key string, _ any, _ EvictReason) {
+		// Re-entering the cache from the callback must not deadlock.
+		cache.Set(ctx, "seen:"+key, true)
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "key", "value")
+	cache.Delete(ctx, "key")
+	if _, ok := cache.Get(ctx, "seen:key"); !ok {
+		t.Errorf("Callback should have written to the cache")
+	}
+}
+
+func TestOnMiss(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	var missed []string
+	var cache *Cache
+	cache = NewDefault(WithClock(clock.Now), WithOnMiss(func(key string) {
+		missed = append(missed, key)
+		// OnMiss runs outside the lock, so reading the cache must not deadlock.
+		cache.Size()
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "value")
+	cache.SetWithTTL(ctx, "memo:2", "value", time.Second)
+	if _, ok := cache.Get(ctx, "memo:1"); !ok {
+		t.Fatalf("Expected a hit on memo:1")
+	}
+	if len(missed) != 0 {
+		t.Errorf("Expected no miss callback on a hit, got %v", missed)
+	}
+
+	cache.Get(ctx, "memo:absent")
+	clock.Advance(2 * time.Second)
+	if _, ok := cache.Get(ctx, "memo:2"); ok {
+		t.Fatalf("Expected memo:2 to have expired")
+	}
+	if want := []string{"memo:absent", "memo:2"}; !reflect.DeepEqual(missed, want) {
+		t.Errorf("Expected misses %v, got %v", want, missed)
+	}
+	if val, ok := cache.Get(ctx, "memo:1"); !ok || val != "value" {
+		t.Errorf("Expected OnMiss to leave the cache untouched, got %v", val)
+	}
+}
+
+type sizedValue int64
+
+func (v sizedValue) Size() int64 { return int64(v) }
+
+func TestCacheByteBudget(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithMaxBytes(100, WithShards(1))
+	defer cache.Close()
+
+	cache.Set(ctx, "a", make([]byte, 40))
+	cache.Set(ctx, "b", "0123456789012345678901234567890123456789") // 40 bytes
+	if got := cache.Stats().Bytes; got != 80 {
+		t.Fatalf("Expected 80 bytes, got %d", got)
+	}
+
+	// A custom Sizer reports its own size; 80 + 30 exceeds the budget, evicting "a".
+	cache.Set(ctx, "c", sizedValue(30))
+	if _, ok := cache.Get(ctx, "a"); ok {
+		t.Errorf("Key 'a' should have been evicted by the byte budget")
+	}
+	if got := cache.Stats().Bytes; got != 70 {
+		t.Errorf("Expected 70 bytes after eviction, got %d", got)
+	}
+
+	// Many tiny values fit even though the item count grows.
+	for i := 0; i < 10; i++ {
+		cache.Set(ctx, fmt.Sprintf("tiny%d", i), sizedValue(1))
+	}
+	if cache.Size() != 12 {
+		t.Errorf("Expected 12 items within the byte budget, got %d", cache.Size())
+	}
+
+	// Overwrites and deletes keep the byte total accurate.
+	cache.Set(ctx, "b", sizedValue(5))
+	cache.Delete(ctx, "c")
+	if got := cache.Stats().Bytes; got != 15 {
+		t.Errorf("Expected 15 bytes, got %d", got)
+	}
+}
+
+func TestCacheEvictionBatch(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithMaxBytes(100, WithShards(4), WithEvictionBatch(5))
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		cache.Set(ctx, fmt.Sprintf("small:%d", i), sizedValue(1))
+	}
+
+	// A burst of large writes would each need dozens of evictions.
+	for i := 0; i < 3; i++ {
+		before := cache.Stats().Evictions
+		cache.Set(ctx, fmt.Sprintf("large:%d", i), sizedValue(30))
+		if n := cache.Stats().Evictions - before; n > 5 {
+			t.Errorf("Expected a write to evict at most 5 entries, evicted %d", n)
+		}
+	}
+	if got := cache.Stats().Bytes; got <= 100 {
+		t.Fatalf("Expected the cache to be over budget after the burst, got %d bytes", got)
+	}
+
+	// The janitor catches up with the rest.
+	cache.sweep(cache.config.CleanupInterval)
+	if got := cache.Stats().Bytes; got > 100 {
+		t.Errorf("Expected the janitor to bring the cache back within budget, got %d bytes", got)
+	}
+	for i := 0; i < 3; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("large:%d", i)); !ok {
+			t.Errorf("Expected the recently written large:%d to survive", i)
+		}
+	}
+}
+
+func TestCacheWeigher(t *testing.T) {
+	ctx := context.Background()
+	costs := map[string]int64{"cheap": 1, "medium": 10, "expensive": 50}
+	cache := NewWithMaxBytes(60, WithShards(1), WithWeigher(func(key string, _ any) int64 {
+		return costs[strings.SplitN(key, ":", 2)[0]]
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "expensive:1", "x")
+	cache.Set(ctx, "medium:1", "x")
+	if got := cache.Stats().Bytes; got != 60 {
+		t.Fatalf("Expected a total weight of 60, got %d", got)
+	}
+
+	// Going over the weight budget evicts the least recently used entry,
+	// however many entries are left afterwards.
+	cache.Set(ctx, "cheap:1", "x")
+	if _, ok := cache.Get(ctx, "expensive:1"); ok {
+		t.Errorf("Key 'expensive:1' should have been evicted by the weight budget")
+	}
+	for i := 2; i <= 40; i++ {
+		cache.Set(ctx, fmt.Sprintf("cheap:%d", i), "x")
+	}
+	if got := cache.Stats().Bytes; got != 50 || cache.Size() != 41 {
+		t.Errorf("Expected 41 entries weighing 50, got %d weighing %d", cache.Size(), got)
+	}
+
+	// Each write keeps the total within the budget.
+	cache.Set(ctx, "expensive:2", "x")
+	if got := cache.Stats().Bytes; got > 60 {
+		t.Errorf("Expected the total weight to stay within 60, got %d", got)
+	}
+	if _, ok := cache.Get(ctx, "medium:1"); ok {
+		t.Errorf("Key 'medium:1' should have been evicted first as the least recently used")
+	}
+}
+
+func TestCacheMaxValueBytes(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithMaxBytes(100, WithShards(1), WithMaxValueBytes(40))
+	defer cache.Close()
+
+	cache.Set(ctx, "a", sizedValue(30))
+	cache.Set(ctx, "b", sizedValue(40))
+	if cache.Size() != 2 {
+		t.Fatalf("Expected 2 in-bounds entries, got %d", cache.Size())
+	}
+
+	// An oversized value is rejected before it can evict anything or replace "a".
+	if err := cache.SetWithTTL(ctx, "big", sizedValue(41), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge, got %v", err)
+	}
+	if err := cache.SetWithTTL(ctx, "a", sizedValue(90), 0); !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge replacing a key, got %v", err)
+	}
+	if cache.SetIfAbsent(ctx, "c", sizedValue(50)) {
+		t.Errorf("SetIfAbsent should reject an oversized value")
+	}
+	if cache.CompareAndSwap(ctx, "b", sizedValue(40), sizedValue(50), nil) {
+		t.Errorf("CompareAndSwap should reject an oversized value")
+	}
+	err := cache.SetMulti(ctx, map[string]any{"d": sizedValue(1), "e": sizedValue(99)})
+	if !errors.Is(err, ErrValueTooLarge) {
+		t.Errorf("Expected ErrValueTooLarge from SetMulti, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "d"); ok {
+		t.Errorf("SetMulti should write nothing when one value is too large")
+	}
+
+	if v, ok := cache.Get(ctx, "a"); !ok || v != sizedValue(30) {
+		t.Errorf("Expected 'a' to be left intact, got %v, %v", v, ok)
+	}
+	if v, ok := cache.Get(ctx, "b"); !ok || v != sizedValue(40) {
+		t.Errorf("Expected 'b' to be left intact, got %v, %v", v, ok)
+	}
+	if got := cache.Stats(); got.Bytes != 70 || got.Evictions != 0 {
+		t.Errorf("Expected 70 bytes and no evictions, got %d bytes and %d evictions", got.Bytes, got.Evictions)
+	}
+}
+
+func TestCacheNilValue(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.Set(ctx, "empty", nil)
+	if val, ok := cache.Get(ctx, "empty"); !ok || val != nil {
+		t.Errorf("Expected a stored nil to be a hit, got %v, exists: %v", val, ok)
+	}
+	if val, ok := cache.Get(ctx, "missing"); ok || val != nil {
+		t.Errorf("Expected a miss, got %v, exists: %v", val, ok)
+	}
+
+	got := cache.GetMulti(ctx, []string{"empty", "missing"})
+	if val, ok := got["empty"]; !ok || val != nil {
+		t.Errorf("Expected GetMulti to include the stored nil, got %v", got)
+	}
+	if _, ok := got["missing"]; ok {
+		t.Errorf("GetMulti should omit missing keys")
+	}
+
+	if _, ok := cache.Inspect(ctx, "empty"); !ok {
+		t.Errorf("Expected a stored nil to be inspectable")
+	}
+	if _, ok := cache.Inspect(ctx, "missing"); ok {
+		t.Errorf("Expected a missing key not to be inspectable")
+	}
+
+	// GetOrSet treats a stored nil as a hit and does not reload it.
+	val, err := cache.GetOrSet(ctx, "empty", func(context.Context) (any, error) {
+		t.Error("Loader should not run for a stored nil")
+		return "loaded", nil
+	})
+	if err != nil || val != nil {
+		t.Errorf("Expected the stored nil, got %v, err: %v", val, err)
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	ctx := context.Background()
+	var cleared int64
+	cache := NewDefault(WithOnEvict(func(_ string, _ any, reason EvictReason) {
+		if reason == EvictReasonCleared {
+			atomic.AddInt64(&cleared, 1)
+		}
+	}))
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if err := cache.Clear(ctx); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if cache.Size() != 0 || cache.Stats().Bytes != 0 {
+		t.Errorf("Expected empty cache after Clear, got %+v", cache.Stats())
+	}
+	for i := 0; i < 100; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("key%d", i)); ok {
+			t.Errorf("Key 'key%d' should miss after Clear", i)
+		}
+	}
+	if cleared != 100 {
+		t.Errorf("Expected 100 cleared callbacks, got %d", cleared)
+	}
+
+	cache.Set(ctx, "key", "value")
+	if err := cache.ClearSilent(ctx); err != nil {
+		t.Fatalf("ClearSilent failed: %v", err)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected empty cache after ClearSilent, got %d", cache.Size())
+	}
+	if cleared != 100 {
+		t.Errorf("ClearSilent should not fire callbacks, got %d", cleared)
+	}
+}
+
+func TestCacheClearConcurrent(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				key := fmt.Sprintf("worker%d-key%d", id, j)
+				cache.Set(ctx, key, j)
+				cache.Get(ctx, key)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				cache.Clear(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	cache.Clear(ctx)
+	if cache.Size() != 0 {
+		t.Errorf("Expected empty cache, got %d", cache.Size())
+	}
+}
+
+// fakeClock is a manually advanced clock for deterministic TTL tests.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// Now returns the simulated current time.
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Advance moves the simulated time forward by d.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// eventually waits for a background goroutine such as the janitor to make cond true.
+func eventually(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Error(msg)
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheGetAndDelete(t *testing.T) {
+	ctx := context.Background()
+	var deleted int64
+	cache := NewDefault(WithOnEvict(func(_ string, _ any, reason EvictReason) {
+		if reason == EvictReasonDeleted {
+			atomic.AddInt64(&deleted, 1)
+		}
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "otp:42", "123456")
+	cache.Set(ctx, "other", "value")
+
+	const goroutines = 100
+	var wg sync.WaitGroup
+	var successes int64
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			if val, ok := cache.GetAndDelete(ctx, "otp:42"); ok {
+				atomic.AddInt64(&successes, 1)
+				if val != "123456" {
+					t.Errorf("Expected '123456', got %v", val)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if successes != 1 {
+		t.Errorf("Expected exactly one caller to take the token, got %d", successes)
+	}
+	if deleted != 1 {
+		t.Errorf("Expected the token to be removed once, got %d", deleted)
+	}
+	if cache.Size() != 1 || atomic.LoadInt64(&cache.itemCount) != 1 {
+		t.Errorf("Expected only 'other' to remain, size is %d", cache.Size())
+	}
+}
+
+func TestCacheSetIfAbsent(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	const goroutines = 100
+	var wg sync.WaitGroup
+	var winners int64
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			if cache.SetIfAbsent(ctx, "memo:1", id) {
+				atomic.AddInt64(&winners, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if winners != 1 {
+		t.Errorf("Expected exactly one SetIfAbsent to win, got %d", winners)
+	}
+	if cache.Size() != 1 {
+		t.Errorf("Expected size 1, got %d", cache.Size())
+	}
+
+	// An expired value or a negative entry counts as absent.
+	cache.SetWithTTL(ctx, "expired", "old", time.Second)
+	clock.Advance(2 * time.Second)
+	cache.SetNotFound(ctx, "missing", time.Minute)
+	for _, key := range []string{"expired", "missing"} {
+		if !cache.SetIfAbsent(ctx, key, "new") {
+			t.Errorf("Expected SetIfAbsent to replace '%s'", key)
+		}
+		if val, ok := cache.Get(ctx, key); !ok || val != "new" {
+			t.Errorf("Expected 'new' for key '%s', got %v", key, val)
+		}
+	}
+}
+
+func TestCacheKeyValidator(t *testing.T) {
+	ctx := context.Background()
+	errKeyTooLong := errors.New("key too long")
+	cache := NewDefault(WithKeyValidator(func(key string) error {
+		if len(key) > 16 {
+			return errKeyTooLong
+		}
+		return nil
+	}))
+	defer cache.Close()
+
+	long := strings.Repeat("k", 17)
+	if err := cache.Set(ctx, long, "value"); !errors.Is(err, errKeyTooLong) {
+		t.Errorf("Expected the validator error from Set, got %v", err)
+	}
+	if err := cache.SetMulti(ctx, map[string]any{"ok": 1, long: 2}); !errors.Is(err, errKeyTooLong) {
+		t.Errorf("Expected the validator error from SetMulti, got %v", err)
+	}
+	if _, err := cache.Increment(ctx, long, 1); !errors.Is(err, errKeyTooLong) {
+		t.Errorf("Expected the validator error from Increment, got %v", err)
+	}
+	if cache.SetIfAbsent(ctx, long, "value") {
+		t.Errorf("Expected SetIfAbsent to reject the key")
+	}
+	if _, err := cache.GetOrSet(ctx, long, func(context.Context) (any, error) {
+		t.Errorf("Loader should not run for a rejected key")
+		return nil, nil
+	}); !errors.Is(err, errKeyTooLong) {
+		t.Errorf("Expected the validator error from GetOrSet, got %v", err)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected rejected writes to leave the cache empty, size is %d", cache.Size())
+	}
+
+	if err := cache.Set(ctx, "short", "value"); err != nil {
+		t.Errorf("Expected a short key to be accepted, got %v", err)
+	}
+}
+
+func TestCacheKeyValidatorPrefix(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithKeyValidator(func(key string) error {
+		if !strings.HasPrefix(key, "memos:") {
+			return fmt.Errorf("key %q is outside the memos: namespace", key)
+		}
+		return nil
+	}))
+	defer cache.Close()
+
+	if err := cache.SetWithTTL(ctx, "other:1", "value", time.Minute); err == nil {
+		t.Errorf("Expected a key outside the namespace to be rejected")
+	}
+	if err := cache.SetWithTTL(ctx, "memos:1", "value", time.Minute); err != nil {
+		t.Errorf("Expected a namespaced key to be accepted, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "other:1"); ok {
+		t.Errorf("Expected the rejected key to be absent")
+	}
+}
+
+func TestCacheReplaceIfPresent(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "count:memos", 10, time.Minute)
+	clock.Advance(20 * time.Second)
+	if !cache.ReplaceIfPresent(ctx, "count:memos", 11) {
+		t.Fatalf("Expected ReplaceIfPresent to update a present key")
+	}
+	if val, remaining, ok := cache.GetWithTTL(ctx, "count:memos"); !ok || val != 11 || remaining != 40*time.Second {
+		t.Errorf("Expected 11 with its TTL untouched, got %v, remaining: %v, exists: %v", val, remaining, ok)
+	}
+
+	if cache.ReplaceIfPresent(ctx, "count:users", 1) {
+		t.Errorf("Expected ReplaceIfPresent to skip an absent key")
+	}
+	if _, ok := cache.Get(ctx, "count:users"); ok {
+		t.Errorf("Expected the absent key not to be created")
+	}
+
+	clock.Advance(time.Minute)
+	if cache.ReplaceIfPresent(ctx, "count:memos", 12) {
+		t.Errorf("Expected ReplaceIfPresent to skip an expired key")
+	}
+}
+
+func TestCacheCompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.Set(ctx, "comments:memo:1", []int{})
+
+	// Competing retry loops each append their own comments to the shared thread.
+	const goroutines = 20
+	const appendsPerGoroutine = 50
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func(id int) {
+			defer wg.Done()
+			for j := 0; j < appendsPerGoroutine; j++ {
+				for {
+					current, _ := cache.Get(ctx, "comments:memo:1")
+					thread := current.([]int)
+					next := append(append([]int(nil), thread...), id*appendsPerGoroutine+j)
+					if cache.CompareAndSwap(ctx, "comments:memo:1", thread, next, nil) {
+						break
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	val, _ := cache.Get(ctx, "comments:memo:1")
+	thread := val.([]int)
+	if len(thread) != goroutines*appendsPerGoroutine {
+		t.Fatalf("Expected %d comments, got %d", goroutines*appendsPerGoroutine, len(thread))
+	}
+	seen := make(map[int]bool, len(thread))
+	for _, comment := range thread {
+		if seen[comment] {
+			t.Fatalf("Comment %d was applied twice", comment)
+		}
+		seen[comment] = true
+	}
+}
+
+func TestCacheCompareAndSwapMismatch(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.Set(ctx, "key", "current")
+	if cache.CompareAndSwap(ctx, "key", "stale", "new", nil) {
+		t.Errorf("Expected CompareAndSwap to fail on a mismatch")
+	}
+	if cache.CompareAndSwap(ctx, "missing", nil, "new", nil) {
+		t.Errorf("Expected CompareAndSwap to fail on a missing key")
+	}
+	sameLength := func(a, b any) bool { return len(a.(string)) == len(b.(string)) }
+	if !cache.CompareAndSwap(ctx, "key", "abcdefg", "new", sameLength) {
+		t.Errorf("Expected the custom comparator to be used")
+	}
+	if val, _ := cache.Get(ctx, "key"); val != "new" {
+		t.Errorf("Expected 'new', got %v", val)
+	}
+}
+
+func TestCacheHighWaterMark(t *testing.T) {
+	ctx := context.Background()
+	type mark struct{ current, max int64 }
+	var marks []mark
+	cache := NewWithCapacity(10, WithShards(1), WithHighWaterMark(0.8, func(current, max int64) {
+		marks = append(marks, mark{current, max})
+	}))
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if len(marks) != 1 || marks[0] != (mark{8, 10}) {
+		t.Fatalf("Expected a single warning at 8 of 10 items, got %v", marks)
+	}
+
+	// Dropping below the mark re-arms the warning.
+	for i := 0; i < 5; i++ {
+		cache.Delete(ctx, fmt.Sprintf("key%d", i))
+	}
+	for i := 0; i < 3; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if len(marks) != 2 || marks[1] != (mark{8, 10}) {
+		t.Errorf("Expected a second warning after refilling, got %v", marks)
+	}
+}
+
+func TestCacheHighWaterMarkBytes(t *testing.T) {
+	ctx := context.Background()
+	var warned []int64
+	cache := NewWithMaxBytes(1000, WithHighWaterMark(0.5, func(current, max int64) {
+		if max != 1000 {
+			t.Errorf("Expected the byte limit as max, got %d", max)
+		}
+		warned = append(warned, current)
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "small", strings.Repeat("a", 400))
+	if len(warned) != 0 {
+		t.Errorf("Expected no warning below the mark, got %v", warned)
+	}
+	cache.Set(ctx, "large", strings.Repeat("b", 200))
+	if len(warned) != 1 || warned[0] != 600 {
+		t.Errorf("Expected a warning at 600 bytes, got %v", warned)
 	}
-	evictedMu.Unlock()
 }
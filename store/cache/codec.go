@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec converts cached values to and from bytes for backends that store
+// values outside the process.
+type Codec interface {
+	Marshal(value any) ([]byte, error)
+	Unmarshal(data []byte, value any) error
+}
+
+// JSONCodec is the default Codec, backed by encoding/json.
+type JSONCodec struct{}
+
+// Marshal encodes a value as JSON.
+func (JSONCodec) Marshal(value any) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Unmarshal decodes JSON into value, which must be a pointer.
+func (JSONCodec) Unmarshal(data []byte, value any) error {
+	return json.Unmarshal(data, value)
+}
+
+// MsgpackCodec is a Codec backed by MessagePack, which is faster and more compact
+// than JSON. Struct fields are named by their json tags, so types already tagged
+// for JSON encode the same way. Decoding into an interface yields MessagePack's
+// native types, such as int64 rather than float64 for integers.
+type MsgpackCodec struct{}
+
+// Marshal encodes a value as MessagePack.
+func (MsgpackCodec) Marshal(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes MessagePack into value, which must be a pointer.
+func (MsgpackCodec) Unmarshal(data []byte, value any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(value)
+}
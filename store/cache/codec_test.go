@@ -0,0 +1,108 @@
+package cache
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// codecMemo mirrors the shape of the memo structs the store caches.
+type codecMemo struct {
+	ID         int32    `json:"id"`
+	UID        string   `json:"uid"`
+	RowStatus  string   `json:"rowStatus"`
+	CreatorID  int32    `json:"creatorId"`
+	CreatedTs  int64    `json:"createdTs"`
+	UpdatedTs  int64    `json:"updatedTs"`
+	Content    string   `json:"content"`
+	Visibility string   `json:"visibility"`
+	Pinned     bool     `json:"pinned"`
+	Tags       []string `json:"tags"`
+	ParentID   *int32   `json:"parentId"`
+}
+
+var codecs = map[string]Codec{"json": JSONCodec{}, "msgpack": MsgpackCodec{}}
+
+func FuzzCodecRoundTrip(f *testing.F) {
+	f.Add(int32(1), "memos", int64(1700000000), "hello #world", true, int32(0))
+	f.Add(int32(-7), "", int64(-1), "", false, int32(42))
+	f.Fuzz(func(t *testing.T, id int32, uid string, ts int64, content string, pinned bool, parent int32) {
+		memo := codecMemo{
+			ID:         id,
+			UID:        uid,
+			RowStatus:  "NORMAL",
+			CreatorID:  id / 2,
+			CreatedTs:  ts,
+			UpdatedTs:  ts + 1,
+			Content:    content,
+			Visibility: "PRIVATE",
+			Pinned:     pinned,
+			Tags:       strings.Fields(content),
+		}
+		if parent != 0 {
+			memo.ParentID = &parent
+		}
+
+		for name, codec := range codecs {
+			// JSON replaces invalid UTF-8, which memo content never contains.
+			if name == "json" && !(utf8.ValidString(uid) && utf8.ValidString(content)) {
+				continue
+			}
+			data, err := codec.Marshal(memo)
+			if err != nil {
+				t.Fatalf("%s: Marshal failed: %v", name, err)
+			}
+			var got codecMemo
+			if err := codec.Unmarshal(data, &got); err != nil {
+				t.Fatalf("%s: Unmarshal failed: %v", name, err)
+			}
+			if !reflect.DeepEqual(got, memo) {
+				t.Errorf("%s: round trip changed %+v into %+v", name, memo, got)
+			}
+		}
+	})
+}
+
+func TestMsgpackCodecUsesJSONTags(t *testing.T) {
+	data, err := MsgpackCodec{}.Marshal(codecMemo{ID: 1})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var fields map[string]any
+	if err := (MsgpackCodec{}).Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if _, ok := fields["creatorId"]; !ok {
+		t.Errorf("Expected fields to be named by their json tags, got %v", fields)
+	}
+}
+
+func BenchmarkCodec(b *testing.B) {
+	parent := int32(3)
+	memo := codecMemo{
+		ID: 42, UID: "7f3c1d2e", RowStatus: "NORMAL", CreatorID: 1,
+		CreatedTs: 1700000000, UpdatedTs: 1700000100,
+		Content:    strings.Repeat("Some #memo content with a few words. ", 10),
+		Visibility: "PUBLIC", Tags: []string{"memo", "notes"}, ParentID: &parent,
+	}
+	for _, name := range []string{"json", "msgpack"} {
+		codec := codecs[name]
+		b.Run(fmt.Sprintf("%s/marshal", name), func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				data, _ := codec.Marshal(memo)
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes")
+		})
+		b.Run(fmt.Sprintf("%s/unmarshal", name), func(b *testing.B) {
+			data, _ := codec.Marshal(memo)
+			for i := 0; i < b.N; i++ {
+				var got codecMemo
+				codec.Unmarshal(data, &got)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// compressedValue is a gzip-compressed []byte value, stored in place of the original
+// when compression is enabled. It never escapes the cache; see Cache.decompress.
+type compressedValue []byte
+
+var gzipWriters = sync.Pool{
+	New: func() any {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// compress returns value gzip-compressed if it is a []byte above the configured
+// threshold and compression actually makes it smaller; otherwise it returns value as is.
+func (c *Cache) compress(value any) any {
+	data, ok := value.([]byte)
+	if !ok || c.config.CompressThreshold <= 0 || len(data) <= c.config.CompressThreshold {
+		return value
+	}
+
+	var buf bytes.Buffer
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return value
+	}
+	if err := zw.Close(); err != nil {
+		return value
+	}
+	if buf.Len() >= len(data) {
+		return value
+	}
+	return compressedValue(buf.Bytes())
+}
+
+// decompress restores a value stored by compress. Other values are returned as is.
+func (c *Cache) decompress(value any) any {
+	compressed, ok := value.(compressedValue)
+	if !ok {
+		return value
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		c.logger.Warn("failed to decompress cache value", "err", err)
+		return nil
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		c.logger.Warn("failed to decompress cache value", "err", err)
+		return nil
+	}
+	return data
+}
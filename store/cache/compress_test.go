@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCacheCompression(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithCompression(1024))
+	defer cache.Close()
+
+	html := bytes.Repeat([]byte("<p>memo</p>"), 4096)
+	cache.Set(ctx, "render", html)
+	if stored := cache.Stats().Bytes; stored >= int64(len(html)) {
+		t.Errorf("Expected a compressed size below %d, got %d", len(html), stored)
+	}
+
+	val, ok := cache.Get(ctx, "render")
+	if got, isBytes := val.([]byte); !ok || !isBytes || !bytes.Equal(got, html) {
+		t.Errorf("Expected the original value back, got %T of length %d", val, len(got))
+	}
+	if values := cache.GetMulti(ctx, []string{"render"}); !bytes.Equal(values["render"].([]byte), html) {
+		t.Errorf("Expected GetMulti to decompress the value")
+	}
+}
+
+func TestCacheCompressionPassThrough(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithCompression(1024))
+	defer cache.Close()
+
+	small := bytes.Repeat([]byte("a"), 1024)
+	large := strings.Repeat("a", 4096)
+	cache.Set(ctx, "small", small)
+	cache.Set(ctx, "string", large)
+
+	want := int64(len(small) + len(large))
+	if stored := cache.Stats().Bytes; stored != want {
+		t.Errorf("Expected uncompressed size %d, got %d", want, stored)
+	}
+	if val, _ := cache.Get(ctx, "small"); !bytes.Equal(val.([]byte), small) {
+		t.Errorf("Expected the small value back unchanged")
+	}
+	if val, _ := cache.Get(ctx, "string"); val != large {
+		t.Errorf("Expected the string value back unchanged")
+	}
+}
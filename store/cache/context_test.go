@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanceledContextIsNoOp(t *testing.T) {
+	cache := NewDefault()
+	defer cache.Close()
+
+	background := context.Background()
+	cache.Set(background, "existing", "value")
+
+	ctx, cancel := context.WithCancel(background)
+	cancel()
+
+	if err := cache.Set(ctx, "new", "value"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Set to report cancellation, got %v", err)
+	}
+	if err := cache.SetWithTTL(ctx, "new", "value", time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected SetWithTTL to report cancellation, got %v", err)
+	}
+	if err := cache.SetMulti(ctx, map[string]any{"new": "value"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected SetMulti to report cancellation, got %v", err)
+	}
+	if err := cache.Delete(ctx, "existing"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Delete to report cancellation, got %v", err)
+	}
+	if err := cache.DeleteMulti(ctx, []string{"existing"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected DeleteMulti to report cancellation, got %v", err)
+	}
+	if err := cache.Clear(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Clear to report cancellation, got %v", err)
+	}
+	if _, ok, err := cache.Fetch(ctx, "existing"); ok || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Fetch to report cancellation, got exists: %v, err: %v", ok, err)
+	}
+	if _, ok := cache.Get(ctx, "existing"); ok {
+		t.Errorf("Expected Get with a canceled context to miss")
+	}
+	if _, err := cache.GetOrSet(ctx, "new", func(context.Context) (any, error) {
+		t.Errorf("Loader should not run with a canceled context")
+		return nil, nil
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected GetOrSet to report cancellation, got %v", err)
+	}
+
+	// Nothing was mutated or counted.
+	if _, ok := cache.Get(background, "new"); ok {
+		t.Errorf("Key 'new' should not have been stored")
+	}
+	if _, ok := cache.Get(background, "existing"); !ok {
+		t.Errorf("Key 'existing' should not have been deleted")
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Canceled calls should not touch counters, got %+v", stats)
+	}
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"reflect"
+)
+
+// Copier can be implemented by cached values to copy themselves for WithCopyOnGet,
+// when the default deep copy would be wrong or slow for them.
+type Copier interface {
+	// Copy returns a copy of the value that shares no mutable state with it.
+	Copy() any
+}
+
+// readValue returns a stored value the way reads hand it out: decompressed and,
+// with WithCopyOnGet, copied.
+func (c *Cache) readValue(value any) any {
+	return c.copyValue(c.decompress(value))
+}
+
+// copyValue returns a copy of value for the caller with WithCopyOnGet, and value
+// itself otherwise. A panicking Copy function is recovered and logged, and the caller
+// gets nil, so that it never shares the cached original by accident.
+func (c *Cache) copyValue(value any) (copied any) {
+	if !c.config.CopyOnGet || value == nil {
+		return value
+	}
+	fn := c.config.Copy
+	if fn == nil {
+		fn = DeepCopy
+	}
+	defer c.recoverPanic("copy function", nil)
+	return fn(value)
+}
+
+// DeepCopy returns a copy of value that shares no pointers, slices or maps with it,
+// following them recursively. Values implementing Copier copy themselves. Unexported
+// struct fields cannot be set through reflection, so they are copied shallowly, as are
+// channels and functions. Pointer cycles are preserved rather than followed forever.
+// It is the default copy function of WithCopyOnGet.
+func DeepCopy(value any) any {
+	if value == nil {
+		return nil
+	}
+	return deepCopy(reflect.ValueOf(value), make(map[uintptr]reflect.Value)).Interface()
+}
+
+var copierType = reflect.TypeFor[Copier]()
+
+// deepCopy copies v; seen maps the pointers copied so far to their copies.
+func deepCopy(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	if v.Type().Implements(copierType) && v.CanInterface() && !isNil(v) {
+		if copied := reflect.ValueOf(v.Interface().(Copier).Copy()); copied.IsValid() && copied.Type().AssignableTo(v.Type()) {
+			out := reflect.New(v.Type()).Elem()
+			out.Set(copied)
+			return out
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		if copied, ok := seen[v.Pointer()]; ok {
+			return copied
+		}
+		out := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = out
+		out.Elem().Set(deepCopy(v.Elem(), seen))
+		return out
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(deepCopy(v.Elem(), seen))
+		return out
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Cap())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return out
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			out.SetMapIndex(iter.Key(), deepCopy(iter.Value(), seen))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if field := out.Field(i); field.CanSet() {
+				field.Set(deepCopy(v.Field(i), seen))
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// isNil reports whether v is a nil pointer, interface, slice, map, channel or function.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Slice, reflect.Map, reflect.Chan, reflect.Func:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+type copyMemo struct {
+	ID     int
+	Tags   []string
+	Attrs  map[string]string
+	Parent *copyMemo
+}
+
+func TestCacheCopyOnGet(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithCopyOnGet(nil))
+	defer cache.Close()
+
+	memo := &copyMemo{ID: 1, Tags: []string{"a", "b"}, Attrs: map[string]string{"k": "v"}}
+	memo.Parent = memo
+	cache.Set(ctx, "memo", memo)
+
+	value, _ := cache.Get(ctx, "memo")
+	got := value.(*copyMemo)
+	if got == memo || got.Parent != got {
+		t.Fatalf("Expected a copy that keeps the pointer cycle, got %p with parent %p", got, got.Parent)
+	}
+	got.Tags[0] = "mutated"
+	got.Tags = append(got.Tags, "c")
+	got.Attrs["k"] = "mutated"
+
+	if !slices.Equal(memo.Tags, []string{"a", "b"}) || memo.Attrs["k"] != "v" {
+		t.Errorf("Expected the cached value to be unaffected, got %+v", memo)
+	}
+	value, _ = cache.Get(ctx, "memo")
+	if second := value.(*copyMemo); !slices.Equal(second.Tags, []string{"a", "b"}) || second.Attrs["k"] != "v" {
+		t.Errorf("Expected a second Get to be unaffected, got %+v", second)
+	}
+
+	// Loaded values are copied for the caller too.
+	loaded, err := cache.GetOrSet(ctx, "loaded", func(context.Context) (any, error) {
+		return []string{"x"}, nil
+	})
+	if err != nil {
+		t.Fatalf("GetOrSet failed: %v", err)
+	}
+	loaded.([]string)[0] = "mutated"
+	if value, _ := cache.Get(ctx, "loaded"); value.([]string)[0] != "x" {
+		t.Errorf("Expected the loaded value to be unaffected, got %v", value)
+	}
+}
+
+func TestCacheCopyOnGetCustomCopy(t *testing.T) {
+	ctx := context.Background()
+	copies := 0
+	cache := NewDefault(WithCopyOnGet(func(value any) any {
+		copies++
+		return slices.Clone(value.([]int))
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "key", []int{1})
+	value, _ := cache.Get(ctx, "key")
+	value.([]int)[0] = 2
+	if value, _ := cache.Get(ctx, "key"); value.([]int)[0] != 1 || copies != 2 {
+		t.Errorf("Expected the custom copy to protect the value, got %v after %d copies", value, copies)
+	}
+
+	plain := NewDefault()
+	defer plain.Close()
+	stored := []int{1}
+	plain.Set(ctx, "key", stored)
+	if value, _ := plain.Get(ctx, "key"); &value.([]int)[0] != &stored[0] {
+		t.Errorf("Expected values to be shared without WithCopyOnGet")
+	}
+}
+
+type selfCopier struct{ n *int }
+
+func (s selfCopier) Copy() any {
+	n := *s.n + 100
+	return selfCopier{n: &n}
+}
+
+func TestDeepCopy(t *testing.T) {
+	n := 1
+	arr := [2][]int{{1}, {2}}
+	copied := DeepCopy(map[string]any{"c": selfCopier{n: &n}, "arr": arr}).(map[string]any)
+	if got := *copied["c"].(selfCopier).n; got != 101 {
+		t.Errorf("Expected Copier to be used, got %d", got)
+	}
+	copied["arr"].([2][]int)[0][0] = 9
+	if arr[0][0] != 1 {
+		t.Errorf("Expected slices inside arrays to be copied")
+	}
+	if DeepCopy(nil) != nil {
+		t.Errorf("Expected DeepCopy(nil) to be nil")
+	}
+}
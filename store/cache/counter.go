@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Increment atomically adds delta to the integer stored at key and returns the new value.
+// A missing or expired key is created with the value delta and the default TTL;
+// an existing key keeps its TTL. If the stored value is not an integer, it is left
+// untouched and an error wrapping ErrNotInteger is returned. A read-only cache
+// returns ErrReadOnly under either ReadOnlyPolicy, and a disabled cache ErrDisabled.
+func (c *Cache) Increment(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if err := c.checkOpen(); err != nil {
+		return 0, err
+	}
+	if skip, _ := c.checkWritable(); skip {
+		// There is no new value to report without storing it, whatever the policy.
+		return 0, ErrReadOnly
+	}
+	if err := c.validateKey(key); err != nil {
+		return 0, err
+	}
+	now := c.now()
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	// SetEnabled empties the cache under every shard lock, so the flag is checked under one.
+	if atomic.LoadInt32(&c.disabled) != 0 {
+		s.mu.Unlock()
+		return 0, ErrDisabled
+	}
+	existing, ok := s.items[key]
+	if !ok || !existing.live(now) {
+		evicted := c.setLocked(s, c.newItem(key, delta, c.expiresAt(c.config.DefaultTTL)), nil)
+		s.mu.Unlock()
+
+		evicted = c.evictOverflow(s, evicted)
+		c.notifyEvicted(evicted)
+		return delta, nil
+	}
+	current, ok := toInt64(existing.value)
+	if !ok {
+		value := existing.value
+		s.mu.Unlock()
+		return 0, errors.Wrapf(ErrNotInteger, "key %q holds %T", key, value)
+	}
+	// The sum is stored as a replacement item, so that its size or weight is counted
+	// afresh against MaxBytes; it keeps the TTL, grace window, tags and age of the old one.
+	itm := c.newItem(key, current+delta, time.Time{})
+	itm.expiration, itm.staleUntil, itm.ttl, itm.tags = existing.expiration, existing.staleUntil, existing.ttl, existing.tags
+	itm.createdAt = existing.createdAt
+	evicted := c.setLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
+	return current + delta, nil
+}
+
+// Decrement atomically subtracts delta from the integer stored at key and returns the new value.
+// It behaves like Increment with a negated delta.
+func (c *Cache) Decrement(ctx context.Context, key string, delta int64) (int64, error) {
+	return c.Increment(ctx, key, -delta)
+}
+
+// toInt64 converts any Go integer type to int64.
+func toInt64(value any) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestCacheIncrement(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	if v, err := cache.Increment(ctx, "views", 5); err != nil || v != 5 {
+		t.Errorf("Expected 5, got %d, err: %v", v, err)
+	}
+	if v, err := cache.Decrement(ctx, "views", 2); err != nil || v != 3 {
+		t.Errorf("Expected 3, got %d, err: %v", v, err)
+	}
+
+	// Existing integers of any width are adjusted.
+	cache.Set(ctx, "limit", int32(10))
+	if v, err := cache.Increment(ctx, "limit", 1); err != nil || v != 11 {
+		t.Errorf("Expected 11, got %d, err: %v", v, err)
+	}
+
+	// Non-integers are rejected without corrupting the entry.
+	cache.Set(ctx, "name", "memos")
+	if _, err := cache.Increment(ctx, "name", 1); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Expected ErrNotInteger, got %v", err)
+	}
+	if v, ok := cache.Get(ctx, "name"); !ok || v != "memos" {
+		t.Errorf("Expected 'memos' to be untouched, got %v", v)
+	}
+}
+
+func TestCacheIncrementPreservesTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "rate", 1, 20*time.Millisecond)
+	clock.Advance(10 * time.Millisecond)
+	if _, err := cache.Increment(ctx, "rate", 1); err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+	clock.Advance(10*time.Millisecond + time.Nanosecond)
+	if _, ok := cache.Get(ctx, "rate"); ok {
+		t.Errorf("Increment should not extend the TTL")
+	}
+}
+
+func TestCacheIncrementReweighs(t *testing.T) {
+	ctx := context.Background()
+	// Weigh counters by their value, so that the budget tracks what they hold.
+	weigher := func(_ string, value any) int64 {
+		n, _ := toInt64(value)
+		return n
+	}
+	cache := NewDefault(WithWeigher(weigher), WithDebugChecks(true))
+	defer cache.Close()
+
+	cache.Increment(ctx, "views", 10)
+	if _, err := cache.Increment(ctx, "views", 15); err != nil {
+		t.Fatalf("Increment failed: %v", err)
+	}
+	if bytes := cache.Stats().Bytes; bytes != 25 {
+		t.Errorf("Expected the counter to weigh 25 after the increment, got %d", bytes)
+	}
+	if err := cache.Verify(); err != nil {
+		t.Errorf("Expected consistent accounting, got %v", err)
+	}
+}
+
+func TestCacheIncrementConcurrent(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	const goroutines = 50
+	const increments = 200
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < increments; j++ {
+				cache.Increment(ctx, "counter", 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if v, _ := cache.Get(ctx, "counter"); v != int64(goroutines*increments) {
+		t.Errorf("Expected %d, got %v", goroutines*increments, v)
+	}
+}
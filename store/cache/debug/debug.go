@@ -0,0 +1,171 @@
+// Package debug exposes a cache over HTTP for operators: its statistics, its keys,
+// the metadata of single entries, and endpoints to clear it, guarded by a token.
+package debug
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+const (
+	// defaultPageSize is the number of keys listed when the request sets no limit.
+	defaultPageSize = 100
+	// maxPageSize bounds the number of keys listed in one response.
+	maxPageSize = 1000
+)
+
+// Handler serves the debug endpoints of one cache. Every request must carry the
+// token given to NewHandler as "Authorization: Bearer <token>". The endpoints are:
+//
+//	GET  /stats                         the cache statistics
+//	GET  /keys?offset=0&limit=100       a page of the live keys, in ascending order
+//	GET  /entry?key=K                   the metadata of one entry; see cache.Cache.Inspect
+//	POST /clear                         removes every entry
+//	POST /delete-prefix?prefix=P        removes the entries whose key starts with P
+//
+// Mount it under a path of its own with http.StripPrefix.
+type Handler struct {
+	cache *cache.Cache
+	token string
+	mux   *http.ServeMux
+}
+
+var _ http.Handler = (*Handler)(nil)
+
+// NewHandler creates a debug handler for c that only answers requests carrying token.
+// An empty token rejects every request.
+func NewHandler(c *cache.Cache, token string) *Handler {
+	h := &Handler{cache: c, token: token, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /stats", h.stats)
+	h.mux.HandleFunc("GET /keys", h.keys)
+	h.mux.HandleFunc("GET /entry", h.entry)
+	h.mux.HandleFunc("POST /clear", h.clear)
+	h.mux.HandleFunc("POST /delete-prefix", h.deletePrefix)
+	return h
+}
+
+// ServeHTTP authenticates the request and dispatches it to its endpoint.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !h.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && h.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+func (h *Handler) stats(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, h.cache.Stats())
+}
+
+// KeysPage is the response of the keys endpoint.
+type KeysPage struct {
+	Keys  []string `json:"keys"`
+	Total int      `json:"total"`
+	// Next is the offset of the next page, or zero on the last page.
+	Next int `json:"next,omitempty"`
+}
+
+func (h *Handler) keys(w http.ResponseWriter, r *http.Request) {
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultPageSize)
+	if err != nil || limit <= 0 {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	limit = min(limit, maxPageSize)
+
+	keys := h.cache.SortedKeys()
+	page := KeysPage{Keys: []string{}, Total: len(keys)}
+	if offset < len(keys) {
+		end := min(offset+limit, len(keys))
+		page.Keys = keys[offset:end]
+		if end < len(keys) {
+			page.Next = end
+		}
+	}
+	writeJSON(w, page)
+}
+
+func (h *Handler) entry(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "missing key", http.StatusBadRequest)
+		return
+	}
+	info, ok := h.cache.Inspect(r.Context(), key)
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, info)
+}
+
+func (h *Handler) clear(w http.ResponseWriter, r *http.Request) {
+	if err := h.cache.Clear(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) deletePrefix(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		// An empty prefix would match every key; /clear says so explicitly.
+		http.Error(w, "missing prefix", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, map[string]int{"deleted": h.cache.DeletePrefix(r.Context(), prefix)})
+}
+
+func queryInt(r *http.Request, name string, fallback int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func writeJSON(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// ClearOnSignal clears c every time the process receives one of signals, such as
+// syscall.SIGUSR1, so operators can flush a running instance without a restart.
+// It stops listening when ctx is done.
+func ClearOnSignal(ctx context.Context, c *cache.Cache, signals ...os.Signal) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				c.Clear(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,113 @@
+package debug
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+const testToken = "s3cret"
+
+func do(t *testing.T, h http.Handler, method, target, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func newTestCache(t *testing.T) *cache.Cache {
+	t.Helper()
+	c := cache.NewDefault()
+	t.Cleanup(func() { c.Close() })
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		c.Set(ctx, fmt.Sprintf("memo:%d", i), i)
+	}
+	c.Set(ctx, "user:1", "alice")
+	return c
+}
+
+func TestHandlerAuth(t *testing.T) {
+	h := NewHandler(newTestCache(t), testToken)
+	for _, token := range []string{"", "wrong"} {
+		rec := do(t, h, http.MethodGet, "/stats", token)
+		require.Equal(t, http.StatusUnauthorized, rec.Code, "token %q", token)
+	}
+	rec := do(t, h, http.MethodPost, "/clear", "wrong")
+	require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Without a configured token nothing is served.
+	open := NewHandler(newTestCache(t), "")
+	require.Equal(t, http.StatusUnauthorized, do(t, open, http.MethodGet, "/stats", "").Code)
+}
+
+func TestHandlerStats(t *testing.T) {
+	c := newTestCache(t)
+	c.Get(context.Background(), "memo:1")
+	rec := do(t, NewHandler(c, testToken), http.MethodGet, "/stats", testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var stats cache.Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	require.Equal(t, int64(6), stats.ItemCount)
+	require.Equal(t, int64(1), stats.Hits)
+}
+
+func TestHandlerKeys(t *testing.T) {
+	h := NewHandler(newTestCache(t), testToken)
+
+	var page KeysPage
+	rec := do(t, h, http.MethodGet, "/keys?limit=4", testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, KeysPage{Keys: []string{"memo:0", "memo:1", "memo:2", "memo:3"}, Total: 6, Next: 4}, page)
+
+	page = KeysPage{}
+	rec = do(t, h, http.MethodGet, "/keys?offset=4&limit=4", testToken)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Equal(t, KeysPage{Keys: []string{"memo:4", "user:1"}, Total: 6}, page)
+
+	require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/keys?limit=x", testToken).Code)
+}
+
+func TestHandlerEntry(t *testing.T) {
+	h := NewHandler(newTestCache(t), testToken)
+
+	rec := do(t, h, http.MethodGet, "/entry?key=user:1", testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var info cache.EntryInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	require.False(t, info.CreatedAt.IsZero())
+
+	require.Equal(t, http.StatusNotFound, do(t, h, http.MethodGet, "/entry?key=missing", testToken).Code)
+	require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodGet, "/entry", testToken).Code)
+}
+
+func TestHandlerClearAndDeletePrefix(t *testing.T) {
+	c := newTestCache(t)
+	h := NewHandler(c, testToken)
+
+	// Mutations require POST.
+	require.Equal(t, http.StatusMethodNotAllowed, do(t, h, http.MethodGet, "/clear", testToken).Code)
+
+	rec := do(t, h, http.MethodPost, "/delete-prefix?prefix=memo:", testToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"deleted":5}`, rec.Body.String())
+	require.Equal(t, 1, c.Len())
+	require.Equal(t, http.StatusBadRequest, do(t, h, http.MethodPost, "/delete-prefix", testToken).Code)
+
+	rec = do(t, h, http.MethodPost, "/clear", testToken)
+	require.Equal(t, http.StatusNoContent, rec.Code)
+	require.Equal(t, 0, c.Len())
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package debug
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClearOnSignal(t *testing.T) {
+	c := newTestCache(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ClearOnSignal(ctx, c, syscall.SIGUSR1)
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	require.Eventually(t, func() bool { return c.Len() == 0 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+
+	"github.com/pkg/errors"
+)
+
+// EncryptedCodec is a Codec that encrypts the output of another Codec with AES-GCM,
+// for values that leave the process, such as in a RedisCache or a snapshot.
+// Every value is sealed with its own random nonce, which is stored in front of it.
+type EncryptedCodec struct {
+	codec Codec
+	aead  cipher.AEAD
+}
+
+var _ Codec = (*EncryptedCodec)(nil)
+
+// NewEncryptedCodec wraps codec, or JSONCodec if it is nil, to encrypt values with key,
+// which must be 16, 24 or 32 bytes long to select AES-128, AES-192 or AES-256.
+func NewEncryptedCodec(codec Codec, key []byte) (*EncryptedCodec, error) {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid cache encryption key")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create AES-GCM cipher")
+	}
+	return &EncryptedCodec{codec: codec, aead: aead}, nil
+}
+
+// Marshal encodes a value with the wrapped Codec and encrypts the result.
+func (e *EncryptedCodec) Marshal(value any) ([]byte, error) {
+	plaintext, err := e.codec.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, e.aead.NonceSize(), e.aead.NonceSize()+len(plaintext)+e.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Unmarshal decrypts data and decodes it with the wrapped Codec.
+// It returns an error wrapping ErrDecrypt if data cannot be decrypted.
+func (e *EncryptedCodec) Unmarshal(data []byte, value any) error {
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize {
+		return errors.Wrap(ErrDecrypt, "ciphertext too short")
+	}
+	plaintext, err := e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return errors.Wrap(ErrDecrypt, err.Error())
+	}
+	return e.codec.Unmarshal(plaintext, value)
+}
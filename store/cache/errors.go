@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"github.com/pkg/errors"
+)
+
+// The errors returned by this package. Most are wrapped with details such as the key
+// involved, so match them with errors.Is rather than comparing them. The simple
+// in-memory lookups report a missing key with a bool instead; the loader paths and the
+// network backends, which can fail for real, report it with ErrNotFound.
+var (
+	// ErrNotFound reports that a key does not exist. A LoadingCache loader returns it
+	// for a missing key; with WithNegativeTTL, the LoadingCache remembers it and answers
+	// later lookups of the key with ErrNotFound without calling the loader. LoadMulti
+	// reports the keys its loader did not find with it.
+	ErrNotFound = errors.New("cache: not found")
+
+	// ErrClosed is returned by writes and loads on a cache after Close, and by a
+	// RedisCache whose client is closed.
+	ErrClosed = errors.New("cache: closed")
+
+	// ErrReadOnly is returned by writes to a cache or RedisCache that is read-only
+	// under ReadOnlyReject.
+	ErrReadOnly = errors.New("cache: read-only")
+
+	// ErrValueTooLarge is returned by writes of a value larger than MaxValueBytes.
+	ErrValueTooLarge = errors.New("cache: value too large")
+
+	// ErrTagLimit is returned by SetWithTags for an entry that would exceed
+	// MaxTagsPerEntry or MaxKeysPerTag.
+	ErrTagLimit = errors.New("cache: tag limit exceeded")
+
+	// ErrInconsistent is returned by Verify when the bookkeeping of the cache does not
+	// match its contents.
+	ErrInconsistent = errors.New("cache: internal state is inconsistent")
+
+	// ErrDebugChecksDisabled is returned by Verify on a cache created without WithDebugChecks.
+	ErrDebugChecksDisabled = errors.New("cache: debug checks are disabled")
+
+	// ErrReentrantLoad is returned when a loader asks, through the context it was given,
+	// for a key that it is itself loading, which would otherwise wait on itself forever.
+	ErrReentrantLoad = errors.New("cache: re-entrant load of a key being loaded")
+
+	// ErrDisabled is returned by Increment and Decrement on a cache turned off with
+	// SetEnabled, which drops writes and so has no count to report.
+	ErrDisabled = errors.New("cache: disabled")
+
+	// ErrNotInteger is returned by Increment and Decrement when the stored value is not an integer.
+	ErrNotInteger = errors.New("cache: value is not an integer")
+
+	// ErrPanic is wrapped by the error returned when a user-supplied function, such as
+	// a loader or a key validator, panics and the panic is recovered.
+	ErrPanic = errors.New("cache: recovered from panic")
+
+	// ErrCircuitOpen is returned by a CircuitBreaker while it rejects calls
+	// without reaching its backend.
+	ErrCircuitOpen = errors.New("cache: circuit breaker is open")
+
+	// ErrDecrypt is wrapped by the error an EncryptedCodec returns for data it cannot
+	// decrypt, for example because it was encrypted with another key or tampered with.
+	ErrDecrypt = errors.New("cache: failed to decrypt value")
+
+	// ErrRingEmpty is returned by a RingCache that has no nodes to route to.
+	ErrRingEmpty = errors.New("cache: ring has no nodes")
+)
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	ctx := context.Background()
+
+	closedCache := func() *Cache {
+		cache := NewDefault()
+		cache.Close()
+		return cache
+	}
+	load := func(context.Context) (any, error) { return "value", nil }
+
+	tests := []struct {
+		name string
+		want error
+		call func() error
+	}{
+		{"Set after Close", ErrClosed, func() error {
+			return closedCache().Set(ctx, "key", "value")
+		}},
+		{"GetOrSet after Close", ErrClosed, func() error {
+			_, err := closedCache().GetOrSet(ctx, "key", load)
+			return err
+		}},
+		{"GetMultiOrLoad after Close", ErrClosed, func() error {
+			_, err := closedCache().GetMultiOrLoad(ctx, []string{"key"}, func(context.Context, []string) (map[string]any, error) {
+				return nil, nil
+			})
+			return err
+		}},
+		{"Redis after Close", ErrClosed, func() error {
+			redis := NewRedis(RedisConfig{Addr: "127.0.0.1:0"})
+			redis.Close()
+			_, _, err := redis.Fetch(ctx, "key")
+			return err
+		}},
+		{"SetWithTTL of an oversized value", ErrValueTooLarge, func() error {
+			cache := NewDefault(WithMaxValueBytes(8))
+			defer cache.Close()
+			return cache.SetWithTTL(ctx, "key", sizedValue(9), time.Minute)
+		}},
+		{"GetOrSet from its own loader", ErrReentrantLoad, func() error {
+			cache := NewDefault()
+			defer cache.Close()
+			_, err := cache.GetOrSet(ctx, "key", func(ctx context.Context) (any, error) {
+				return cache.GetOrSet(ctx, "key", load)
+			})
+			return err
+		}},
+		{"LoadingCache of a missing key", ErrNotFound, func() error {
+			cache := NewLoadingCache(func(context.Context, string) (any, error) { return nil, ErrNotFound }, time.Minute)
+			defer cache.Close()
+			_, err := cache.Get(ctx, "key")
+			return err
+		}},
+		{"LoadMulti of a missing key", ErrNotFound, func() error {
+			cache := NewDefault()
+			defer cache.Close()
+			_, errs, _ := cache.LoadMulti(ctx, []string{"key"}, func(context.Context, []string) (map[string]any, map[string]error, error) {
+				return nil, nil, nil
+			})
+			return errs["key"]
+		}},
+		{"Increment of a string", ErrNotInteger, func() error {
+			cache := NewDefault()
+			defer cache.Close()
+			cache.Set(ctx, "key", "text")
+			_, err := cache.Increment(ctx, "key", 1)
+			return err
+		}},
+		{"GetOrSet with a panicking loader", ErrPanic, func() error {
+			cache := NewDefault()
+			defer cache.Close()
+			_, err := cache.GetOrSet(ctx, "key", func(context.Context) (any, error) { panic("boom") })
+			return err
+		}},
+		{"CircuitBreaker once open", ErrCircuitOpen, func() error {
+			breaker := NewCircuitBreaker(closedCache(), BreakerConfig{FailureThreshold: 1})
+			breaker.Put(ctx, "key", "value", time.Minute)
+			return breaker.Put(ctx, "key", "value", time.Minute)
+		}},
+		{"EncryptedCodec with the wrong key", ErrDecrypt, func() error {
+			sealer, _ := NewEncryptedCodec(JSONCodec{}, make([]byte, 32))
+			opener, _ := NewEncryptedCodec(JSONCodec{}, []byte("0123456789abcdef0123456789abcdef"))
+			data, _ := sealer.Marshal("value")
+			var value any
+			return opener.Unmarshal(data, &value)
+		}},
+		{"RingCache without nodes", ErrRingEmpty, func() error {
+			return NewRing(nil, RingConfig{}).Put(ctx, "key", "value", time.Minute)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected an error matching %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before events are dropped.
+const subscriberBuffer = 256
+
+// EventType is the kind of cache operation an event describes.
+type EventType int
+
+const (
+	// EventSet means a value was stored.
+	EventSet EventType = iota
+	// EventGetHit means a lookup found a live value.
+	EventGetHit
+	// EventGetMiss means a lookup found nothing usable.
+	EventGetMiss
+	// EventDelete means a value was removed explicitly.
+	EventDelete
+	// EventEvict means a value left the cache for any other reason.
+	EventEvict
+)
+
+// String returns the name of the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventGetHit:
+		return "get-hit"
+	case EventGetMiss:
+		return "get-miss"
+	case EventDelete:
+		return "delete"
+	case EventEvict:
+		return "evict"
+	}
+	return "unknown"
+}
+
+// CacheEvent describes one cache operation, for debugging.
+type CacheEvent struct {
+	Type EventType
+	Key  string
+	// Reason is why the value left the cache, for EventDelete and EventEvict.
+	Reason EvictReason
+}
+
+// eventBus delivers events to subscribers without ever blocking the cache.
+type eventBus struct {
+	// count mirrors len(subscribers) so publishing is free when nobody listens.
+	count int32
+
+	mu          sync.RWMutex
+	subscribers map[chan CacheEvent]struct{}
+	closed      bool
+}
+
+// active reports whether anybody is subscribed.
+func (b *eventBus) active() bool {
+	return atomic.LoadInt32(&b.count) > 0
+}
+
+// closeAll unsubscribes everybody and refuses new subscribers.
+func (b *eventBus) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	atomic.StoreInt32(&b.count, 0)
+	b.closed = true
+}
+
+// Subscribe returns a channel of the operations performed on the cache and a function
+// that ends the subscription and closes the channel. Events of a single goroutine
+// arrive in order. Delivery never blocks the cache: if the subscriber falls behind,
+// events are dropped and counted by DroppedEvents. Close ends every subscription.
+func (c *Cache) Subscribe() (<-chan CacheEvent, func()) {
+	ch := make(chan CacheEvent, subscriberBuffer)
+
+	b := &c.events
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	b.subscribers[ch] = struct{}{}
+	atomic.AddInt32(&b.count, 1)
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			atomic.AddInt32(&b.count, -1)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// DroppedEvents returns how many events were dropped because a subscriber fell behind.
+func (c *Cache) DroppedEvents() int64 {
+	return atomic.LoadInt64(&c.droppedEvents)
+}
+
+// publish hands an event to every subscriber that has room for it.
+func (c *Cache) publish(event CacheEvent) {
+	b := &c.events
+	if !b.active() {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			atomic.AddInt64(&c.droppedEvents, 1)
+		}
+	}
+}
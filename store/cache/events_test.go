@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCacheSubscribe(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(1, WithShards(1))
+	defer cache.Close()
+
+	events, unsubscribe := cache.Subscribe()
+	other, unsubscribeOther := cache.Subscribe()
+	defer unsubscribeOther()
+
+	cache.Set(ctx, "memo:1", "v1")
+	cache.Get(ctx, "memo:1")
+	cache.Get(ctx, "missing")
+	cache.Set(ctx, "memo:2", "v2") // evicts memo:1 for capacity
+	cache.Delete(ctx, "memo:2")
+	unsubscribe()
+
+	want := []CacheEvent{
+		{Type: EventSet, Key: "memo:1"},
+		{Type: EventGetHit, Key: "memo:1"},
+		{Type: EventGetMiss, Key: "missing"},
+		{Type: EventSet, Key: "memo:2"},
+		{Type: EventEvict, Key: "memo:1", Reason: EvictReasonCapacity},
+		{Type: EventDelete, Key: "memo:2", Reason: EvictReasonDeleted},
+	}
+	var got []CacheEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected events %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected event %d to be %v, got %v", i, want[i], got[i])
+		}
+	}
+
+	// Each subscriber gets its own copy of the stream.
+	if len(other) != len(want) {
+		t.Errorf("Expected the second subscriber to get %d events, got %d", len(want), len(other))
+	}
+}
+
+func TestCacheSubscribeDropsWhenSlow(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	events, unsubscribe := cache.Subscribe()
+	defer unsubscribe()
+
+	// Nobody reads, so everything past the buffer is dropped without blocking.
+	for i := 0; i < subscriberBuffer+10; i++ {
+		cache.Set(ctx, "key", i)
+	}
+	if dropped := cache.DroppedEvents(); dropped != 10 {
+		t.Errorf("Expected 10 dropped events, got %d", dropped)
+	}
+	if len(events) != subscriberBuffer {
+		t.Errorf("Expected a full buffer of %d events, got %d", subscriberBuffer, len(events))
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// expiryHeap is a min-heap of items ordered by the time they become dead, that is the
+// end of their grace window or else their expiration. Each shard keeps one when the
+// SweepExpiryHeap strategy is selected, holding every item of the shard that expires.
+type expiryHeap []*item
+
+func (h expiryHeap) Len() int { return len(h) }
+
+func (h expiryHeap) Less(i, j int) bool {
+	return h[i].deadline().Before(h[j].deadline())
+}
+
+func (h expiryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapPos = i + 1
+	h[j].heapPos = j + 1
+}
+
+func (h *expiryHeap) Push(x any) {
+	itm := x.(*item)
+	*h = append(*h, itm)
+	itm.heapPos = len(*h)
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	itm := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	itm.heapPos = 0
+	return itm
+}
+
+// deadline returns the time after which the item is dead; see dead.
+func (i *item) deadline() time.Time {
+	if !i.staleUntil.IsZero() {
+		return i.staleUntil
+	}
+	return i.expiration
+}
+
+// trackExpiry adds an item that was just stored to the expiry heap, if the shard
+// keeps one and the item expires. The caller must hold s.mu.
+func (s *shard) trackExpiry(itm *item) {
+	if s.expiry == nil || itm.expiration.IsZero() {
+		return
+	}
+	heap.Push(s.expiry, itm)
+}
+
+// untrackExpiry drops an item that is being removed from the expiry heap.
+// The caller must hold s.mu.
+func (s *shard) untrackExpiry(itm *item) {
+	if itm.heapPos > 0 {
+		heap.Remove(s.expiry, itm.heapPos-1)
+	}
+}
+
+// retrackExpiry repositions an item whose expiration changed in place, as Touch and
+// sliding expiration do. The caller must hold s.mu.
+func (s *shard) retrackExpiry(itm *item) {
+	switch {
+	case itm.heapPos == 0:
+		s.trackExpiry(itm)
+	case itm.expiration.IsZero():
+		s.untrackExpiry(itm)
+	default:
+		heap.Fix(s.expiry, itm.heapPos-1)
+	}
+}
+
+// sweepExpiryHeap removes the dead items at the top of each shard's expiry heap,
+// so it only visits items that have actually expired. It returns the fraction of
+// the items in the cache that had expired.
+func (c *Cache) sweepExpiryHeap() float64 {
+	now := c.now()
+	total := atomic.LoadInt64(&c.itemCount)
+
+	var evicted []evictedItem
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for s.expiry.Len() > 0 && (*s.expiry)[0].dead(now) {
+			evicted = c.dropLocked(s, (*s.expiry)[0], EvictReasonExpired, evicted)
+		}
+		s.mu.Unlock()
+	}
+
+	if len(evicted) > 0 {
+		atomic.AddInt64(&c.counters.Load().evictions, int64(len(evicted)))
+		c.notifyEvicted(evicted)
+	}
+	if total <= 0 {
+		return 0
+	}
+	return float64(len(evicted)) / float64(total)
+}
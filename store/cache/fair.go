@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"sync"
+)
+
+// fairScanLimit bounds how many items from the back of a shard's LRU list fair eviction
+// looks through for an item of the tenant most over its share.
+const fairScanLimit = 64
+
+// tenantUsage tracks how much of the cache each tenant holds, for fair eviction.
+// Usage is counted in items, or in bytes for a cache bounded by MaxBytes only.
+type tenantUsage struct {
+	mu    sync.Mutex
+	usage map[string]int64
+}
+
+// add adjusts the usage of a tenant, forgetting tenants that hold nothing.
+func (t *tenantUsage) add(tenant string, delta int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.usage[tenant] += delta; t.usage[tenant] <= 0 {
+		delete(t.usage, tenant)
+	}
+}
+
+// mostOverShare returns the tenant whose usage is largest relative to its weight,
+// that is the one furthest over its fair share of the cache.
+func (t *tenantUsage) mostOverShare(weights map[string]float64) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var (
+		worst string
+		ratio float64
+		found bool
+	)
+	for tenant, usage := range t.usage {
+		weight := weights[tenant]
+		if weight <= 0 {
+			weight = 1
+		}
+		if r := float64(usage) / weight; !found || r > ratio {
+			worst, ratio, found = tenant, r, true
+		}
+	}
+	return worst, found
+}
+
+// resetTenants forgets the usage of every tenant, as Clear empties the cache.
+func (c *Cache) resetTenants() {
+	if c.tenants == nil {
+		return
+	}
+	c.tenants.mu.Lock()
+	c.tenants.usage = make(map[string]int64)
+	c.tenants.mu.Unlock()
+}
+
+// tenantCost is the usage an item counts for against its tenant's share.
+func (c *Cache) tenantCost(itm *item) int64 {
+	if c.config.MaxItems == 0 && c.config.MaxBytes > 0 {
+		return itm.size
+	}
+	return 1
+}
+
+// trackTenant counts an item that was just stored against its tenant.
+func (c *Cache) trackTenant(itm *item) {
+	if c.tenants == nil {
+		return
+	}
+	itm.tenant = c.config.TenantOf(itm.key)
+	c.tenants.add(itm.tenant, c.tenantCost(itm))
+}
+
+// untrackTenant uncounts an item that is being removed.
+func (c *Cache) untrackTenant(itm *item) {
+	if c.tenants == nil {
+		return
+	}
+	c.tenants.add(itm.tenant, -c.tenantCost(itm))
+}
+
+// victimLocked picks the item of a non-empty shard to evict for capacity: the least
+// recently used one, or with fair eviction the least recently used item of the tenant
+// most over its share among the last items of the shard, never the most recently used
+// item of the shard. The caller must hold s.mu.
+func (c *Cache) victimLocked(s *shard) *item {
+	victim := s.lru.back()
+	if c.tenants == nil {
+		return victim
+	}
+	tenant, ok := c.tenants.mostOverShare(c.config.TenantWeights)
+	if !ok {
+		return victim
+	}
+	itm := victim
+	for i := 0; i < fairScanLimit && itm != nil && itm != s.lru.head; i++ {
+		if itm.tenant == tenant {
+			return itm
+		}
+		itm = itm.prev
+	}
+	return victim
+}
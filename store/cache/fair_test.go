@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func tenantOf(key string) string {
+	tenant, _, _ := strings.Cut(key, ":")
+	return tenant
+}
+
+// countTenant returns the number of entries of a tenant left in the cache.
+func countTenant(cache *Cache, tenant string) int {
+	n := 0
+	for _, key := range cache.Keys() {
+		if tenantOf(key) == tenant {
+			n++
+		}
+	}
+	return n
+}
+
+func TestFairEvictionProtectsQuietTenant(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(100, WithShards(1), WithFairEviction(tenantOf, nil))
+	defer cache.Close()
+
+	for i := 0; i < 20; i++ {
+		cache.Set(ctx, fmt.Sprintf("quiet:%d", i), i)
+	}
+	// The busy tenant writes and reads far more, which under plain LRU would push
+	// every quiet entry out.
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("busy:%d", i)
+		cache.Set(ctx, key, i)
+		cache.Get(ctx, key)
+	}
+
+	if n := countTenant(cache, "quiet"); n != 20 {
+		t.Errorf("Expected the quiet tenant, under its fair share, to keep all 20 entries, kept %d", n)
+	}
+	if cache.Size() != 100 {
+		t.Errorf("Expected the cache to stay full, got %d entries", cache.Size())
+	}
+
+	// A tenant over its share loses entries until it is back to it.
+	for i := 20; i < 200; i++ {
+		cache.Set(ctx, fmt.Sprintf("quiet:%d", i), i)
+	}
+	if n := countTenant(cache, "quiet"); n < 45 || n > 55 {
+		t.Errorf("Expected two equally busy tenants to split the cache, quiet kept %d", n)
+	}
+}
+
+func TestFairEvictionWeights(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(100, WithShards(1), WithFairEviction(tenantOf, map[string]float64{"busy": 3}))
+	defer cache.Close()
+
+	for i := 0; i < 500; i++ {
+		cache.Set(ctx, fmt.Sprintf("busy:%d", i), i)
+		cache.Set(ctx, fmt.Sprintf("quiet:%d", i), i)
+	}
+	if n := countTenant(cache, "quiet"); n < 20 || n > 30 {
+		t.Errorf("Expected the quiet tenant to keep about a quarter of the cache, kept %d", n)
+	}
+}
+
+func TestFairEvictionClear(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(10, WithShards(1), WithFairEviction(tenantOf, nil))
+	defer cache.Close()
+
+	cache.Set(ctx, "a:1", 1)
+	cache.Clear(ctx)
+	if _, ok := cache.tenants.mostOverShare(nil); ok {
+		t.Errorf("Expected Clear to reset tenant usage")
+	}
+}
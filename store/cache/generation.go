@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"sync/atomic"
+)
+
+// generation is a namespace that every stored item belongs to. Retiring it
+// invalidates all of its items at once; they then read as expired and are removed
+// lazily, by reads, writes and the janitor.
+type generation struct {
+	n       int64
+	retired int32
+}
+
+// retired reports whether the item belongs to a generation that was bumped away from.
+func (i *item) retired() bool {
+	return i.gen != nil && atomic.LoadInt32(&i.gen.retired) != 0
+}
+
+// Generation returns the current generation of the cache; see WithGeneration.
+func (c *Cache) Generation() int64 {
+	return c.generation.Load().n
+}
+
+// BumpGeneration starts a new generation and returns its number. Every entry written
+// before the call stops matching in O(1), for example after a schema change makes
+// cached values unreadable, and the memory it holds is reclaimed as the entries are
+// found: on access, by eviction, or by the next janitor pass, which scans the whole
+// cache once whatever its SweepStrategy.
+func (c *Cache) BumpGeneration() int64 {
+	for {
+		old := c.generation.Load()
+		next := &generation{n: old.n + 1}
+		if c.generation.CompareAndSwap(old, next) {
+			atomic.StoreInt32(&old.retired, 1)
+			atomic.AddInt32(&c.retiring, 1)
+			// Replaying the append-only log must not bring retired entries back.
+			c.logClear()
+			return next.n
+		}
+	}
+}
+
+// sweepRetired removes the items of retired generations by a full cleanup if any may
+// remain, and reports whether it ran, along with the fraction of items it removed.
+func (c *Cache) sweepRetired() (swept bool, fraction float64) {
+	bumps := atomic.LoadInt32(&c.retiring)
+	if bumps == 0 {
+		return false, 0
+	}
+	fraction = c.cleanup()
+	// Size counts retired items as live once the count is cleared, so it may only be
+	// cleared after the scan. A concurrent bump keeps it set for the next pass.
+	atomic.CompareAndSwapInt32(&c.retiring, bumps, 0)
+	return true, fraction
+}
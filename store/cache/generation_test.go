@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCacheBumpGeneration(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithGeneration(1), WithSweepStrategy(SweepSampled))
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(ctx, fmt.Sprintf("memo:%d", i), "v1 layout")
+	}
+	if got := cache.Generation(); got != 1 {
+		t.Fatalf("Expected generation 1, got %d", got)
+	}
+
+	if got := cache.BumpGeneration(); got != 2 {
+		t.Errorf("Expected BumpGeneration to return 2, got %d", got)
+	}
+	if _, ok := cache.Get(ctx, "memo:0"); ok {
+		t.Errorf("Expected an entry of generation 1 to miss after BumpGeneration")
+	}
+	if _, ok := cache.Peek(ctx, "memo:1"); ok {
+		t.Errorf("Expected Peek to miss on an entry of generation 1")
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected no live entries after BumpGeneration, got %d", cache.Size())
+	}
+
+	// Writes after the bump are visible and replace the retired entries.
+	cache.Set(ctx, "memo:2", "v2 layout")
+	if v, ok := cache.Get(ctx, "memo:2"); !ok || v != "v2 layout" {
+		t.Errorf("Expected the new generation's value, got %v, %v", v, ok)
+	}
+
+	// The next janitor pass reclaims the remaining retired entries, even with a
+	// strategy that would not otherwise visit items without a TTL.
+	cache.sweep(cache.config.CleanupInterval)
+	if got := atomic.LoadInt64(&cache.itemCount); got != 1 {
+		t.Errorf("Expected only the new entry to remain stored, got %d", got)
+	}
+	if cache.Size() != 1 {
+		t.Errorf("Expected 1 live entry, got %d", cache.Size())
+	}
+}
+
+func TestCacheBumpGenerationWithoutJanitor(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.CleanupInterval = 0
+	cache := New(config)
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(ctx, fmt.Sprintf("memo:%d", i), "v1 layout")
+	}
+	cache.BumpGeneration()
+	if n := cache.Size(); n != 0 {
+		t.Errorf("Expected no live entries right after BumpGeneration, got %d", n)
+	}
+
+	// A pass of the full scan janitor clears the bump only once the retired entries are gone.
+	cache.sweepRetired()
+	if n := cache.Size(); n != 0 {
+		t.Errorf("Expected no live entries after sweeping the retired generation, got %d", n)
+	}
+	if n := atomic.LoadInt64(&cache.itemCount); n != 0 {
+		t.Errorf("Expected the retired entries to be removed, %d remain", n)
+	}
+}
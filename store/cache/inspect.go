@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Len returns the number of items Get would currently return; see Size.
+func (c *Cache) Len() int {
+	return int(c.Size())
+}
+
+// EntryInfo describes a cached entry for diagnostics; see Inspect.
+type EntryInfo struct {
+	// CreatedAt is when the value was written.
+	CreatedAt time.Time
+	// LastAccessedAt is when the value was last read, or zero if it never was.
+	LastAccessedAt time.Time
+	// AccessCount is the number of reads that returned the value.
+	AccessCount int64
+	// ExpiresAt is when the value expires, or zero if it never does.
+	ExpiresAt time.Time
+	// ApproxSize is the estimated size of the value in bytes.
+	ApproxSize int64
+}
+
+// Inspect returns metadata about the live entry stored at key. It is not an access:
+// it leaves the access statistics, the LRU order and the hit and miss counters untouched.
+func (c *Cache) Inspect(ctx context.Context, key string) (EntryInfo, bool) {
+	if ctx.Err() != nil {
+		return EntryInfo{}, false
+	}
+	now := c.now()
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	itm, ok := s.items[key]
+	if !ok || !itm.live(now) {
+		return EntryInfo{}, false
+	}
+	return EntryInfo{
+		CreatedAt:      itm.createdAt,
+		LastAccessedAt: itm.lastAccess,
+		AccessCount:    itm.accessCount,
+		ExpiresAt:      itm.expiration,
+		ApproxSize:     itm.size,
+	}, true
+}
+
+// Peek returns the live value stored at key without it counting as an access: unlike
+// Get it leaves the LRU order, the access statistics, the hit and miss counters and
+// sliding expiration untouched, and it never calls OnMiss.
+func (c *Cache) Peek(ctx context.Context, key string) (any, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	now := c.now()
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	itm, ok := s.items[key]
+	if !ok || !itm.live(now) {
+		s.mu.Unlock()
+		return nil, false
+	}
+	value := itm.value
+	s.mu.Unlock()
+	return c.readValue(value), true
+}
+
+// Keys returns a snapshot of the keys currently in the cache, skipping
+// negative entries and items that have expired but have not been swept yet.
+func (c *Cache) Keys() []string {
+	now := c.now()
+
+	keys := make([]string, 0, atomic.LoadInt64(&c.itemCount))
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, itm := range s.items {
+			if itm.live(now) {
+				keys = append(keys, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	return keys
+}
+
+// SortedKeys is like Keys but returns the keys in ascending order.
+// Sorting costs O(n log n), so prefer Keys where order does not matter.
+func (c *Cache) SortedKeys() []string {
+	keys := c.Keys()
+	slices.Sort(keys)
+	return keys
+}
+
+// Clone returns a copy of the live entries, skipping negative and expired ones,
+// that the caller can use without locks while the cache keeps changing. It is O(n)
+// and copies one shard at a time under its lock, so it is only approximately atomic:
+// writes landing during the copy may or may not be reflected. The copy is shallow;
+// values are shared with the cache, not deep-copied.
+func (c *Cache) Clone() map[string]any {
+	now := c.now()
+
+	clone := make(map[string]any, atomic.LoadInt64(&c.itemCount))
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, itm := range s.items {
+			if itm.live(now) {
+				clone[key] = itm.value
+			}
+		}
+		s.mu.Unlock()
+	}
+	for key, value := range clone {
+		clone[key] = c.decompress(value)
+	}
+	return clone
+}
+
+// Range calls fn for each live item until fn returns false.
+// Items are copied out under each shard lock and fn runs without it,
+// so fn may safely call back into the cache.
+func (c *Cache) Range(fn func(key string, value any) bool) {
+	now := c.now()
+
+	snapshot := make([]evictedItem, 0, atomic.LoadInt64(&c.itemCount))
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, itm := range s.items {
+			if itm.live(now) {
+				snapshot = append(snapshot, evictedItem{key: key, value: itm.value})
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	for _, e := range snapshot {
+		if !fn(e.key, c.decompress(e.value)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCacheIntrospectionEmpty(t *testing.T) {
+	cache := NewDefault()
+	defer cache.Close()
+
+	if cache.Len() != 0 {
+		t.Errorf("Expected Len 0, got %d", cache.Len())
+	}
+	if keys := cache.Keys(); len(keys) != 0 {
+		t.Errorf("Expected no keys, got %v", keys)
+	}
+	cache.Range(func(key string, _ any) bool {
+		t.Errorf("Range should not visit %q on an empty cache", key)
+		return true
+	})
+}
+
+func TestCacheIntrospectionPopulated(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	for i := 0; i < 3; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	cache.SetWithTTL(ctx, "expired", "value", time.Millisecond)
+	clock.Advance(5 * time.Millisecond)
+
+	keys := cache.Keys()
+	sort.Strings(keys)
+	if fmt.Sprint(keys) != "[key0 key1 key2]" {
+		t.Errorf("Expected live keys only, got %v", keys)
+	}
+
+	visited := make(map[string]any)
+	cache.Range(func(key string, value any) bool {
+		visited[key] = value
+		return true
+	})
+	if len(visited) != 3 || visited["key1"] != 1 {
+		t.Errorf("Unexpected Range visit %v", visited)
+	}
+}
+
+func TestCacheRangeEarlyStop(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	for i := 0; i < 10; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if cache.Len() != 10 {
+		t.Errorf("Expected Len 10, got %d", cache.Len())
+	}
+
+	visits := 0
+	cache.Range(func(string, any) bool {
+		visits++
+		return visits < 3
+	})
+	if visits != 3 {
+		t.Errorf("Expected Range to stop after 3 visits, got %d", visits)
+	}
+}
+
+func TestCacheLenExcludesExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	config := DefaultConfig()
+	config.CleanupInterval = 0
+	cache := New(config, WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "short", "value", time.Second)
+	cache.SetWithGrace(ctx, "graced", "value", time.Second, time.Hour)
+	cache.SetNotFound(ctx, "missing", time.Hour)
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 live entries, got %d", cache.Len())
+	}
+
+	// Nothing is swept, but neither entry would be returned by Get any more.
+	clock.Advance(2 * time.Second)
+	if cache.Len() != 0 {
+		t.Errorf("Expected Len to exclude logically expired entries, got %d", cache.Len())
+	}
+	if stats := cache.Stats(); stats.ItemCount != 0 {
+		t.Errorf("Expected ItemCount 0, got %d", stats.ItemCount)
+	}
+
+	// Overwriting with a value without TTL makes the shard count it again.
+	cache.SetWithTTL(ctx, "short", "value", 0)
+	if cache.Len() != 1 {
+		t.Errorf("Expected 1 live entry, got %d", cache.Len())
+	}
+}
+
+func TestCacheInspect(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	created := clock.Now()
+	cache.SetWithTTL(ctx, "memo:1", "content", time.Minute)
+	info, ok := cache.Inspect(ctx, "memo:1")
+	if !ok {
+		t.Fatalf("Expected memo:1 to be inspectable")
+	}
+	if !info.CreatedAt.Equal(created) || !info.LastAccessedAt.IsZero() || info.AccessCount != 0 {
+		t.Errorf("Unexpected metadata before any read: %+v", info)
+	}
+	if !info.ExpiresAt.Equal(created.Add(time.Minute)) || info.ApproxSize != int64(len("content")) {
+		t.Errorf("Unexpected expiry or size: %+v", info)
+	}
+
+	for i := 0; i < 3; i++ {
+		clock.Advance(time.Second)
+		cache.Get(ctx, "memo:1")
+	}
+	info, _ = cache.Inspect(ctx, "memo:1")
+	if info.AccessCount != 3 {
+		t.Errorf("Expected 3 accesses, got %d", info.AccessCount)
+	}
+	if !info.LastAccessedAt.Equal(created.Add(3 * time.Second)) {
+		t.Errorf("Expected last access at %v, got %v", created.Add(3*time.Second), info.LastAccessedAt)
+	}
+
+	// Inspecting is not an access.
+	hits := cache.Stats().Hits
+	cache.Inspect(ctx, "memo:1")
+	if info, _ := cache.Inspect(ctx, "memo:1"); info.AccessCount != 3 || cache.Stats().Hits != hits {
+		t.Errorf("Inspect should not count as an access, got %d accesses", info.AccessCount)
+	}
+
+	if _, ok := cache.Inspect(ctx, "missing"); ok {
+		t.Errorf("Expected missing key not to be inspectable")
+	}
+}
+
+func TestCacheSortedKeys(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	for _, key := range []string{"memo:3", "memo:1", "user:1", "memo:2"} {
+		cache.Set(ctx, key, key)
+	}
+	got := cache.SortedKeys()
+	want := []string{"memo:1", "memo:2", "memo:3", "user:1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestCacheClone(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now), WithCompression(1))
+	defer cache.Close()
+
+	cache.Set(ctx, "a", "one")
+	cache.Set(ctx, "b", []byte("two"))
+	cache.SetWithTTL(ctx, "expired", "gone", time.Second)
+	cache.SetNotFound(ctx, "missing", time.Hour)
+	clock.Advance(2 * time.Second)
+
+	clone := cache.Clone()
+	if len(clone) != 2 || clone["a"] != "one" || string(clone["b"].([]byte)) != "two" {
+		t.Errorf("Expected the clone to hold the live entries, got %v", clone)
+	}
+
+	// The clone is decoupled from later mutations, and the other way round.
+	cache.Set(ctx, "c", "three")
+	cache.Delete(ctx, "a")
+	clone["d"] = "four"
+	if _, ok := clone["c"]; ok || clone["a"] != "one" {
+		t.Errorf("Later writes should not reach the clone, got %v", clone)
+	}
+	if _, ok := cache.Get(ctx, "d"); ok {
+		t.Errorf("Changes to the clone should not reach the cache")
+	}
+}
+
+func TestCachePeek(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewWithCapacity(3, WithShards(1), WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "one")
+	cache.Set(ctx, "memo:2", "two")
+	cache.Set(ctx, "memo:3", "three")
+	if val, ok := cache.Peek(ctx, "memo:1"); !ok || val != "one" {
+		t.Fatalf("Expected to peek 'one', got %v", val)
+	}
+	if _, ok := cache.Peek(ctx, "memo:missing"); ok {
+		t.Errorf("Expected a missing key not to be peeked")
+	}
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Expected Peek not to count hits or misses, got %+v", stats)
+	}
+	if info, _ := cache.Inspect(ctx, "memo:1"); info.AccessCount != 0 || !info.LastAccessedAt.IsZero() {
+		t.Errorf("Expected Peek not to record an access, got %+v", info)
+	}
+
+	// memo:1 was only peeked, so it is still the least recently used entry.
+	cache.Set(ctx, "memo:4", "four")
+	if _, ok := cache.Peek(ctx, "memo:1"); ok {
+		t.Errorf("Expected the peeked-only entry to be evicted first")
+	}
+	if _, ok := cache.Peek(ctx, "memo:2"); !ok {
+		t.Errorf("Expected memo:2 to survive")
+	}
+
+	cache.SetWithTTL(ctx, "memo:5", "five", time.Second)
+	clock.Advance(2 * time.Second)
+	if _, ok := cache.Peek(ctx, "memo:5"); ok {
+		t.Errorf("Expected Peek to respect the TTL")
+	}
+}
@@ -0,0 +1,60 @@
+package cache
+
+import (
+	"context"
+	"strings"
+)
+
+// DeletePrefix removes every key that starts with prefix and returns how many were removed.
+// It scans every shard under its own lock, so its cost is O(n) in the size of the cache.
+// If ctx is already done, nothing is removed.
+func (c *Cache) DeletePrefix(ctx context.Context, prefix string) int {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return 0
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return 0
+	}
+
+	var evicted []evictedItem
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, itm := range s.items {
+			if strings.HasPrefix(key, prefix) {
+				evicted = c.dropLocked(s, itm, EvictReasonDeleted, evicted)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	c.notifyEvicted(evicted)
+	return len(evicted)
+}
+
+// DeleteFunc removes every live entry for which pred returns true and returns how many
+// were removed. It scans one shard at a time, holding only that shard's lock while pred
+// runs, so pred must not call back into the cache or it will deadlock.
+// If ctx is already done, nothing is removed.
+func (c *Cache) DeleteFunc(ctx context.Context, pred func(key string, value any) bool) int {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return 0
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return 0
+	}
+	now := c.now()
+
+	var evicted []evictedItem
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key, itm := range s.items {
+			if itm.live(now) && c.matches(func() bool { return pred(key, c.decompress(itm.value)) }) {
+				evicted = c.dropLocked(s, itm, EvictReasonDeleted, evicted)
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	c.notifyEvicted(evicted)
+	return len(evicted)
+}
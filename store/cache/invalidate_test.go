@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCacheDeletePrefix(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	for i := 0; i < 5; i++ {
+		cache.Set(ctx, fmt.Sprintf("memo:123:%d", i), i)
+		cache.Set(ctx, fmt.Sprintf("user:45:%d", i), i)
+	}
+
+	if deleted := cache.DeletePrefix(ctx, "memo:123:"); deleted != 5 {
+		t.Errorf("Expected 5 keys deleted, got %d", deleted)
+	}
+	if cache.Size() != 5 {
+		t.Errorf("Expected size 5, got %d", cache.Size())
+	}
+	for i := 0; i < 5; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("memo:123:%d", i)); ok {
+			t.Errorf("Key 'memo:123:%d' should have been deleted", i)
+		}
+		if _, ok := cache.Get(ctx, fmt.Sprintf("user:45:%d", i)); !ok {
+			t.Errorf("Key 'user:45:%d' should still be present", i)
+		}
+	}
+
+	if deleted := cache.DeletePrefix(ctx, "missing:"); deleted != 0 {
+		t.Errorf("Expected 0 keys deleted, got %d", deleted)
+	}
+}
+
+func TestCacheDeleteFunc(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	fill := func() {
+		for i := 0; i < 10; i++ {
+			cache.Set(ctx, fmt.Sprintf("session:%d", i), i)
+		}
+	}
+
+	fill()
+	if deleted := cache.DeleteFunc(ctx, func(string, any) bool { return false }); deleted != 0 {
+		t.Errorf("Expected 0 entries deleted, got %d", deleted)
+	}
+	if cache.Size() != 10 {
+		t.Errorf("Expected size 10, got %d", cache.Size())
+	}
+
+	// Drop sessions created before a cutoff encoded in the value.
+	if deleted := cache.DeleteFunc(ctx, func(_ string, value any) bool { return value.(int) < 4 }); deleted != 4 {
+		t.Errorf("Expected 4 entries deleted, got %d", deleted)
+	}
+	for i := 0; i < 10; i++ {
+		if _, ok := cache.Get(ctx, fmt.Sprintf("session:%d", i)); ok != (i >= 4) {
+			t.Errorf("Unexpected presence %v for session %d", ok, i)
+		}
+	}
+
+	if deleted := cache.DeleteFunc(ctx, func(string, any) bool { return true }); deleted != 6 {
+		t.Errorf("Expected 6 entries deleted, got %d", deleted)
+	}
+	if cache.Size() != 0 || atomic.LoadInt64(&cache.itemCount) != 0 {
+		t.Errorf("Expected an empty cache, size is %d", cache.Size())
+	}
+}
@@ -0,0 +1,28 @@
+package cache
+
+import (
+	"time"
+)
+
+// jitter randomizes a positive duration by up to ±TTLJitter of its value.
+// It never turns a positive duration into a non-positive one.
+func (c *Cache) jitter(d time.Duration) time.Duration {
+	if d <= 0 || c.config.TTLJitter <= 0 {
+		return d
+	}
+	delta := float64(d) * c.config.TTLJitter * (2*c.rand.Float64() - 1)
+	if jittered := d + time.Duration(delta); jittered > 0 {
+		return jittered
+	}
+	return d
+}
+
+// jitterEarlier randomizes a positive duration by up to TTLJitter of its value,
+// only ever shortening it.
+func (c *Cache) jitterEarlier(d time.Duration) time.Duration {
+	if d <= 0 || c.config.TTLJitter <= 0 {
+		return d
+	}
+	delta := float64(d) * min(c.config.TTLJitter, 1) * c.rand.Float64()
+	return max(d-time.Duration(delta), 1)
+}
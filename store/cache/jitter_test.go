@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheTTLJitterSpreadsExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{CleanupInterval: 0}, WithClock(clock.Now), WithTTLJitter(0.2))
+	defer cache.Close()
+
+	const n = 1000
+	const ttl = 100 * time.Second
+	for i := 0; i < n; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("key%d", i), i, ttl)
+	}
+
+	// Bucket the actual deadlines across the ±20% window.
+	const buckets = 4
+	var counts [buckets]int
+	low, high := clock.Now().Add(80*time.Second), clock.Now().Add(120*time.Second)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%d", i)
+		expiration := cache.shardFor(key).items[key].expiration
+		if expiration.Before(low) || expiration.After(high) {
+			t.Fatalf("Expected key '%s' to expire within the jitter window, expires at %v", key, expiration.Sub(clock.Now()))
+		}
+		bucket := min(int(expiration.Sub(low)*buckets/high.Sub(low)), buckets-1)
+		counts[bucket]++
+	}
+	// A uniform spread puts about 250 keys in each bucket.
+	for i, count := range counts {
+		if count < n/buckets/2 {
+			t.Errorf("Expected deadlines spread across the window, bucket %d has %d of %d keys: %v", i, count, n, counts)
+		}
+	}
+}
+
+func TestCacheTTLJitterKeepsTTLPositive(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{CleanupInterval: 0}, WithClock(clock.Now), WithTTLJitter(5))
+	defer cache.Close()
+
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		cache.SetWithTTL(ctx, key, i, time.Second)
+		if expiration := cache.shardFor(key).items[key].expiration; !expiration.After(clock.Now()) {
+			t.Fatalf("Expected a positive TTL for key '%s', got %v", key, expiration.Sub(clock.Now()))
+		}
+	}
+
+	// Values without a TTL are left alone.
+	cache.SetWithTTL(ctx, "forever", "value", 0)
+	if !cache.shardFor("forever").items["forever"].expiration.IsZero() {
+		t.Errorf("Expected jitter to leave a non-positive TTL without expiration")
+	}
+}
+
+func TestCacheRandSourceReproducesJitter(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	expirations := func(seed int64) []time.Time {
+		cache := New(Config{}, WithClock(clock.Now), WithTTLJitter(0.2), WithRandSource(seed))
+		defer cache.Close()
+		var result []time.Time
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprintf("key%d", i)
+			cache.SetWithTTL(ctx, key, i, 100*time.Second)
+			result = append(result, cache.shardFor(key).items[key].expiration)
+		}
+		return result
+	}
+
+	a, b, other := expirations(42), expirations(42), expirations(7)
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			t.Fatalf("Expected caches with the same seed to jitter key%d alike, got %v and %v", i, a[i], b[i])
+		}
+	}
+	same := 0
+	for i := range a {
+		if a[i].Equal(other[i]) {
+			same++
+		}
+	}
+	if same == len(a) {
+		t.Errorf("Expected a different seed to jitter differently")
+	}
+}
@@ -0,0 +1,346 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// loadChainKey is the context key of the loadFrame chain.
+type loadChainKey struct{}
+
+// loadFrame records the keys a loader was called for. Frames are linked through the
+// contexts handed to nested loaders, so a load can tell whether it is part of its own chain.
+type loadFrame struct {
+	cache  *Cache
+	keys   []string
+	parent *loadFrame
+}
+
+// withLoad returns the context a loader for keys runs with.
+func (c *Cache) withLoad(ctx context.Context, keys []string) context.Context {
+	parent, _ := ctx.Value(loadChainKey{}).(*loadFrame)
+	return context.WithValue(ctx, loadChainKey{}, &loadFrame{cache: c, keys: keys, parent: parent})
+}
+
+// checkReentrant returns ErrReentrantLoad if ctx comes from a loader of this cache
+// that is loading key.
+func (c *Cache) checkReentrant(ctx context.Context, key string) error {
+	frame, _ := ctx.Value(loadChainKey{}).(*loadFrame)
+	for ; frame != nil; frame = frame.parent {
+		if frame.cache == c && slices.Contains(frame.keys, key) {
+			return errors.Wrapf(ErrReentrantLoad, "key %q", key)
+		}
+	}
+	return nil
+}
+
+// call is an in-flight or completed loader invocation shared by every caller
+// that asked for the same key while it was running.
+type call struct {
+	done  chan struct{}
+	value any
+	err   error
+}
+
+// GetOrSet returns the cached value for key, or calls loader to produce it on a miss.
+// Concurrent calls for the same key share a single loader invocation; the other callers
+// wait for its result or until their own context is done. A successful result is cached
+// with the default TTL, while an error is returned to every waiter and nothing is cached.
+// If ctx is already done, ctx.Err() is returned without running the loader.
+// A loader that calls GetOrSet for its own key with the context it was given gets
+// ErrReentrantLoad instead of deadlocking; a loader that drops its context cannot be checked.
+func (c *Cache) GetOrSet(ctx context.Context, key string, loader func(context.Context) (any, error)) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if value, ok := c.Get(ctx, key); ok {
+		return value, nil
+	}
+	return c.load(ctx, key, c.config.DefaultTTL, loader)
+}
+
+// GetOrSetWithErrorTTL is like GetOrSet, but caches a successful result for valueTTL and
+// a loader error for errorTTL, so that callers of a failing loader, such as one calling
+// a rate-limited API, get the cached error back without calling the loader again until
+// errorTTL has passed. The error itself is cached and returned, unlike the not-found
+// tombstones of SetNotFound; ErrNotFound and context errors are never cached.
+// Get and Lookup see a cached error as a miss, and serving one counts as a hit.
+// A non-positive errorTTL caches no errors.
+func (c *Cache) GetOrSetWithErrorTTL(ctx context.Context, key string, loader func(context.Context) (any, error), valueTTL, errorTTL time.Duration) (any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err, ok := c.cachedError(key); ok {
+		return nil, err
+	}
+	if value, ok := c.Get(ctx, key); ok {
+		return value, nil
+	}
+	return c.load(ctx, key, valueTTL, func(ctx context.Context) (any, error) {
+		value, err := loader(ctx)
+		if err != nil && errorTTL > 0 && !c.config.ReadOnly && cacheableError(err) {
+			itm := c.newItem(key, nil, c.expiresAt(errorTTL))
+			itm.negative, itm.err = true, err
+			c.insert(itm)
+		}
+		return value, err
+	})
+}
+
+// cachedError returns the loader error cached for key by GetOrSetWithErrorTTL, if any.
+func (c *Cache) cachedError(key string) (error, bool) {
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	itm, ok := s.items[key]
+	if !ok || itm.err == nil || itm.expired(c.now()) || atomic.LoadInt32(&c.disabled) != 0 {
+		return nil, false
+	}
+	s.lru.moveToFront(itm)
+	atomic.AddInt64(&c.counters.Load().hits, 1)
+	return itm.err, true
+}
+
+// cacheableError reports whether GetOrSetWithErrorTTL may cache a loader error.
+// Not-found results belong to negative caching, and context errors are the caller's.
+func cacheableError(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// load runs loader for key, sharing one invocation between concurrent callers,
+// and caches a successful result with ttl, kept stale for StaleIfError.
+func (c *Cache) load(ctx context.Context, key string, ttl time.Duration, loader func(context.Context) (any, error)) (any, error) {
+	cl, owner, err := c.claimLoad(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !owner {
+		return c.awaitLoad(ctx, cl)
+	}
+	return c.runLoad(ctx, key, ttl, cl, loader)
+}
+
+// claimLoad returns the in-flight call loading key, registering a new one owned by
+// the caller if there is none.
+func (c *Cache) claimLoad(ctx context.Context, key string) (cl *call, owner bool, err error) {
+	if err := c.checkOpen(); err != nil {
+		return nil, false, err
+	}
+	if err := c.validateKey(key); err != nil {
+		return nil, false, err
+	}
+	if err := c.checkReentrant(ctx, key); err != nil {
+		return nil, false, err
+	}
+	c.loadMu.Lock()
+	defer c.loadMu.Unlock()
+	if cl, ok := c.loads[key]; ok {
+		return cl, false, nil
+	}
+	cl = &call{done: make(chan struct{})}
+	c.loads[key] = cl
+	return cl, true, nil
+}
+
+// awaitLoad waits for the result of a call owned by someone else, or until ctx is done.
+func (c *Cache) awaitLoad(ctx context.Context, cl *call) (any, error) {
+	select {
+	case <-cl.done:
+		return c.copyValue(cl.value), cl.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runLoad runs loader for the call the caller owns, caches a successful result and
+// completes the call.
+func (c *Cache) runLoad(ctx context.Context, key string, ttl time.Duration, cl *call, loader func(context.Context) (any, error)) (any, error) {
+	defer func() {
+		c.loadMu.Lock()
+		delete(c.loads, key)
+		c.loadMu.Unlock()
+		close(cl.done)
+	}()
+
+	cl.value, cl.err = c.callLoader(c.withLoad(ctx, []string{key}), loader)
+	if cl.err != nil {
+		return nil, cl.err
+	}
+	c.SetWithGrace(ctx, key, cl.value, ttl, c.config.StaleIfError)
+	return c.copyValue(cl.value), nil
+}
+
+// GetMultiOrLoad returns the cached values for keys and loads the missing ones with a
+// single call to loader, which receives only the keys that missed. Loaded values are
+// cached with the default TTL and merged into the result; keys the loader does not
+// return are left out. A key already being loaded by a concurrent GetOrSet or
+// GetMultiOrLoad call is waited for rather than loaded again. Like GetOrSet, it returns
+// ErrReentrantLoad when called from a loader for a key that loader is loading.
+// If ctx is already done, ctx.Err() is returned without running the loader.
+func (c *Cache) GetMultiOrLoad(ctx context.Context, keys []string, loader func(ctx context.Context, missing []string) (map[string]any, error)) (map[string]any, error) {
+	values, _, err := c.loadMulti(ctx, keys, false, func(ctx context.Context, missing []string) (map[string]any, map[string]error, error) {
+		values, err := loader(ctx, missing)
+		return values, nil, err
+	})
+	return values, err
+}
+
+// LoadMulti is like GetMultiOrLoad, but lets the batch loader fail keys one by one: it
+// returns the values it loaded and an error for each key it could not load, and LoadMulti
+// caches the values and hands the per-key errors back instead of failing the whole batch,
+// so that a list can render the entries that loaded and mark the rest. Keys the loader
+// returns neither a value nor an error for, and keys recorded as not found, get an error
+// wrapping ErrNotFound; with WithNegativeTTL, keys found missing are recorded as not
+// found. A key with an error is not cached even if a value is returned for it too.
+// A concurrent GetOrSet failing a key that LoadMulti waits for also fails only that key.
+// The error result is reserved for failures of the whole call, such as an error returned
+// by the loader itself.
+func (c *Cache) LoadMulti(ctx context.Context, keys []string, loader func(ctx context.Context, missing []string) (map[string]any, map[string]error, error)) (map[string]any, map[string]error, error) {
+	return c.loadMulti(ctx, keys, true, loader)
+}
+
+// loadMulti implements GetMultiOrLoad and, if partial is set, LoadMulti.
+func (c *Cache) loadMulti(ctx context.Context, keys []string, partial bool, loader func(ctx context.Context, missing []string) (map[string]any, map[string]error, error)) (map[string]any, map[string]error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	if err := c.checkOpen(); err != nil {
+		return nil, nil, err
+	}
+	var result map[string]any
+	errs := make(map[string]error)
+	if partial {
+		// Look keys up one by one to answer the ones recorded as not found.
+		result = make(map[string]any, len(keys))
+		for _, key := range keys {
+			switch value, state := c.Lookup(ctx, key); state {
+			case Hit:
+				result[key] = value
+			case NegativeHit:
+				errs[key] = errors.Wrapf(ErrNotFound, "key %q", key)
+			}
+		}
+	} else {
+		result = c.GetMulti(ctx, keys)
+	}
+
+	var missing []string
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if _, ok := result[key]; ok || seen[key] || errs[key] != nil {
+			continue
+		}
+		seen[key] = true
+		if err := c.validateKey(key); err != nil {
+			return nil, nil, err
+		}
+		if err := c.checkReentrant(ctx, key); err != nil {
+			return nil, nil, err
+		}
+		missing = append(missing, key)
+	}
+	if len(missing) == 0 {
+		return result, errs, nil
+	}
+
+	// Claim the keys nobody is loading yet, and remember the rest to wait for.
+	owned := make(map[string]*call)
+	waiting := make(map[string]*call)
+	var ownedKeys []string
+	c.loadMu.Lock()
+	for _, key := range missing {
+		if cl, ok := c.loads[key]; ok {
+			waiting[key] = cl
+			continue
+		}
+		cl := &call{done: make(chan struct{})}
+		c.loads[key] = cl
+		owned[key] = cl
+		ownedKeys = append(ownedKeys, key)
+	}
+	c.loadMu.Unlock()
+
+	if len(ownedKeys) > 0 {
+		values, keyErrs, err := c.loadBatch(ctx, ownedKeys, owned, loader)
+		if err != nil {
+			return nil, nil, err
+		}
+		for key, value := range values {
+			result[key] = c.copyValue(value)
+		}
+		if partial {
+			for key, err := range keyErrs {
+				errs[key] = err
+				if negativeTTL := c.config.NegativeTTL; negativeTTL > 0 && errors.Is(err, ErrNotFound) {
+					c.SetNotFound(ctx, key, negativeTTL)
+				}
+			}
+		}
+	}
+
+	for key, cl := range waiting {
+		select {
+		case <-cl.done:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+		if cl.err != nil {
+			if partial {
+				errs[key] = cl.err
+				continue
+			}
+			if errors.Is(cl.err, ErrNotFound) {
+				continue
+			}
+			return nil, nil, cl.err
+		}
+		result[key] = c.copyValue(cl.value)
+	}
+	return result, errs, nil
+}
+
+// loadBatch runs a batch loader for keys, caches the values it returns, and completes the
+// in-flight call of each key. It returns an error for each key that did not load: the one
+// the loader returned for it, or ErrNotFound if the loader returned nothing for it.
+func (c *Cache) loadBatch(ctx context.Context, keys []string, calls map[string]*call, loader func(ctx context.Context, missing []string) (map[string]any, map[string]error, error)) (values map[string]any, keyErrs map[string]error, err error) {
+	defer func() {
+		c.loadMu.Lock()
+		for _, key := range keys {
+			delete(c.loads, key)
+		}
+		c.loadMu.Unlock()
+		for key, cl := range calls {
+			switch value, ok := values[key]; {
+			case err != nil:
+				cl.err = err
+			case ok:
+				cl.value = value
+			default:
+				cl.err = keyErrs[key]
+			}
+			close(cl.done)
+		}
+	}()
+
+	values, keyErrs, err = c.callBatchLoader(c.withLoad(ctx, keys), keys, loader)
+	if err != nil {
+		return nil, nil, err
+	}
+	loaded := make(map[string]any, len(values))
+	failed := make(map[string]error)
+	for _, key := range keys {
+		if err := keyErrs[key]; err != nil {
+			failed[key] = err
+		} else if value, ok := values[key]; ok {
+			loaded[key] = value
+		} else {
+			failed[key] = errors.Wrapf(ErrNotFound, "key %q", key)
+		}
+	}
+	c.PutMulti(ctx, loaded, c.config.DefaultTTL)
+	return loaded, failed, nil
+}
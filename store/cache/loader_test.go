@@ -0,0 +1,439 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrSetSingleflight(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	var calls int64
+	loader := func(context.Context) (any, error) {
+		atomic.AddInt64(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return "rendered", nil
+	}
+
+	const goroutines = 100
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			val, err := cache.GetOrSet(ctx, "memo:1", loader)
+			if err != nil || val != "rendered" {
+				t.Errorf("Expected 'rendered', got %v, err: %v", val, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("Expected loader to run once, ran %d times", calls)
+	}
+	if val, ok := cache.Get(ctx, "memo:1"); !ok || val != "rendered" {
+		t.Errorf("Expected loaded value to be cached, got %v, exists: %v", val, ok)
+	}
+}
+
+func TestGetOrSetError(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	loadErr := errors.New("database unavailable")
+	release := make(chan struct{})
+	loader := func(context.Context) (any, error) {
+		<-release
+		return nil, loadErr
+	}
+
+	const goroutines = 10
+	errs := make(chan error, goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			_, err := cache.GetOrSet(ctx, "memo:1", loader)
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < goroutines; i++ {
+		if err := <-errs; !errors.Is(err, loadErr) {
+			t.Errorf("Expected loader error, got %v", err)
+		}
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Failed load should not be cached")
+	}
+}
+
+func TestGetOrSetWithErrorTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	rateLimited := errors.New("rate limited")
+	calls := 0
+	fail := true
+	loader := func(context.Context) (any, error) {
+		calls++
+		if fail {
+			return nil, rateLimited
+		}
+		return "memo", nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetOrSetWithErrorTTL(ctx, "memo:1", loader, time.Minute, 5*time.Second); !errors.Is(err, rateLimited) {
+			t.Fatalf("Expected the loader error, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the loader not to be called again within the error window, called %d times", calls)
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected Get to miss on a cached error")
+	}
+	if _, state := cache.Lookup(ctx, "memo:1"); state != Miss {
+		t.Errorf("Expected Lookup to report a cached error as a Miss, got %v", state)
+	}
+
+	// Past the error window the loader is retried, and success is cached for valueTTL.
+	fail = false
+	clock.Advance(6 * time.Second)
+	value, err := cache.GetOrSetWithErrorTTL(ctx, "memo:1", loader, time.Minute, 5*time.Second)
+	if err != nil || value != "memo" || calls != 2 {
+		t.Errorf("Expected a retry after the error window, got %v, %v after %d calls", value, err, calls)
+	}
+	clock.Advance(30 * time.Second)
+	if _, err := cache.GetOrSetWithErrorTTL(ctx, "memo:1", loader, time.Minute, 5*time.Second); err != nil || calls != 2 {
+		t.Errorf("Expected the cached value within valueTTL, got %v after %d calls", err, calls)
+	}
+}
+
+func TestGetOrSetWithErrorTTLSkipsNotFound(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	calls := 0
+	loader := func(context.Context) (any, error) {
+		calls++
+		return nil, ErrNotFound
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetOrSetWithErrorTTL(ctx, "memo:1", loader, time.Minute, time.Minute); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected ErrNotFound not to be cached as an error, loader called %d times", calls)
+	}
+}
+
+func TestGetOrSetWaiterCancellation(t *testing.T) {
+	cache := NewDefault()
+	defer cache.Close()
+
+	release := make(chan struct{})
+	go cache.GetOrSet(context.Background(), "memo:1", func(context.Context) (any, error) {
+		<-release
+		return "slow", nil
+	})
+	defer close(release)
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetOrSet(ctx, "memo:1", func(context.Context) (any, error) {
+		t.Errorf("Waiter should not run its own loader")
+		return nil, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestGetMultiOrLoadMissingOnly(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "one")
+	cache.Set(ctx, "memo:3", "three")
+
+	var requested []string
+	loader := func(_ context.Context, missing []string) (map[string]any, error) {
+		requested = append(requested, missing...)
+		return map[string]any{"memo:2": "two"}, nil
+	}
+	values, err := cache.GetMultiOrLoad(ctx, []string{"memo:1", "memo:2", "memo:3", "memo:4", "memo:2"}, loader)
+	if err != nil {
+		t.Fatalf("GetMultiOrLoad failed: %v", err)
+	}
+
+	if len(requested) != 2 || requested[0] != "memo:2" || requested[1] != "memo:4" {
+		t.Errorf("Expected loader to receive [memo:2 memo:4], got %v", requested)
+	}
+	if len(values) != 3 || values["memo:1"] != "one" || values["memo:2"] != "two" || values["memo:3"] != "three" {
+		t.Errorf("Expected hits merged with loaded values, got %v", values)
+	}
+	if val, ok := cache.Get(ctx, "memo:2"); !ok || val != "two" {
+		t.Errorf("Expected loaded value to be cached, got %v, exists: %v", val, ok)
+	}
+	if _, ok := cache.Get(ctx, "memo:4"); ok {
+		t.Errorf("Keys the loader did not return should not be cached")
+	}
+
+	requested = nil
+	if _, err := cache.GetMultiOrLoad(ctx, []string{"memo:1", "memo:2"}, loader); err != nil {
+		t.Fatalf("GetMultiOrLoad failed: %v", err)
+	}
+	if requested != nil {
+		t.Errorf("Loader should not run when every key hits, got %v", requested)
+	}
+}
+
+func TestLoadMultiPartialResults(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithNegativeTTL(time.Minute))
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "one")
+
+	dbErr := errors.New("row locked")
+	var requested []string
+	loader := func(_ context.Context, missing []string) (map[string]any, map[string]error, error) {
+		requested = append(requested, missing...)
+		return map[string]any{"memo:2": "two"}, map[string]error{
+			"memo:3": ErrNotFound,
+			"memo:4": dbErr,
+		}, nil
+	}
+	keys := []string{"memo:1", "memo:2", "memo:3", "memo:4", "memo:5"}
+	values, errs, err := cache.LoadMulti(ctx, keys, loader)
+	if err != nil {
+		t.Fatalf("LoadMulti failed: %v", err)
+	}
+	if len(values) != 2 || values["memo:1"] != "one" || values["memo:2"] != "two" {
+		t.Errorf("Expected the hit and the loaded value, got %v", values)
+	}
+	if len(errs) != 3 || !errors.Is(errs["memo:3"], ErrNotFound) || !errors.Is(errs["memo:4"], dbErr) || !errors.Is(errs["memo:5"], ErrNotFound) {
+		t.Errorf("Expected per-key errors for memo:3, memo:4 and memo:5, got %v", errs)
+	}
+	if val, ok := cache.Get(ctx, "memo:2"); !ok || val != "two" {
+		t.Errorf("Expected the loaded value to be cached, got %v, exists: %v", val, ok)
+	}
+	if _, state := cache.Lookup(ctx, "memo:3"); state != NegativeHit {
+		t.Errorf("Expected memo:3 to be recorded as not found, got %v", state)
+	}
+	if _, state := cache.Lookup(ctx, "memo:4"); state != Miss {
+		t.Errorf("Expected the failed key not to be cached, got %v", state)
+	}
+
+	// Only the failed key is loaded again; hits and not-found keys are answered from the cache.
+	requested = nil
+	values, errs, err = cache.LoadMulti(ctx, keys, loader)
+	if err != nil {
+		t.Fatalf("LoadMulti failed: %v", err)
+	}
+	if len(requested) != 1 || requested[0] != "memo:4" {
+		t.Errorf("Expected the loader to receive [memo:4], got %v", requested)
+	}
+	if len(values) != 2 || len(errs) != 3 || !errors.Is(errs["memo:3"], ErrNotFound) {
+		t.Errorf("Expected the same partial result, got %v and %v", values, errs)
+	}
+
+	// An error of the loader itself still fails the whole call.
+	if _, _, err := cache.LoadMulti(ctx, []string{"memo:6"}, func(context.Context, []string) (map[string]any, map[string]error, error) {
+		return nil, nil, dbErr
+	}); !errors.Is(err, dbErr) {
+		t.Errorf("Expected the loader error, got %v", err)
+	}
+}
+
+func TestGetMultiOrLoadSharesInFlightKeys(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	first := func(_ context.Context, missing []string) (map[string]any, error) {
+		close(started)
+		<-release
+		values := make(map[string]any)
+		for _, key := range missing {
+			values[key] = "first:" + key
+		}
+		return values, nil
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.GetMultiOrLoad(ctx, []string{"a", "b", "c"}, first)
+		done <- err
+	}()
+	<-started
+
+	var requested []string
+	second := func(_ context.Context, missing []string) (map[string]any, error) {
+		requested = missing
+		return map[string]any{"d": "second:d"}, nil
+	}
+	result := make(chan map[string]any, 1)
+	go func() {
+		values, err := cache.GetMultiOrLoad(ctx, []string{"b", "c", "d"}, second)
+		if err != nil {
+			t.Errorf("GetMultiOrLoad failed: %v", err)
+		}
+		result <- values
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if err := <-done; err != nil {
+		t.Fatalf("GetMultiOrLoad failed: %v", err)
+	}
+	values := <-result
+	if len(requested) != 1 || requested[0] != "d" {
+		t.Errorf("Expected second loader to receive only [d], got %v", requested)
+	}
+	if values["b"] != "first:b" || values["c"] != "first:c" || values["d"] != "second:d" {
+		t.Errorf("Expected in-flight keys to be shared, got %v", values)
+	}
+}
+
+func TestGetMultiOrLoadError(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	loadErr := errors.New("database unavailable")
+	_, err := cache.GetMultiOrLoad(ctx, []string{"memo:1"}, func(context.Context, []string) (map[string]any, error) {
+		return nil, loadErr
+	})
+	if !errors.Is(err, loadErr) {
+		t.Errorf("Expected loader error, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Failed load should not be cached")
+	}
+}
+
+func TestGetOrSetReentrantLoad(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		var loader func(context.Context) (any, error)
+		loader = func(ctx context.Context) (any, error) {
+			// An indirect code path asking for the key being loaded.
+			return cache.GetOrSet(ctx, "memo:1", loader)
+		}
+		_, err := cache.GetOrSet(ctx, "memo:1", loader)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrReentrantLoad) {
+			t.Errorf("Expected ErrReentrantLoad, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Re-entrant GetOrSet deadlocked")
+	}
+
+	// Nested loads of other keys are fine.
+	value, err := cache.GetOrSet(ctx, "memo:2", func(ctx context.Context) (any, error) {
+		return cache.GetOrSet(ctx, "memo:3", func(context.Context) (any, error) { return "inner", nil })
+	})
+	if err != nil || value != "inner" {
+		t.Errorf("Expected a nested load of another key to succeed, got %v, %v", value, err)
+	}
+
+	_, err = cache.GetMultiOrLoad(ctx, []string{"memo:4", "memo:5"}, func(ctx context.Context, _ []string) (map[string]any, error) {
+		_, err := cache.GetOrSet(ctx, "memo:5", func(context.Context) (any, error) { return "value", nil })
+		return nil, err
+	})
+	if !errors.Is(err, ErrReentrantLoad) {
+		t.Errorf("Expected ErrReentrantLoad from a batch loader, got %v", err)
+	}
+}
+
+func TestGetOrSetMaxConcurrentLoads(t *testing.T) {
+	ctx := context.Background()
+	const limit = 3
+	cache := NewDefault(WithMaxConcurrentLoads(limit))
+	defer cache.Close()
+
+	var inFlight, peak int64
+	loader := func(context.Context) (any, error) {
+		n := atomic.AddInt64(&inFlight, 1)
+		for {
+			p := atomic.LoadInt64(&peak)
+			if n <= p || atomic.CompareAndSwapInt64(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt64(&inFlight, -1)
+		return "value", nil
+	}
+
+	const goroutines = 20
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for i := 0; i < goroutines; i++ {
+		go func() {
+			defer wg.Done()
+			if _, err := cache.GetOrSet(ctx, fmt.Sprintf("memo:%d", i), loader); err != nil {
+				t.Errorf("GetOrSet failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak > limit {
+		t.Errorf("Expected at most %d loads in flight, saw %d", limit, peak)
+	}
+	if peak < 2 {
+		t.Errorf("Expected loads to run concurrently, saw a peak of %d", peak)
+	}
+}
+
+func TestGetOrSetMaxConcurrentLoadsCancellation(t *testing.T) {
+	cache := NewDefault(WithMaxConcurrentLoads(1))
+	defer cache.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go cache.GetOrSet(context.Background(), "slow", func(context.Context) (any, error) {
+		close(started)
+		<-release
+		return "value", nil
+	})
+	<-started
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := cache.GetOrSet(ctx, "other", func(context.Context) (any, error) {
+		t.Error("Loader should not run without a free slot")
+		return nil, nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LoaderLatencyBuckets are the upper bounds of the loader latency histogram.
+// Loads slower than the last bound are counted in an extra overflow bucket.
+var LoaderLatencyBuckets = [...]time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// LoaderStats holds statistics about the loader calls made on cache misses
+// by GetOrSet, GetMultiOrLoad, LoadingCache and refresh-ahead.
+type LoaderStats struct {
+	// Calls is the number of loader invocations. Callers sharing an in-flight
+	// load count once.
+	Calls int64
+	// Errors is the number of invocations that failed or panicked.
+	// A loader reporting ErrNotFound is not counted as an error.
+	Errors int64
+	// TotalLatency is the time spent in all loader invocations.
+	TotalLatency time.Duration
+	// Latency counts the invocations by duration: Latency[i] is the number that took
+	// at most LoaderLatencyBuckets[i] and longer than the previous bound, and the
+	// last element counts those slower than every bound.
+	Latency [len(LoaderLatencyBuckets) + 1]int64
+}
+
+// LoaderStats returns a snapshot of the loader statistics.
+func (c *Cache) LoaderStats() LoaderStats {
+	counters := c.counters.Load()
+	stats := LoaderStats{
+		Calls:        atomic.LoadInt64(&counters.loaderCalls),
+		Errors:       atomic.LoadInt64(&counters.loaderErrors),
+		TotalLatency: time.Duration(atomic.LoadInt64(&counters.loaderNanos)),
+	}
+	for i := range counters.loaderLatency {
+		stats.Latency[i] = atomic.LoadInt64(&counters.loaderLatency[i])
+	}
+	return stats
+}
+
+// recordLoad accounts for one loader invocation that took elapsed and returned err.
+func (c *Cache) recordLoad(elapsed time.Duration, err error) {
+	counters := c.counters.Load()
+	atomic.AddInt64(&counters.loaderCalls, 1)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		atomic.AddInt64(&counters.loaderErrors, 1)
+	}
+	atomic.AddInt64(&counters.loaderNanos, int64(elapsed))
+	bucket := len(LoaderLatencyBuckets)
+	for i, bound := range LoaderLatencyBuckets {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddInt64(&counters.loaderLatency[bucket], 1)
+}
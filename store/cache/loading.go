@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// LoadingCache is a read-through cache: Get calls the loader on a miss and caches the result.
+// Concurrent misses for the same key share a single loader invocation.
+// The embedded Cache can be used to invalidate or prime entries directly.
+type LoadingCache struct {
+	*Cache
+
+	loader func(ctx context.Context, key string) (any, error)
+	ttl    time.Duration
+}
+
+// NewLoadingCache creates a read-through cache with default configuration and the given options.
+// Loaded values are cached for ttl; a non-positive ttl caches them without expiration.
+func NewLoadingCache(loader func(ctx context.Context, key string) (any, error), ttl time.Duration, opts ...Option) *LoadingCache {
+	return &LoadingCache{
+		Cache:  NewDefault(opts...),
+		loader: loader,
+		ttl:    ttl,
+	}
+}
+
+// Get returns the cached value for key, loading and caching it on a miss.
+// Loader errors are returned to the caller and are not cached, except for
+// ErrNotFound when negative caching is enabled with WithNegativeTTL.
+// With WithStaleIfError, a failed reload may return a stale value instead; use
+// GetWithStale to tell it apart.
+// If ctx is already done, ctx.Err() is returned without running the loader.
+func (l *LoadingCache) Get(ctx context.Context, key string) (any, error) {
+	value, _, err := l.GetWithStale(ctx, key)
+	return value, err
+}
+
+// GetWithStale is like Get but, when the loader fails while the value is past its
+// soft TTL and within the StaleIfError window, returns the stale value with stale set
+// and a nil error. ErrNotFound from the loader is always returned, since the value
+// no longer exists.
+func (l *LoadingCache) GetWithStale(ctx context.Context, key string) (value any, stale bool, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+
+	switch value, state := l.Lookup(ctx, key); state {
+	case Hit:
+		return value, false, nil
+	case NegativeHit:
+		return nil, false, errors.Wrapf(ErrNotFound, "key %q", key)
+	}
+
+	value, err = l.load(ctx, key, l.ttl, func(ctx context.Context) (any, error) {
+		return l.loader(ctx, key)
+	})
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			if negativeTTL := l.config.NegativeTTL; negativeTTL > 0 {
+				l.SetNotFound(ctx, key, negativeTTL)
+			}
+			return nil, false, err
+		}
+		if value, ok := l.peekStale(key); ok {
+			return value, true, nil
+		}
+		return nil, false, err
+	}
+	return value, false, nil
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLoadingCache(t *testing.T) {
+	ctx := context.Background()
+	var loads []string
+	cache := NewLoadingCache(func(_ context.Context, key string) (any, error) {
+		loads = append(loads, key)
+		return "value:" + key, nil
+	}, time.Minute)
+	defer cache.Close()
+
+	// A miss triggers a load and caches the result.
+	if val, err := cache.Get(ctx, "memo:1"); err != nil || val != "value:memo:1" {
+		t.Errorf("Expected 'value:memo:1', got %v, err: %v", val, err)
+	}
+	// A hit is served without the loader.
+	if val, err := cache.Get(ctx, "memo:1"); err != nil || val != "value:memo:1" {
+		t.Errorf("Expected 'value:memo:1', got %v, err: %v", val, err)
+	}
+	if len(loads) != 1 {
+		t.Errorf("Expected a single load, got %v", loads)
+	}
+
+	// Invalidating through the embedded Cache forces a reload.
+	cache.Delete(ctx, "memo:1")
+	cache.Get(ctx, "memo:1")
+	if len(loads) != 2 {
+		t.Errorf("Expected a reload after Delete, got %v", loads)
+	}
+}
+
+func TestLoadingCacheError(t *testing.T) {
+	ctx := context.Background()
+	loadErr := errors.New("database unavailable")
+	calls := 0
+	cache := NewLoadingCache(func(context.Context, string) (any, error) {
+		calls++
+		return nil, loadErr
+	}, time.Minute)
+	defer cache.Close()
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Get(ctx, "memo:1"); !errors.Is(err, loadErr) {
+			t.Errorf("Expected the loader error, got %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected errors not to be cached, loader ran %d times", calls)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected nothing cached, size is %d", cache.Size())
+	}
+}
+
+func TestLoadingCacheNegative(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	cache := NewLoadingCache(func(context.Context, string) (any, error) {
+		calls++
+		return nil, ErrNotFound
+	}, time.Minute, WithNegativeTTL(time.Minute))
+	defer cache.Close()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Get(ctx, "memo:404"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("Expected the miss to be remembered, loader ran %d times", calls)
+	}
+}
+
+func TestLoadingCacheLoaderStats(t *testing.T) {
+	ctx := context.Background()
+	const delay = 20 * time.Millisecond
+	loadErr := errors.New("database unavailable")
+	cache := NewLoadingCache(func(_ context.Context, key string) (any, error) {
+		time.Sleep(delay)
+		if key == "broken" {
+			return nil, loadErr
+		}
+		return "value:" + key, nil
+	}, time.Minute)
+	defer cache.Close()
+
+	for _, key := range []string{"memo:1", "memo:2", "broken", "memo:1"} {
+		cache.Get(ctx, key)
+	}
+
+	stats := cache.LoaderStats()
+	if stats.Calls != 3 || stats.Errors != 1 {
+		t.Errorf("Expected 3 calls and 1 error, got %d calls and %d errors", stats.Calls, stats.Errors)
+	}
+	if stats.TotalLatency < 3*delay || stats.TotalLatency > time.Second {
+		t.Errorf("Expected total latency of about %v, got %v", 3*delay, stats.TotalLatency)
+	}
+	var counted int64
+	for i, n := range stats.Latency {
+		counted += n
+		if n > 0 && i < len(LoaderLatencyBuckets) && LoaderLatencyBuckets[i] < delay {
+			t.Errorf("Loads of %v counted in the %v bucket", delay, LoaderLatencyBuckets[i])
+		}
+	}
+	if counted != stats.Calls {
+		t.Errorf("Expected the histogram to count %d loads, got %d", stats.Calls, counted)
+	}
+}
+
+func TestLoadingCacheStaleIfError(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	loadErr := errors.New("database unavailable")
+	var version int
+	var failing bool
+	cache := NewLoadingCache(func(context.Context, string) (any, error) {
+		if failing {
+			return nil, loadErr
+		}
+		version++
+		return version, nil
+	}, time.Minute, WithClock(clock.Now), WithStaleIfError(10*time.Minute))
+	defer cache.Close()
+
+	// Fresh: served from the cache.
+	if val, stale, err := cache.GetWithStale(ctx, "memo:1"); err != nil || stale || val != 1 {
+		t.Fatalf("Expected a fresh 1, got %v, stale: %v, err: %v", val, stale, err)
+	}
+
+	// Past the soft TTL with a working loader: reloaded.
+	clock.Advance(2 * time.Minute)
+	if val, stale, err := cache.GetWithStale(ctx, "memo:1"); err != nil || stale || val != 2 {
+		t.Errorf("Expected a refreshed 2, got %v, stale: %v, err: %v", val, stale, err)
+	}
+
+	// Past the soft TTL with a failing loader: the stale value is served.
+	failing = true
+	clock.Advance(2 * time.Minute)
+	if val, stale, err := cache.GetWithStale(ctx, "memo:1"); err != nil || !stale || val != 2 {
+		t.Errorf("Expected a stale 2, got %v, stale: %v, err: %v", val, stale, err)
+	}
+	if val, err := cache.Get(ctx, "memo:1"); err != nil || val != 2 {
+		t.Errorf("Expected Get to serve the stale value too, got %v, err: %v", val, err)
+	}
+
+	// Past the hard TTL: the loader error propagates.
+	clock.Advance(10 * time.Minute)
+	if val, _, err := cache.GetWithStale(ctx, "memo:1"); !errors.Is(err, loadErr) {
+		t.Errorf("Expected the loader error past the hard TTL, got %v, err: %v", val, err)
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultEvictionLogRate is the number of eviction debug logs written per second
+// when EvictionLogRate is zero.
+const defaultEvictionLogRate = 100
+
+// logLimiter caps the number of log lines written per second.
+type logLimiter struct {
+	mu sync.Mutex
+	// window is the second being counted.
+	window time.Time
+	logged int
+	// suppressed counts the lines dropped in the current window.
+	suppressed int
+}
+
+// allow reports whether another line may be logged at now, and returns the number of
+// lines suppressed in the previous window the first time it is called in a new one.
+func (l *logLimiter) allow(now time.Time, limit int) (ok bool, suppressed int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if window := now.Truncate(time.Second); !window.Equal(l.window) {
+		suppressed = l.suppressed
+		l.window, l.logged, l.suppressed = window, 0, 0
+	}
+	if l.logged < limit {
+		l.logged++
+		return true, suppressed
+	}
+	l.suppressed++
+	return false, suppressed
+}
+
+// logEvictions writes a debug log for each removed item, at most EvictionLogRate
+// per second, and summarizes the ones it suppressed once the next second starts.
+func (c *Cache) logEvictions(evicted []evictedItem) {
+	limit := c.config.EvictionLogRate
+	if limit == 0 {
+		limit = defaultEvictionLogRate
+	}
+	now := c.now()
+	for _, e := range evicted {
+		if limit < 0 {
+			c.logger.Debug("cache entry evicted", "key", e.key, "reason", e.reason)
+			continue
+		}
+		ok, suppressed := c.evictionLogs.allow(now, limit)
+		if suppressed > 0 {
+			c.logger.Debug("cache eviction logs suppressed", "count", suppressed)
+		}
+		if !ok {
+			atomic.AddInt64(&c.counters.Load().suppressedLogs, 1)
+			continue
+		}
+		c.logger.Debug("cache entry evicted", "key", e.key, "reason", e.reason)
+	}
+}
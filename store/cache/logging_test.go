@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureHandler is a slog.Handler that records every log record.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *captureHandler) WithGroup(string) slog.Handler { return h }
+
+// messages returns the messages logged at level.
+func (h *captureHandler) messages(level slog.Level) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var messages []string
+	for _, r := range h.records {
+		if r.Level == level {
+			messages = append(messages, r.Message)
+		}
+	}
+	return messages
+}
+
+func TestCacheLogsPanickingCallback(t *testing.T) {
+	ctx := context.Background()
+	handler := &captureHandler{}
+	cache := NewDefault(WithLogger(slog.New(handler)), WithOnEvict(func(string, any, EvictReason) {
+		panic("callback bug")
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "key", "value")
+	// The panic is recovered, so Delete still completes.
+	if err := cache.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, ok := cache.Get(ctx, "key"); ok {
+		t.Errorf("Expected the key to be deleted despite the panicking callback")
+	}
+
+	if warnings := handler.messages(slog.LevelWarn); len(warnings) != 1 || warnings[0] != "cache eviction callback panicked" {
+		t.Errorf("Expected a warning for the panicking callback, got %v", warnings)
+	}
+	if debugs := handler.messages(slog.LevelDebug); len(debugs) != 1 || debugs[0] != "cache entry evicted" {
+		t.Errorf("Expected a debug log for the eviction, got %v", debugs)
+	}
+}
+
+func TestCacheEvictionLogRate(t *testing.T) {
+	ctx := context.Background()
+	handler := &captureHandler{}
+	clock := newFakeClock()
+	cache := NewWithCapacity(10, WithLogger(slog.New(handler)), WithClock(clock.Now), WithEvictionLogRate(5))
+	defer cache.Close()
+
+	// A burst of writes over capacity evicts 990 entries within one second.
+	for i := 0; i < 1000; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+	}
+	if debugs := handler.messages(slog.LevelDebug); len(debugs) != 5 {
+		t.Errorf("Expected 5 eviction logs within the second, got %d", len(debugs))
+	}
+	if suppressed := cache.Stats().SuppressedEvictionLogs; suppressed != 985 {
+		t.Errorf("Expected 985 suppressed logs, got %d", suppressed)
+	}
+
+	// The next eviction in a new second summarizes the suppressed ones.
+	clock.Advance(time.Second)
+	cache.Set(ctx, "next", 0)
+	debugs := handler.messages(slog.LevelDebug)
+	if len(debugs) != 7 || debugs[5] != "cache eviction logs suppressed" || debugs[6] != "cache entry evicted" {
+		t.Errorf("Expected a summary followed by an eviction log, got %v", debugs[5:])
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+// lruList is an intrusive doubly linked list of items ordered by recency of use.
+// The front holds the most recently used item and the back the least recently used.
+// It is not safe for concurrent use; callers must hold the owning lock.
+type lruList struct {
+	head *item
+	tail *item
+	len  int
+}
+
+// pushFront inserts an item at the front of the list.
+func (l *lruList) pushFront(itm *item) {
+	itm.prev = nil
+	itm.next = l.head
+	if l.head != nil {
+		l.head.prev = itm
+	}
+	l.head = itm
+	if l.tail == nil {
+		l.tail = itm
+	}
+	l.len++
+}
+
+// remove unlinks an item from the list.
+func (l *lruList) remove(itm *item) {
+	if itm.prev != nil {
+		itm.prev.next = itm.next
+	} else {
+		l.head = itm.next
+	}
+	if itm.next != nil {
+		itm.next.prev = itm.prev
+	} else {
+		l.tail = itm.prev
+	}
+	itm.prev = nil
+	itm.next = nil
+	l.len--
+}
+
+// moveToFront marks an item as the most recently used.
+func (l *lruList) moveToFront(itm *item) {
+	if l.head == itm {
+		return
+	}
+	l.remove(itm)
+	l.pushFront(itm)
+}
+
+// back returns the least recently used item, or nil if the list is empty.
+func (l *lruList) back() *item {
+	return l.tail
+}
@@ -0,0 +1,65 @@
+// Package metrics exports cache statistics as Prometheus metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+const namespace = "memos"
+
+// StatsSource is anything that reports cache statistics, such as *cache.Cache.
+type StatsSource interface {
+	Stats() cache.Stats
+}
+
+// Collector is a prometheus.Collector that reads the statistics of one cache
+// on every scrape. The name is attached as the "cache" label so several caches
+// can be registered side by side.
+type Collector struct {
+	source StatsSource
+
+	items     *prometheus.Desc
+	bytes     *prometheus.Desc
+	hits      *prometheus.Desc
+	misses    *prometheus.Desc
+	evictions *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector creates a collector for the given cache.
+func NewCollector(source StatsSource, name string) *Collector {
+	labels := prometheus.Labels{"cache": name}
+	desc := func(metric, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", metric), help, nil, labels)
+	}
+	return &Collector{
+		source:    source,
+		items:     desc("items", "Number of items currently stored in the cache."),
+		bytes:     desc("bytes", "Approximate total size of the values stored in the cache."),
+		hits:      desc("hits_total", "Number of cache lookups that found a live value."),
+		misses:    desc("misses_total", "Number of cache lookups that found nothing."),
+		evictions: desc("evictions_total", "Number of items removed by TTL expiry or capacity pressure."),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.items
+	ch <- c.bytes
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.evictions
+}
+
+// Collect implements prometheus.Collector.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+	ch <- prometheus.MustNewConstMetric(c.items, prometheus.GaugeValue, float64(stats.ItemCount))
+	ch <- prometheus.MustNewConstMetric(c.bytes, prometheus.GaugeValue, float64(stats.Bytes))
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions))
+}
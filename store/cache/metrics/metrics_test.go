@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewDefault()
+	defer c.Close()
+
+	c.Set(ctx, "key1", "value")
+	c.Get(ctx, "key1")
+	c.Get(ctx, "key1")
+	c.Get(ctx, "missing")
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector(c, "users"))
+	registry.MustRegister(NewCollector(cache.NewDefault(), "settings"))
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+
+	got := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			if metric.GetLabel()[0].GetValue() != "users" {
+				continue
+			}
+			value := metric.GetGauge().GetValue() + metric.GetCounter().GetValue()
+			got[family.GetName()] = value
+		}
+	}
+
+	want := map[string]float64{
+		"memos_cache_items":           1,
+		"memos_cache_bytes":           float64(len("value")),
+		"memos_cache_hits_total":      2,
+		"memos_cache_misses_total":    1,
+		"memos_cache_evictions_total": 0,
+	}
+	for name, value := range want {
+		if v, ok := got[name]; !ok || v != value {
+			t.Errorf("Expected %s=%v, got %v (present: %v)", name, value, v, ok)
+		}
+	}
+	if len(families) != len(want) {
+		t.Errorf("Expected %d metric families, got %d", len(want), len(families))
+	}
+}
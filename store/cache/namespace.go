@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// namespaceSeparator joins the levels of a namespace prefix.
+const namespaceSeparator = ":"
+
+// Namespace is a view of a Cache whose keys are transparently prefixed, giving a
+// subsystem its own key space in a shared cache. Entries are stored in the underlying
+// cache under their full key, so they share its limits, eviction and callbacks.
+type Namespace struct {
+	cache  *Cache
+	prefix string
+}
+
+var _ Interface = (*Namespace)(nil)
+
+// Namespace returns a view of the cache whose keys are prefixed with prefix and a colon.
+func (c *Cache) Namespace(prefix string) *Namespace {
+	return &Namespace{cache: c, prefix: prefix + namespaceSeparator}
+}
+
+// Namespace returns a view nested within this one.
+func (n *Namespace) Namespace(prefix string) *Namespace {
+	return &Namespace{cache: n.cache, prefix: n.prefix + prefix + namespaceSeparator}
+}
+
+// Set adds a value to the namespace with the default TTL.
+func (n *Namespace) Set(ctx context.Context, key string, value any) error {
+	return n.cache.Set(ctx, n.prefix+key, value)
+}
+
+// SetWithTTL adds a value to the namespace with a custom TTL.
+func (n *Namespace) SetWithTTL(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return n.cache.SetWithTTL(ctx, n.prefix+key, value, ttl)
+}
+
+// Get retrieves a value from the namespace.
+func (n *Namespace) Get(ctx context.Context, key string) (any, bool) {
+	return n.cache.Get(ctx, n.prefix+key)
+}
+
+// Delete removes a value from the namespace.
+func (n *Namespace) Delete(ctx context.Context, key string) error {
+	return n.cache.Delete(ctx, n.prefix+key)
+}
+
+// Clear removes every value in the namespace, including nested namespaces,
+// and leaves the rest of the cache alone.
+func (n *Namespace) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	n.cache.DeletePrefix(ctx, n.prefix)
+	return nil
+}
+
+// Size returns the number of live items in the namespace, including nested namespaces.
+// Unlike Cache.Size it scans every shard.
+func (n *Namespace) Size() int64 {
+	now := n.cache.now()
+	var size int64
+	for _, s := range n.cache.shards {
+		s.mu.Lock()
+		for key, itm := range s.items {
+			if strings.HasPrefix(key, n.prefix) && itm.live(now) {
+				size++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return size
+}
+
+// Close is a no-op: the view does not own the underlying cache, which must be
+// closed on its own.
+func (n *Namespace) Close() error {
+	return nil
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNamespaceIsolation(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	memos, users := cache.Namespace("memos"), cache.Namespace("users")
+	memos.Set(ctx, "1", "memo")
+	users.Set(ctx, "1", "user")
+	cache.Set(ctx, "1", "global")
+
+	if val, ok := memos.Get(ctx, "1"); !ok || val != "memo" {
+		t.Errorf("Expected 'memo' in the memos namespace, got %v", val)
+	}
+	if val, ok := users.Get(ctx, "1"); !ok || val != "user" {
+		t.Errorf("Expected 'user' in the users namespace, got %v", val)
+	}
+	if val, ok := cache.Get(ctx, "memos:1"); !ok || val != "memo" {
+		t.Errorf("Expected the namespaced key to be stored as 'memos:1', got %v", val)
+	}
+
+	users.Delete(ctx, "1")
+	if _, ok := memos.Get(ctx, "1"); !ok {
+		t.Errorf("Deleting from one namespace should not affect another")
+	}
+}
+
+func TestNamespaceClear(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	memos, users := cache.Namespace("memos"), cache.Namespace("users")
+	drafts := memos.Namespace("drafts")
+	memos.Set(ctx, "1", "memo")
+	drafts.Set(ctx, "1", "draft")
+	users.Set(ctx, "1", "user")
+	// A key sharing the prefix text but outside the namespace.
+	cache.Set(ctx, "memosx", "other")
+
+	if memos.Size() != 2 || drafts.Size() != 1 {
+		t.Errorf("Expected sizes 2 and 1, got %d and %d", memos.Size(), drafts.Size())
+	}
+	if err := memos.Clear(ctx); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if memos.Size() != 0 || drafts.Size() != 0 {
+		t.Errorf("Expected the namespace and its children to be empty, got %d and %d", memos.Size(), drafts.Size())
+	}
+	if users.Size() != 1 || cache.Size() != 2 {
+		t.Errorf("Clearing one namespace should leave the rest intact, got %d users and %d total", users.Size(), cache.Size())
+	}
+	if err := memos.Close(); err != nil || cache.Set(ctx, "after", 1) != nil {
+		t.Errorf("Closing a view should leave the cache open")
+	}
+}
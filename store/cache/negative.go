@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// EntryState describes what a lookup found for a key.
+type EntryState int
+
+const (
+	// Miss means the cache knows nothing about the key.
+	Miss EntryState = iota
+	// Hit means the key holds a live value.
+	Hit
+	// NegativeHit means the key was recorded as not found by SetNotFound,
+	// so the caller can skip the underlying store.
+	NegativeHit
+)
+
+// String returns the name of the entry state.
+func (s EntryState) String() string {
+	switch s {
+	case Miss:
+		return "miss"
+	case Hit:
+		return "hit"
+	case NegativeHit:
+		return "negative hit"
+	}
+	return "unknown"
+}
+
+// SetNotFound records that key does not exist in the underlying store, so that
+// repeated lookups for it can be answered without querying the store again.
+// The tombstone expires on its own ttl, which should usually be short,
+// and is overwritten by any later Set. Get treats it as a miss.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) SetNotFound(ctx context.Context, key string, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	if err := c.validateKey(key); err != nil {
+		return err
+	}
+
+	itm := c.newItem(key, nil, c.expiresAt(ttl))
+	itm.negative = true
+	c.insert(itm)
+	return nil
+}
+
+// Lookup retrieves a value from the cache like Get, but also reports whether
+// the key was recorded as not found. The value is only set for a Hit.
+// A NegativeHit counts as a hit in the cache statistics.
+// If ctx is already done, Lookup reports a Miss without touching the cache or its counters.
+func (c *Cache) Lookup(ctx context.Context, key string) (any, EntryState) {
+	if ctx.Err() != nil {
+		return nil, Miss
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	value, state, evicted := c.lookupLocked(s, key, c.now(), nil)
+	s.mu.Unlock()
+
+	if state == NegativeHit {
+		atomic.AddInt64(&c.counters.Load().hits, 1)
+	}
+	c.notifyEvicted(evicted)
+	if state == Miss {
+		c.notifyMiss(key)
+	}
+	return c.readValue(value), state
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheNegativeEntry(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	if err := cache.SetNotFound(ctx, "memo:404", 50*time.Millisecond); err != nil {
+		t.Fatalf("SetNotFound failed: %v", err)
+	}
+	if val, state := cache.Lookup(ctx, "memo:404"); state != NegativeHit || val != nil {
+		t.Errorf("Expected a negative hit, got %v (%v)", val, state)
+	}
+	// A tombstone is never returned as a real value.
+	if val, ok := cache.Get(ctx, "memo:404"); ok {
+		t.Errorf("Expected Get to miss on a negative entry, got %v", val)
+	}
+	if keys := cache.Keys(); len(keys) != 0 {
+		t.Errorf("Expected negative entries to be hidden from Keys, got %v", keys)
+	}
+
+	clock.Advance(50*time.Millisecond + time.Nanosecond)
+	if _, state := cache.Lookup(ctx, "memo:404"); state != Miss {
+		t.Errorf("Expected a miss after the negative entry expired, got %v", state)
+	}
+}
+
+func TestCacheNegativeEntryOverwritten(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.SetNotFound(ctx, "memo:1", time.Minute)
+	cache.Set(ctx, "memo:1", "created")
+	if val, state := cache.Lookup(ctx, "memo:1"); state != Hit || val != "created" {
+		t.Errorf("Expected 'created', got %v (%v)", val, state)
+	}
+
+	cache.SetNotFound(ctx, "counter", time.Minute)
+	if n, err := cache.Increment(ctx, "counter", 2); err != nil || n != 2 {
+		t.Errorf("Expected Increment to replace the negative entry, got %d, err: %v", n, err)
+	}
+}
+
+func TestCacheNegativeEntrySized(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.SetNotFound(ctx, "memo:404", time.Minute)
+	cache.GetOrSetWithErrorTTL(ctx, "memo:500", func(context.Context) (any, error) {
+		return nil, errors.New("database unavailable")
+	}, time.Minute, time.Minute)
+
+	// Tombstones count against MaxBytes like any other entry.
+	for _, key := range []string{"memo:404", "memo:500"} {
+		s := cache.shardFor(key)
+		s.mu.Lock()
+		itm, ok := s.items[key]
+		s.mu.Unlock()
+		if !ok || itm.size == 0 {
+			t.Errorf("Expected %s to be stored with a size", key)
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// Option customizes a Config at construction time.
+type Option func(*Config)
+
+// WithOnEvict registers a callback that runs whenever an item leaves the cache,
+// along with the reason it left.
+func WithOnEvict(fn func(key string, value any, reason EvictReason)) Option {
+	return func(c *Config) {
+		c.OnEvict = fn
+	}
+}
+
+// WithOnMiss registers a lightweight callback that runs, outside the cache lock,
+// with the key of every read that misses. Unlike a loader it never changes the
+// result or the cache contents.
+func WithOnMiss(fn func(key string)) Option {
+	return func(c *Config) {
+		c.OnMiss = fn
+	}
+}
+
+// WithShards sets the number of independently locked partitions of the key space.
+// Fewer shards make LRU order more exact; more shards reduce lock contention.
+// A single shard also skips key hashing, which suits small, uncontended caches.
+// n is rounded up to a power of two.
+func WithShards(n int) Option {
+	return func(c *Config) {
+		c.Shards = n
+	}
+}
+
+// WithHasher replaces the hash that maps keys to shards, for key schemes that the
+// built-in FNV-1a hash spreads unevenly. The hash must be deterministic.
+func WithHasher(hash func(key string) uint64) Option {
+	return func(c *Config) {
+		c.Hasher = hash
+	}
+}
+
+// WithCodec sets the Codec used to serialize values in snapshots, such as MsgpackCodec.
+func WithCodec(codec Codec) Option {
+	return func(c *Config) {
+		c.Codec = codec
+	}
+}
+
+// WithAOF keeps an append-only log of every write at path, like the Redis AOF. A new
+// cache replays the log to rebuild its entries with their remaining TTLs, then keeps
+// logging to it, rewriting it from the live entries whenever it has doubled in size.
+// Unlike a snapshot, the log survives a crash, losing at most the writes not yet
+// synced under the AOFSync policy. Values are serialized with the Codec, so they need
+// to round-trip through it. Renewals by sliding expiration are not logged. If the log
+// cannot be read or written when the cache is created, the error is logged and the
+// cache runs without it.
+func WithAOF(path string) Option {
+	return func(c *Config) {
+		c.AOFPath = path
+	}
+}
+
+// WithAOFSync sets the fsync policy of the append-only log; see WithAOF.
+func WithAOFSync(policy AOFSync) Option {
+	return func(c *Config) {
+		c.AOFSync = policy
+	}
+}
+
+// WithEncryption encrypts snapshot values with AES-GCM under key, which must be 16, 24
+// or 32 bytes long; an invalid key makes SaveSnapshot and LoadSnapshot fail. To encrypt
+// values sent to Redis, set RedisConfig.Codec to an EncryptedCodec.
+func WithEncryption(key []byte) Option {
+	return func(c *Config) {
+		c.EncryptionKey = key
+	}
+}
+
+// WithClock sets the function the cache reads the current time from
+// for expiry decisions, including the janitor's.
+func WithClock(now func() time.Time) Option {
+	return func(c *Config) {
+		c.Clock = now
+	}
+}
+
+// WithRandSource seeds the randomized decisions of the cache, such as TTL jitter and
+// sampled sweeps, with seed, so that two caches created with the same seed and fed
+// the same calls in the same order make the same decisions. It is meant for tests;
+// production caches should keep the default, randomly seeded source.
+func WithRandSource(seed int64) Option {
+	return func(c *Config) {
+		c.RandSource = rand.NewPCG(uint64(seed), 0)
+	}
+}
+
+// WithCompression stores []byte values longer than threshold bytes gzip-compressed,
+// decompressing them transparently on read. The byte budget counts the compressed size.
+func WithCompression(threshold int) Option {
+	return func(c *Config) {
+		c.CompressThreshold = threshold
+	}
+}
+
+// WithTTLJitter randomizes each entry's TTL by up to ±fraction of its nominal value,
+// spreading out the expiry of entries written in the same burst.
+func WithTTLJitter(fraction float64) Option {
+	return func(c *Config) {
+		c.TTLJitter = fraction
+	}
+}
+
+// WithStaleIfError makes a LoadingCache serve a value for up to d past its TTL
+// while its loader fails, for example during a database outage.
+func WithStaleIfError(d time.Duration) Option {
+	return func(c *Config) {
+		c.StaleIfError = d
+	}
+}
+
+// WithMemoryPressure makes the cache shed least recently used entries while memory
+// usage is above high, until it drops to low; see Config.MemoryHighWater.
+func WithMemoryPressure(high, low uint64) Option {
+	return func(c *Config) {
+		c.MemoryHighWater = high
+		c.MemoryLowWater = low
+	}
+}
+
+// WithGeneration sets the number of the generation entries are first written in.
+// BumpGeneration moves to the next one, invalidating every entry written before.
+func WithGeneration(n int) Option {
+	return func(c *Config) {
+		c.Generation = n
+	}
+}
+
+// WithFairEviction makes eviction fair between the tenants tenantOf assigns keys to,
+// weighted by weights, which may be nil for equal shares; see Config.TenantOf.
+func WithFairEviction(tenantOf func(key string) string, weights map[string]float64) Option {
+	return func(c *Config) {
+		c.TenantOf = tenantOf
+		c.TenantWeights = weights
+	}
+}
+
+// WithEvictionBatch evicts at most n entries per write, leaving the rest to later
+// writes and the janitor, at the cost of exceeding the limits for a while; see
+// Config.EvictionBatch.
+func WithEvictionBatch(n int) Option {
+	return func(c *Config) {
+		c.EvictionBatch = n
+	}
+}
+
+// WithMaxValueBytes rejects entries larger than n bytes, as counted for MaxBytes,
+// with ErrValueTooLarge instead of letting them evict everything else.
+func WithMaxValueBytes(n int64) Option {
+	return func(c *Config) {
+		c.MaxValueBytes = n
+	}
+}
+
+// WithTagLimits caps the number of entries that may carry any one tag at maxKeysPerTag
+// and the number of tags one entry may carry at maxTagsPerEntry, bounding the tag index
+// and the cost of InvalidateTag. SetWithTags rejects an entry over either cap with
+// ErrTagLimit rather than dropping tags from it, since an entry missing from the tag index
+// would survive the invalidation of its tag. A non-positive cap means no limit.
+func WithTagLimits(maxKeysPerTag, maxTagsPerEntry int) Option {
+	return func(c *Config) {
+		c.MaxKeysPerTag = maxKeysPerTag
+		c.MaxTagsPerEntry = maxTagsPerEntry
+	}
+}
+
+// WithReadOnly makes the cache read-only when enabled, for replica and standby
+// instances that must never change a cache shared with the primary. Reads, including
+// loads through GetOrSet and LoadingCache, keep working, but their results are not
+// cached; writes such as Set, Delete and Clear leave the cache unchanged under the
+// ReadOnlyPolicy. Unlike SetEnabled(false), which turns the cache into a pass-through,
+// the entries already present keep being served.
+func WithReadOnly(enabled bool) Option {
+	return func(c *Config) {
+		c.ReadOnly = enabled
+	}
+}
+
+// WithReadOnlyPolicy sets what writes to a read-only cache do: ReadOnlyIgnore drops
+// them silently, and ReadOnlyReject makes them return ErrReadOnly.
+func WithReadOnlyPolicy(policy ReadOnlyPolicy) Option {
+	return func(c *Config) {
+		c.ReadOnlyPolicy = policy
+	}
+}
+
+// WithCopyOnGet makes every read, such as Get, GetMulti, Lookup and GetOrSet, return
+// its own copy of the stored value, made by fn or by DeepCopy if fn is nil, so that
+// a caller mutating a cached struct cannot corrupt the cache or the value another
+// goroutine is reading. Copying costs an allocation per reference in the value on every
+// read, so only enable it for values that callers may mutate. Set stores the value it is
+// given, not a copy, and Clone and Range still share values with the cache.
+func WithCopyOnGet(fn func(value any) any) Option {
+	return func(c *Config) {
+		c.CopyOnGet = true
+		c.Copy = fn
+	}
+}
+
+// WithNegativeTTL makes a LoadingCache remember for ttl that its loader
+// returned ErrNotFound for a key.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(c *Config) {
+		c.NegativeTTL = ttl
+	}
+}
+
+// WithDefaultTTL sets the TTL that Set and the other writes without an explicit TTL
+// apply. SetWithTTL still overrides it, and a zero TTL there stores the entry without
+// expiration. A non-positive d makes entries written by Set never expire. MaxTTL,
+// if set, caps the default like any other TTL.
+func WithDefaultTTL(d time.Duration) Option {
+	return func(c *Config) {
+		c.DefaultTTL = d
+	}
+}
+
+// WithMaxTTL caps the TTL of every entry at d. Entries stored without a TTL
+// expire after d too.
+func WithMaxTTL(d time.Duration) Option {
+	return func(c *Config) {
+		c.MaxTTL = d
+	}
+}
+
+// WithWarmup registers fn to preload hot keys when Warm is called, typically once
+// right after construction and before the cache serves traffic.
+func WithWarmup(fn func(ctx context.Context, c *Cache) error) Option {
+	return func(c *Config) {
+		c.Warmup = fn
+	}
+}
+
+// WithKeyValidator vets the key of every write with validate, for example to cap
+// key length or enforce a namespace prefix. A write whose key is rejected returns
+// the validator's error and leaves the cache unchanged.
+func WithKeyValidator(validate func(key string) error) Option {
+	return func(c *Config) {
+		c.KeyValidator = validate
+	}
+}
+
+// WithLogger sends the cache's debug logs and warnings to logger.
+// By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *Config) {
+		c.Logger = logger
+	}
+}
+
+// WithEvictionLogRate caps the eviction debug logs written per second at n,
+// summarizing the rest. A negative n lifts the cap.
+func WithEvictionLogRate(n int) Option {
+	return func(c *Config) {
+		c.EvictionLogRate = n
+	}
+}
+
+// WithAdmissionPolicy sets the policy that decides whether a new key may evict
+// another one once the cache is full, for example NewTinyLFU. Nil turns admission off.
+func WithAdmissionPolicy(policy AdmissionPolicy) Option {
+	return func(c *Config) {
+		c.Admission = policy
+	}
+}
+
+// WithHighWaterMark calls fn once usage reaches fraction of the item or byte limit,
+// and again each time it climbs back after dropping below.
+func WithHighWaterMark(fraction float64, fn func(current, max int64)) Option {
+	return func(c *Config) {
+		c.HighWaterMark = fraction
+		c.OnHighWaterMark = fn
+	}
+}
+
+// WithWeigher makes the byte budget a budget on the total weight that fn assigns
+// to the entries, instead of on their estimated size.
+func WithWeigher(fn func(key string, value any) int64) Option {
+	return func(c *Config) {
+		c.Weigher = fn
+	}
+}
+
+// WithSweepStrategy selects how the janitor finds expired items.
+func WithSweepStrategy(strategy SweepStrategy) Option {
+	return func(c *Config) {
+		c.SweepStrategy = strategy
+	}
+}
+
+// WithSweepInterval makes the janitor interval adaptive between minInterval and
+// maxInterval: it sweeps more often while many entries expire and less often while
+// few do; see Config.SweepMaxInterval.
+func WithSweepInterval(minInterval, maxInterval time.Duration) Option {
+	return func(c *Config) {
+		c.SweepMinInterval = minInterval
+		c.SweepMaxInterval = maxInterval
+	}
+}
+
+// WithSlidingExpiration makes every read that returns a value restart its TTL when
+// enabled, for session-style entries that should live as long as they are in use.
+func WithSlidingExpiration(enabled bool) Option {
+	return func(c *Config) {
+		c.SlidingExpiration = enabled
+	}
+}
+
+// WithMaxConcurrentLoads limits the number of loader calls running at once to n,
+// protecting the backing store from a burst of misses on distinct keys.
+func WithMaxConcurrentLoads(n int) Option {
+	return func(c *Config) {
+		c.MaxConcurrentLoads = n
+	}
+}
+
+// WithDebugChecks enables Verify, the self-check of the internal bookkeeping, and
+// runs it after every janitor sweep. Verify locks the whole cache, so enable it in
+// tests and while chasing a bug, not in production.
+func WithDebugChecks(enabled bool) Option {
+	return func(c *Config) {
+		c.DebugChecks = enabled
+	}
+}
+
+// WithPanicRecovery controls whether panics in user-supplied functions are recovered.
+// Recovery is on by default; turning it off can help when debugging.
+func WithPanicRecovery(enabled bool) Option {
+	return func(c *Config) {
+		c.DisablePanicRecovery = !enabled
+	}
+}
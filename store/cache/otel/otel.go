@@ -0,0 +1,66 @@
+// Package otel exports cache statistics as OpenTelemetry metrics.
+package otel
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+const prefix = "memos.cache."
+
+// StatsSource is anything that reports cache statistics, such as *cache.Cache.
+type StatsSource interface {
+	Stats() cache.Stats
+}
+
+// Register creates asynchronous instruments on meter that read the statistics of one
+// cache on every collection. The name is attached as the "cache" attribute so several
+// caches can be registered side by side. Unregister the returned registration to stop
+// observing the cache.
+func Register(meter metric.Meter, source StatsSource, name string) (metric.Registration, error) {
+	items, err := meter.Int64ObservableGauge(prefix+"items",
+		metric.WithDescription("Number of items currently stored in the cache."))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create items gauge")
+	}
+	bytes, err := meter.Int64ObservableGauge(prefix+"bytes", metric.WithUnit("By"),
+		metric.WithDescription("Approximate total size of the values stored in the cache."))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create bytes gauge")
+	}
+	hits, err := meter.Int64ObservableCounter(prefix+"hits",
+		metric.WithDescription("Number of cache lookups that found a live value."))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create hits counter")
+	}
+	misses, err := meter.Int64ObservableCounter(prefix+"misses",
+		metric.WithDescription("Number of cache lookups that found nothing."))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create misses counter")
+	}
+	evictions, err := meter.Int64ObservableCounter(prefix+"evictions",
+		metric.WithDescription("Number of items removed by TTL expiry or capacity pressure."))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create evictions counter")
+	}
+
+	attrs := metric.WithAttributes(attribute.String("cache", name))
+	registration, err := meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		stats := source.Stats()
+		o.ObserveInt64(items, stats.ItemCount, attrs)
+		o.ObserveInt64(bytes, stats.Bytes, attrs)
+		o.ObserveInt64(hits, stats.Hits, attrs)
+		o.ObserveInt64(misses, stats.Misses, attrs)
+		o.ObserveInt64(evictions, stats.Evictions, attrs)
+		return nil
+	}, items, bytes, hits, misses, evictions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register cache metrics callback")
+	}
+	return registration, nil
+}
@@ -0,0 +1,73 @@
+package otel
+
+import (
+	"context"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	c := cache.NewDefault()
+	defer c.Close()
+
+	c.Set(ctx, "key1", "value")
+	c.Get(ctx, "key1")
+	c.Get(ctx, "key1")
+	c.Get(ctx, "missing")
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(ctx)
+	registration, err := Register(provider.Meter("memos"), c, "users")
+	if err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	defer registration.Unregister()
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(ctx, &data); err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	got := make(map[string]int64)
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			var points []metricdata.DataPoint[int64]
+			switch d := m.Data.(type) {
+			case metricdata.Gauge[int64]:
+				points = d.DataPoints
+			case metricdata.Sum[int64]:
+				if !d.IsMonotonic {
+					t.Errorf("Expected %s to be a monotonic counter", m.Name)
+				}
+				points = d.DataPoints
+			}
+			for _, p := range points {
+				if v, _ := p.Attributes.Value("cache"); v.AsString() == "users" {
+					got[m.Name] = p.Value
+				}
+			}
+		}
+	}
+
+	want := map[string]int64{
+		"memos.cache.items":     1,
+		"memos.cache.bytes":     int64(len("value")),
+		"memos.cache.hits":      2,
+		"memos.cache.misses":    1,
+		"memos.cache.evictions": 0,
+	}
+	for name, value := range want {
+		if v, ok := got[name]; !ok || v != value {
+			t.Errorf("Expected %s=%d, got %d (present: %v)", name, value, v, ok)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d instruments, got %v", len(want), got)
+	}
+}
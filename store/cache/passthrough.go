@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SetEnabled turns the cache on or off at runtime. A disabled cache passes every
+// request through: lookups miss, so GetOrSet and LoadingCache always call their
+// loader, and writes are dropped, except that Increment, which has no count to
+// report, returns ErrDisabled. Disabling empties the cache silently, so turning
+// it back on resumes normal operation with a cold cache.
+func (c *Cache) SetEnabled(enabled bool) {
+	if !enabled {
+		if atomic.CompareAndSwapInt32(&c.disabled, 0, 1) {
+			// Writers check the flag under their shard lock, so none can slip in after this.
+			c.clear(context.Background(), false)
+		}
+		return
+	}
+	atomic.StoreInt32(&c.disabled, 0)
+}
+
+// Enabled reports whether the cache is on; see SetEnabled.
+func (c *Cache) Enabled() bool {
+	return atomic.LoadInt32(&c.disabled) == 0
+}
@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCacheSetEnabled(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "cached")
+	if !cache.Enabled() {
+		t.Fatalf("Expected a new cache to be enabled")
+	}
+
+	cache.SetEnabled(false)
+	if cache.Enabled() {
+		t.Errorf("Expected the cache to be disabled")
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected every Get to miss while disabled")
+	}
+	if err := cache.Set(ctx, "memo:2", "value"); err != nil {
+		t.Errorf("Expected Set to succeed as a no-op, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "memo:2"); ok || cache.Size() != 0 {
+		t.Errorf("Expected writes to be dropped while disabled, size %d", cache.Size())
+	}
+	for i := 0; i < 2; i++ {
+		if n, err := cache.Increment(ctx, "counter", 1); !errors.Is(err, ErrDisabled) || n != 0 {
+			t.Errorf("Expected Increment to report ErrDisabled while disabled, got %d, %v", n, err)
+		}
+	}
+	loads := 0
+	for i := 0; i < 2; i++ {
+		cache.GetOrSet(ctx, "memo:3", func(context.Context) (any, error) {
+			loads++
+			return "fresh", nil
+		})
+	}
+	if loads != 2 {
+		t.Errorf("Expected GetOrSet to load on every call while disabled, loaded %d times", loads)
+	}
+
+	// Turning the cache back on starts cold and caches normally again.
+	cache.SetEnabled(true)
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected entries from before disabling to be gone")
+	}
+	cache.Set(ctx, "memo:1", "cached")
+	if val, ok := cache.Get(ctx, "memo:1"); !ok || val != "cached" {
+		t.Errorf("Expected normal operation once re-enabled, got %v", val)
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// itemPool recycles the wrappers of entries that left the cache, so that a cache
+// under churn, where every write evicts an entry, does not allocate one per write.
+var itemPool = sync.Pool{
+	New: func() any { return new(item) },
+}
+
+// poolDisabled makes allocItem allocate every item and recycleItem drop them, so that
+// benchmarks can measure what the pool saves.
+var poolDisabled atomic.Bool
+
+// allocItem returns an empty item, recycled if one is available.
+func allocItem() *item {
+	if poolDisabled.Load() {
+		return new(item)
+	}
+	return itemPool.Get().(*item)
+}
+
+// recycleItem clears an item that is no longer reachable from the cache, so that the
+// pool retains neither its value nor its key, and returns it to the pool. It must only
+// be called once nothing refers to the item anymore, as it may be reused right away.
+func recycleItem(itm *item) {
+	*itm = item{}
+	if !poolDisabled.Load() {
+		itemPool.Put(itm)
+	}
+}
+
+// dropLocked removes an item, records it in evicted with reason and recycles it.
+// The caller must hold s.mu and must not use itm afterwards.
+func (c *Cache) dropLocked(s *shard, itm *item, reason EvictReason, evicted []evictedItem) []evictedItem {
+	c.removeLocked(s, itm)
+	evicted = append(evicted, evictedItem{itm.key, itm.value, reason})
+	recycleItem(itm)
+	return evicted
+}
@@ -0,0 +1,35 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+// BenchmarkCacheChurn writes distinct keys to a full cache, so that every write
+// evicts an entry and replaces its wrapper, with and without recycling the wrappers.
+func BenchmarkCacheChurn(b *testing.B) {
+	b.Run("pooled", benchmarkCacheChurn)
+	b.Run("unpooled", func(b *testing.B) {
+		poolDisabled.Store(true)
+		defer poolDisabled.Store(false)
+		benchmarkCacheChurn(b)
+	})
+}
+
+func benchmarkCacheChurn(b *testing.B) {
+	ctx := context.Background()
+	cache := NewWithCapacity(1000, WithShards(1))
+	defer cache.Close()
+	keys := benchmarkKeys(10000)
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		values[i] = key
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := i % len(keys)
+		cache.Set(ctx, keys[j], values[j])
+	}
+}
@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultMemoryCheckInterval is how often memory usage is polled by default.
+	defaultMemoryCheckInterval = time.Second
+	// defaultMemoryLowWaterFraction is the default low-water mark as a fraction of the high one.
+	defaultMemoryLowWaterFraction = 0.9
+)
+
+// liveHeapMetric is the runtime/metrics sample the default MemoryUsage reads.
+const liveHeapMetric = "/gc/heap/live:bytes"
+
+// memoryPressureLoop polls memory usage and sheds entries while it is above the
+// high-water mark, until Close.
+func (c *Cache) memoryPressureLoop() {
+	defer c.refreshWG.Done()
+	interval := c.config.MemoryCheckInterval
+	if interval <= 0 {
+		interval = defaultMemoryCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.relieveMemoryPressure()
+		case <-c.refreshCtx.Done():
+			return
+		}
+	}
+}
+
+// relieveMemoryPressure sheds entries while memory usage is above the high-water mark,
+// until it drops to the low-water mark. Each round evicts the share of entries by which
+// usage exceeds the low-water mark. Memory freed by eviction often only shows up in
+// usage after the garbage collector runs, so a round that does not lower usage ends
+// the call and leaves the rest to the next poll. It returns the number of entries evicted.
+func (c *Cache) relieveMemoryPressure() int {
+	usage := c.memoryUsage()
+	if usage <= c.config.MemoryHighWater {
+		return 0
+	}
+	low := c.config.MemoryLowWater
+	if low == 0 || low > c.config.MemoryHighWater {
+		low = uint64(float64(c.config.MemoryHighWater) * defaultMemoryLowWaterFraction)
+	}
+
+	var evicted []evictedItem
+	for usage > low {
+		items := atomic.LoadInt64(&c.itemCount)
+		if items == 0 {
+			break
+		}
+		n := max(int(float64(items)*float64(usage-low)/float64(usage)), 1)
+		before := len(evicted)
+		evicted = c.evictLRU(n, evicted)
+		if len(evicted) == before {
+			break
+		}
+		previous := usage
+		if usage = c.memoryUsage(); usage >= previous {
+			break
+		}
+	}
+	c.notifyEvicted(evicted)
+	return len(evicted)
+}
+
+// memoryUsage returns the current memory usage in bytes.
+func (c *Cache) memoryUsage() uint64 {
+	if c.config.MemoryUsage != nil {
+		return c.config.MemoryUsage()
+	}
+	sample := []metrics.Sample{{Name: liveHeapMetric}}
+	metrics.Read(sample)
+	if sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
+
+// evictLRU evicts up to n least recently used items, one per shard in turn.
+// It takes each shard lock on its own, so it must be called without holding any.
+func (c *Cache) evictLRU(n int, evicted []evictedItem) []evictedItem {
+	start := int(atomic.AddUint32(&c.overflowCursor, 1))
+	for n > 0 {
+		progress := false
+		for i := range c.shards {
+			if n == 0 {
+				break
+			}
+			s := c.shards[(start+i)%len(c.shards)]
+			s.mu.Lock()
+			if s.lru.len > 0 {
+				evicted = c.evictLocked(s, evicted)
+				n--
+				progress = true
+			}
+			s.mu.Unlock()
+		}
+		if !progress {
+			break
+		}
+	}
+	return evicted
+}
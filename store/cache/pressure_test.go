@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheMemoryPressure(t *testing.T) {
+	ctx := context.Background()
+	// Simulate a process whose memory is 20kB plus what the cache holds.
+	const base = 20_000
+	var current atomic.Pointer[Cache]
+	config := Config{
+		MemoryCheckInterval: time.Millisecond,
+		MemoryUsage: func() uint64 {
+			if cache := current.Load(); cache != nil {
+				return base + uint64(atomic.LoadInt64(&cache.bytes))
+			}
+			return base
+		},
+	}
+	cache := New(config,
+		WithWeigher(func(string, any) int64 { return 100 }),
+		WithMemoryPressure(100_000, 70_000))
+	defer cache.Close()
+	current.Store(cache)
+
+	// 700 entries keep usage at 90kB, below the high-water mark.
+	for i := 0; i < 700; i++ {
+		cache.Set(ctx, fmt.Sprintf("memo:%d", i), i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if cache.Len() != 700 {
+		t.Fatalf("Expected no shedding below the high-water mark, got %d entries", cache.Len())
+	}
+
+	// 300 more take usage to 120kB; shedding brings it down to the 70kB low-water mark.
+	for i := 700; i < 1000; i++ {
+		cache.Set(ctx, fmt.Sprintf("memo:%d", i), i)
+	}
+	eventually(t, func() bool { return cache.Len() <= 500 }, "Expected entries to be shed down to the low-water mark")
+	if n := cache.Len(); n < 450 {
+		t.Errorf("Expected shedding to stop near the low-water mark, got %d entries", n)
+	}
+	// The most recently written entries survive.
+	if _, ok := cache.Get(ctx, "memo:999"); !ok {
+		t.Errorf("Expected the newest entry to survive")
+	}
+	if _, ok := cache.Get(ctx, "memo:0"); ok {
+		t.Errorf("Expected the oldest entry to be shed")
+	}
+}
+
+func TestCacheMemoryPressureStopsOnClose(t *testing.T) {
+	before := runtime.NumGoroutine()
+	cache := New(Config{}, WithMemoryPressure(1<<40, 0))
+	cache.Close()
+	eventually(t, func() bool { return runtime.NumGoroutine() <= before }, "Memory-pressure routine should exit on Close")
+}
+
+func TestRelieveMemoryPressureWaitsForGC(t *testing.T) {
+	ctx := context.Background()
+	// Usage that does not drop on eviction, like a heap before the next GC.
+	cache := New(Config{MemoryUsage: func() uint64 { return 200 }}, WithMemoryPressure(100, 50))
+	defer cache.Close()
+	for i := 0; i < 100; i++ {
+		cache.Set(ctx, fmt.Sprintf("memo:%d", i), i)
+	}
+	// One round evicts the excess share, 75%, and waits for usage to reflect it.
+	if n := cache.relieveMemoryPressure(); n != 75 {
+		t.Errorf("Expected a single round of 75 evictions, got %d", n)
+	}
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// newRand returns the random number generator behind the randomized decisions of a
+// cache, such as TTL jitter and sampled sweeps: one drawing from src if it is set,
+// and from the randomly seeded global source otherwise.
+func newRand(src rand.Source) *rand.Rand {
+	if src == nil {
+		return rand.New(globalSource{})
+	}
+	return rand.New(&lockedSource{src: src})
+}
+
+// globalSource draws from the global source of math/rand/v2, which is safe for
+// concurrent use without a lock of its own.
+type globalSource struct{}
+
+func (globalSource) Uint64() uint64 {
+	return rand.Uint64()
+}
+
+// lockedSource makes a Source safe for concurrent use. A rand.Rand keeps no state
+// besides its Source, so one over a lockedSource is safe for concurrent use too.
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Uint64()
+}
@@ -0,0 +1,35 @@
+package cache
+
+// ReadOnlyPolicy is what a read-only cache does with writes; see WithReadOnly.
+type ReadOnlyPolicy int
+
+const (
+	// ReadOnlyIgnore drops writes silently: they report success, or false and zero
+	// where they return a bool or a count, and leave the cache unchanged.
+	ReadOnlyIgnore ReadOnlyPolicy = iota
+	// ReadOnlyReject makes writes that report errors return ErrReadOnly.
+	// Writes that only return a bool or a count report false and zero, as under ReadOnlyIgnore.
+	ReadOnlyReject
+)
+
+// ReadOnly reports whether the cache rejects or ignores writes; see WithReadOnly.
+func (c *Cache) ReadOnly() bool {
+	return c.config.ReadOnly
+}
+
+// checkWritable reports whether a write must be skipped because the cache is read-only,
+// and the error to return for it under the ReadOnlyPolicy.
+func (c *Cache) checkWritable() (skip bool, err error) {
+	if !c.config.ReadOnly {
+		return false, nil
+	}
+	return true, readOnlyError(c.config.ReadOnlyPolicy)
+}
+
+// readOnlyError is the error a skipped write returns under policy.
+func readOnlyError(policy ReadOnlyPolicy) error {
+	if policy == ReadOnlyReject {
+		return ErrReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCacheReadOnly(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		name    string
+		policy  ReadOnlyPolicy
+		wantErr error
+	}{
+		{"ignore", ReadOnlyIgnore, nil},
+		{"reject", ReadOnlyReject, ErrReadOnly},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			aofPath := filepath.Join(t.TempDir(), "cache.aof")
+			snapshot := new(bytes.Buffer)
+			primary := NewDefault(WithAOF(aofPath))
+			primary.Set(ctx, "new", "value")
+			if err := primary.SaveSnapshot(snapshot); err != nil {
+				t.Fatalf("SaveSnapshot failed: %v", err)
+			}
+			primary.Close()
+			logged, err := os.ReadFile(aofPath)
+			if err != nil {
+				t.Fatalf("Failed to read the append-only log: %v", err)
+			}
+
+			// Neither the log of the primary nor a snapshot of it fill the replica.
+			replica := NewDefault(WithReadOnly(true), WithReadOnlyPolicy(tt.policy), WithAOF(aofPath))
+			defer replica.Close()
+			if !replica.ReadOnly() {
+				t.Fatalf("Expected ReadOnly to report true")
+			}
+			if replica.Size() != 0 {
+				t.Errorf("Expected the append-only log not to be replayed, got %d entries", replica.Size())
+			}
+			if after, _ := os.ReadFile(aofPath); !bytes.Equal(after, logged) {
+				t.Errorf("Expected the append-only log to be left alone")
+			}
+
+			checkErr := func(op string, err error) {
+				t.Helper()
+				if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s: expected %v, got %v", op, tt.wantErr, err)
+				}
+			}
+			checkErr("LoadSnapshot", replica.LoadSnapshot(snapshot))
+
+			// Fill the replica behind the read-only checks, as its source would.
+			replica.insert(replica.newItem("key", "value", time.Time{}))
+			tagged := replica.newItem("tagged", "value", time.Time{})
+			tagged.tags = []string{"tag"}
+			replica.insert(tagged)
+			replica.insert(replica.newItem("count", int64(1), time.Time{}))
+
+			checkErr("Set", replica.Set(ctx, "key", "changed"))
+			checkErr("Set new key", replica.Set(ctx, "new", "value"))
+			checkErr("SetWithTTL", replica.SetWithTTL(ctx, "new", "value", time.Minute))
+			checkErr("SetWithDeadline", replica.SetWithDeadline(ctx, "key", "value", time.Now().Add(-time.Minute)))
+			checkErr("SetMulti", replica.SetMulti(ctx, map[string]any{"new": "value"}))
+			checkErr("SetNotFound", replica.SetNotFound(ctx, "key", time.Minute))
+			checkErr("Delete", replica.Delete(ctx, "key"))
+			checkErr("DeleteMulti", replica.DeleteMulti(ctx, []string{"key"}))
+			checkErr("Clear", replica.Clear(ctx))
+			checkErr("Remove", replica.Remove(ctx, "key"))
+			if replica.SetIfAbsent(ctx, "new", "value") || replica.ReplaceIfPresent(ctx, "key", "changed") {
+				t.Errorf("Expected conditional writes to report false")
+			}
+			if n := replica.InvalidateTag(ctx, "tag"); n != 0 {
+				t.Errorf("Expected InvalidateTag to remove nothing, removed %d", n)
+			}
+			if _, err := replica.Increment(ctx, "count", 1); !errors.Is(err, ErrReadOnly) {
+				t.Errorf("Expected ErrReadOnly from Increment, got %v", err)
+			}
+			if _, ok := replica.GetAndDelete(ctx, "key"); ok {
+				t.Errorf("Expected GetAndDelete to report a miss")
+			}
+			if replica.CompareAndSwap(ctx, "key", "value", "changed", nil) || replica.Touch(ctx, "key", time.Hour) {
+				t.Errorf("Expected CompareAndSwap and Touch to report false")
+			}
+			if n := replica.DeletePrefix(ctx, "k") + replica.DeleteFunc(ctx, func(string, any) bool { return true }); n != 0 {
+				t.Errorf("Expected DeletePrefix and DeleteFunc to remove nothing, removed %d", n)
+			}
+
+			stop, err := replica.RefreshAhead(ctx, "refreshed", time.Minute, 0.5, func(context.Context) (any, error) {
+				return "loaded", nil
+			})
+			checkErr("RefreshAhead", err)
+			if stop != nil {
+				stop()
+			}
+			if _, ok := replica.Get(ctx, "refreshed"); ok {
+				t.Errorf("Expected RefreshAhead to store nothing")
+			}
+
+			for key, want := range map[string]any{"key": "value", "tagged": "value"} {
+				if value, ok := replica.Get(ctx, key); !ok || value != want {
+					t.Errorf("Expected %q to still be %v, got %v, %v", key, want, value, ok)
+				}
+			}
+			if _, ok := replica.Get(ctx, "new"); ok {
+				t.Errorf("Expected the rejected write of new to have stored nothing")
+			}
+
+			// Loads still serve their result, without caching it.
+			calls := 0
+			loader := func(context.Context) (any, error) {
+				calls++
+				return "loaded", nil
+			}
+			for i := 0; i < 2; i++ {
+				if value, err := replica.GetOrSet(ctx, "missing", loader); err != nil || value != "loaded" {
+					t.Fatalf("GetOrSet: expected loaded, got %v, %v", value, err)
+				}
+			}
+			if calls != 2 {
+				t.Errorf("Expected the loaded value not to be cached, loader ran %d times", calls)
+			}
+		})
+	}
+}
+
+func TestRedisCacheReadOnly(t *testing.T) {
+	// Nothing listens on the address, so a write that reached Redis would fail.
+	for _, tt := range []struct {
+		policy  ReadOnlyPolicy
+		wantErr error
+	}{
+		{ReadOnlyIgnore, nil},
+		{ReadOnlyReject, ErrReadOnly},
+	} {
+		cache := NewRedis(RedisConfig{Addr: "127.0.0.1:1", ReadOnly: true, ReadOnlyPolicy: tt.policy})
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+
+		for op, err := range map[string]error{
+			"Put":         cache.Put(ctx, "key", "value", time.Minute),
+			"PutBytes":    cache.PutBytes(ctx, "key", []byte("value"), time.Minute),
+			"Remove":      cache.Remove(ctx, "key"),
+			"PutMulti":    cache.PutMulti(ctx, map[string]any{"key": "value"}, time.Minute),
+			"RemoveMulti": cache.RemoveMulti(ctx, []string{"key"}),
+		} {
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s with policy %d: expected %v, got %v", op, tt.policy, tt.wantErr, err)
+			}
+		}
+		if _, _, err := cache.Fetch(ctx, "key"); err == nil || errors.Is(err, ErrReadOnly) {
+			t.Errorf("Expected Fetch to reach Redis and fail to connect, got %v", err)
+		}
+		cancel()
+		cache.Close()
+	}
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// recoverPanic recovers a panic in the user-supplied function named by what, unless
+// panic recovery is disabled. If err is not nil the panic becomes an error wrapping
+// ErrPanic; otherwise it is logged. It must be deferred directly.
+func (c *Cache) recoverPanic(what string, err *error) {
+	if c.config.DisablePanicRecovery {
+		return
+	}
+	r := recover()
+	if r == nil {
+		return
+	}
+	if err != nil {
+		*err = errors.Wrapf(ErrPanic, "%s: %v", what, r)
+		return
+	}
+	c.logger.Warn("cache "+what+" panicked", "panic", r)
+}
+
+// callLoader runs loader once a load slot is free, turning a panic into an error,
+// and records it in LoaderStats.
+func (c *Cache) callLoader(ctx context.Context, loader func(context.Context) (any, error)) (value any, err error) {
+	if err := c.acquireLoadSlot(ctx); err != nil {
+		return nil, err
+	}
+	defer c.releaseLoadSlot()
+	start := time.Now()
+	defer func() { c.recordLoad(time.Since(start), err) }()
+	defer c.recoverPanic("loader", &err)
+	return loader(ctx)
+}
+
+// callBatchLoader is like callLoader for the loader of GetMultiOrLoad and LoadMulti.
+func (c *Cache) callBatchLoader(ctx context.Context, keys []string, loader func(context.Context, []string) (map[string]any, map[string]error, error)) (values map[string]any, keyErrs map[string]error, err error) {
+	if err := c.acquireLoadSlot(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer c.releaseLoadSlot()
+	start := time.Now()
+	defer func() { c.recordLoad(time.Since(start), err) }()
+	defer c.recoverPanic("loader", &err)
+	return loader(ctx, keys)
+}
+
+// sizeOf returns the weight of an entry if a Weigher is configured, and otherwise
+// estimates the size of value. It falls back to the default estimate if a Weigher
+// or Sizer panics.
+func (c *Cache) sizeOf(key string, value any) (size int64) {
+	size = 64 // Kept if the Weigher or Sizer panics
+	if c.config.Weigher != nil {
+		defer c.recoverPanic("weigher", nil)
+		return c.config.Weigher(key, value)
+	}
+	defer c.recoverPanic("sizer", nil)
+	return estimateSize(value)
+}
+
+// matches runs a user-supplied predicate, treating a panic as no match.
+// It is used under shard locks, where an escaping panic would leave the lock held.
+func (c *Cache) matches(pred func() bool) (matched bool) {
+	defer c.recoverPanic("predicate", nil)
+	return pred()
+}
+
+// acquireLoadSlot waits for a free slot under MaxConcurrentLoads or until ctx is done.
+func (c *Cache) acquireLoadSlot(ctx context.Context) error {
+	if c.loadSlots == nil {
+		return nil
+	}
+	select {
+	case c.loadSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseLoadSlot frees a slot taken by acquireLoadSlot.
+func (c *Cache) releaseLoadSlot() {
+	if c.loadSlots != nil {
+		<-c.loadSlots
+	}
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+type panickingSizer struct{}
+
+func (panickingSizer) Size() int64 { panic("sizer bug") }
+
+func TestCacheRecoversLoaderPanic(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	_, err := cache.GetOrSet(ctx, "memo:1", func(context.Context) (any, error) {
+		panic("loader bug")
+	})
+	if !errors.Is(err, ErrPanic) {
+		t.Errorf("Expected an error wrapping ErrPanic, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected nothing cached after a panicking loader")
+	}
+
+	// The key is not stuck in flight: the next call runs its loader.
+	if val, err := cache.GetOrSet(ctx, "memo:1", func(context.Context) (any, error) {
+		return "loaded", nil
+	}); err != nil || val != "loaded" {
+		t.Errorf("Expected 'loaded', got %v, err: %v", val, err)
+	}
+}
+
+func TestCacheRecoversOtherPanics(t *testing.T) {
+	ctx := context.Background()
+	handler := &captureHandler{}
+	cache := NewDefault(WithLogger(slog.New(handler)), WithKeyValidator(func(key string) error {
+		if key == "bad" {
+			panic("validator bug")
+		}
+		return nil
+	}))
+	defer cache.Close()
+
+	if err := cache.Set(ctx, "bad", "value"); !errors.Is(err, ErrPanic) {
+		t.Errorf("Expected an error wrapping ErrPanic from the validator, got %v", err)
+	}
+
+	cache.Set(ctx, "sized", panickingSizer{})
+	if bytes := cache.Stats().Bytes; bytes != 64 {
+		t.Errorf("Expected the default size for a panicking Sizer, got %d", bytes)
+	}
+
+	cache.Set(ctx, "key", "value")
+	if cache.CompareAndSwap(ctx, "key", "value", "new", func(any, any) bool { panic("comparator bug") }) {
+		t.Errorf("Expected a panicking comparator to fail the swap")
+	}
+	// The shard lock was released, so the cache is still usable.
+	if val, ok := cache.Get(ctx, "key"); !ok || val != "value" {
+		t.Errorf("Expected 'value', got %v, exists: %v", val, ok)
+	}
+
+	if warnings := handler.messages(slog.LevelWarn); len(warnings) != 2 {
+		t.Errorf("Expected warnings for the sizer and the comparator, got %v", warnings)
+	}
+}
+
+func TestCacheWithoutPanicRecovery(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithPanicRecovery(false), WithOnEvict(func(string, any, EvictReason) {
+		panic("callback bug")
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "key", "value")
+	defer func() {
+		if r := recover(); r != "callback bug" {
+			t.Errorf("Expected the callback panic to propagate, got %v", r)
+		}
+	}()
+	cache.Delete(ctx, "key")
+	t.Errorf("Expected Delete to panic")
+}
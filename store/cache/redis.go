@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig contains options for configuring a RedisCache.
+type RedisConfig struct {
+	// Addr is the host:port address of the Redis server.
+	Addr string
+
+	// Password is the optional password used to authenticate.
+	Password string
+
+	// DB is the Redis logical database to select.
+	DB int
+
+	// Codec serializes values. Defaults to JSONCodec.
+	// Use an EncryptedCodec to keep values encrypted at rest in Redis.
+	Codec Codec
+
+	// ReadOnly turns Put, PutBytes, Remove, PutMulti and RemoveMulti into no-ops, so
+	// that a replica can read the values the primary shares without ever changing them.
+	ReadOnly bool
+
+	// ReadOnlyPolicy is what writes do while ReadOnly is set. The default drops them
+	// silently; ReadOnlyReject makes them return ErrReadOnly.
+	ReadOnlyPolicy ReadOnlyPolicy
+
+	// Logger receives warnings, such as for undecodable messages on an InvalidationBus
+	// opened from the RedisCache. Nil discards them.
+	Logger *slog.Logger
+}
+
+// RedisCache is a Backend that stores values in Redis so every replica shares them.
+// Values are serialized with the configured Codec; since Fetch decodes into an
+// untyped value, use FetchInto to decode into a concrete type.
+type RedisCache struct {
+	client   *redis.Client
+	codec    Codec
+	readOnly bool
+	policy   ReadOnlyPolicy
+	logger   *slog.Logger
+}
+
+var _ Backend = (*RedisCache)(nil)
+
+// NewRedis creates a new Redis-backed cache with the given configuration.
+func NewRedis(config RedisConfig) *RedisCache {
+	codec := config.Codec
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+		// Apply the caller's context deadline to every network round trip.
+		ContextTimeoutEnabled: true,
+	})
+	return &RedisCache{
+		client:   client,
+		codec:    codec,
+		readOnly: config.ReadOnly,
+		policy:   config.ReadOnlyPolicy,
+		logger:   logger,
+	}
+}
+
+// Fetch retrieves a value from Redis.
+func (r *RedisCache) Fetch(ctx context.Context, key string) (any, bool, error) {
+	var value any
+	ok, err := r.FetchInto(ctx, key, &value)
+	if !ok || err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// FetchInto retrieves a value from Redis and decodes it into dst, which must be a pointer.
+func (r *RedisCache) FetchInto(ctx context.Context, key string, dst any) (bool, error) {
+	data, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, redisError(err, "failed to get key %q from redis", key)
+	}
+	if err := r.codec.Unmarshal(data, dst); err != nil {
+		return false, errors.Wrapf(err, "failed to decode key %q", key)
+	}
+	return true, nil
+}
+
+// Put stores a value in Redis. A non-positive TTL stores the value without expiration.
+func (r *RedisCache) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if r.readOnly {
+		return readOnlyError(r.policy)
+	}
+	data, err := r.codec.Marshal(value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to encode key %q", key)
+	}
+	if err := r.client.Set(ctx, key, data, redisTTL(ttl)).Err(); err != nil {
+		return redisError(err, "failed to set key %q in redis", key)
+	}
+	return nil
+}
+
+// PutBytes stores raw bytes in Redis as they are, skipping the Codec, which makes it
+// the cheaper path for values that are serialized already. They are not encrypted
+// even if the Codec is an EncryptedCodec.
+func (r *RedisCache) PutBytes(ctx context.Context, key string, b []byte, ttl time.Duration) error {
+	if r.readOnly {
+		return readOnlyError(r.policy)
+	}
+	if err := r.client.Set(ctx, key, b, redisTTL(ttl)).Err(); err != nil {
+		return redisError(err, "failed to set key %q in redis", key)
+	}
+	return nil
+}
+
+// FetchBytes retrieves raw bytes stored by PutBytes, skipping the Codec.
+func (r *RedisCache) FetchBytes(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, redisError(err, "failed to get key %q from redis", key)
+	}
+	return b, true, nil
+}
+
+// Remove deletes a value from Redis.
+func (r *RedisCache) Remove(ctx context.Context, key string) error {
+	if r.readOnly {
+		return readOnlyError(r.policy)
+	}
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return redisError(err, "failed to delete key %q from redis", key)
+	}
+	return nil
+}
+
+// FetchMulti retrieves several values from Redis in a single MGET.
+func (r *RedisCache) FetchMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	result := make(map[string]any, len(keys))
+	if len(keys) == 0 {
+		return result, nil
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, redisError(err, "failed to get keys from redis")
+	}
+	for i, raw := range values {
+		data, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		var value any
+		if err := r.codec.Unmarshal([]byte(data), &value); err != nil {
+			return nil, errors.Wrapf(err, "failed to decode key %q", keys[i])
+		}
+		result[keys[i]] = value
+	}
+	return result, nil
+}
+
+// PutMulti stores several values in Redis in a single pipeline.
+func (r *RedisCache) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	if r.readOnly {
+		return readOnlyError(r.policy)
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	pipe := r.client.Pipeline()
+	for key, value := range items {
+		data, err := r.codec.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encode key %q", key)
+		}
+		pipe.Set(ctx, key, data, redisTTL(ttl))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return redisError(err, "failed to set keys in redis")
+	}
+	return nil
+}
+
+// RemoveMulti deletes several values from Redis in a single DEL.
+func (r *RedisCache) RemoveMulti(ctx context.Context, keys []string) error {
+	if r.readOnly {
+		return readOnlyError(r.policy)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return redisError(err, "failed to delete keys from redis")
+	}
+	return nil
+}
+
+// Close closes the connection pool.
+func (r *RedisCache) Close() error {
+	return r.client.Close()
+}
+
+// redisError describes a failed Redis call, reporting a closed client as ErrClosed.
+func redisError(err error, format string, args ...any) error {
+	if errors.Is(err, redis.ErrClosed) {
+		err = ErrClosed
+	}
+	return errors.Wrapf(err, format, args...)
+}
+
+// redisTTL maps a cache TTL onto Redis semantics, where zero means no expiration.
+func redisTTL(ttl time.Duration) time.Duration {
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestRedis(t *testing.T) *RedisCache {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR is not set")
+	}
+	cache := NewRedis(RedisConfig{Addr: addr, Password: os.Getenv("REDIS_PASSWORD")})
+	t.Cleanup(func() { cache.Close() })
+	return cache
+}
+
+func TestRedisCacheOperations(t *testing.T) {
+	cache := newTestRedis(t)
+	ctx := context.Background()
+
+	if err := cache.Put(ctx, "memos-test:key1", "value1", time.Minute); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	val, ok, err := cache.Fetch(ctx, "memos-test:key1")
+	if err != nil || !ok || val != "value1" {
+		t.Errorf("Expected 'value1', got %v, exists: %v, err: %v", val, ok, err)
+	}
+
+	type memo struct {
+		ID      int32
+		Content string
+	}
+	if err := cache.Put(ctx, "memos-test:memo", memo{ID: 1, Content: "hello"}, time.Minute); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	var got memo
+	if ok, err := cache.FetchInto(ctx, "memos-test:memo", &got); err != nil || !ok || got.ID != 1 {
+		t.Errorf("Unexpected FetchInto result %+v, exists: %v, err: %v", got, ok, err)
+	}
+
+	raw := []byte(`{"id":1}`)
+	if err := cache.PutBytes(ctx, "memos-test:raw", raw, time.Minute); err != nil {
+		t.Fatalf("PutBytes failed: %v", err)
+	}
+	if b, ok, err := cache.FetchBytes(ctx, "memos-test:raw"); err != nil || !ok || string(b) != string(raw) {
+		t.Errorf("Expected the raw bytes back, got %q, exists: %v, err: %v", b, ok, err)
+	}
+
+	if err := cache.PutMulti(ctx, map[string]any{"memos-test:a": "a", "memos-test:b": "b"}, time.Minute); err != nil {
+		t.Fatalf("PutMulti failed: %v", err)
+	}
+	values, err := cache.FetchMulti(ctx, []string{"memos-test:a", "memos-test:b", "memos-test:missing"})
+	if err != nil || len(values) != 2 {
+		t.Errorf("Unexpected FetchMulti result %v, err: %v", values, err)
+	}
+
+	if err := cache.RemoveMulti(ctx, []string{"memos-test:key1", "memos-test:memo", "memos-test:raw", "memos-test:a", "memos-test:b"}); err != nil {
+		t.Fatalf("RemoveMulti failed: %v", err)
+	}
+	if _, ok, err := cache.Fetch(ctx, "memos-test:key1"); ok || err != nil {
+		t.Errorf("Expected miss after RemoveMulti, exists: %v, err: %v", ok, err)
+	}
+}
+
+func TestRedisCacheSurfacesConnectionErrors(t *testing.T) {
+	cache := NewRedis(RedisConfig{Addr: "127.0.0.1:1"})
+	defer cache.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, ok, err := cache.Fetch(ctx, "key"); err == nil || ok {
+		t.Errorf("Expected connection error, got exists: %v, err: %v", ok, err)
+	}
+	if err := cache.Put(ctx, "key", "value", time.Minute); err == nil {
+		t.Errorf("Expected connection error from Put")
+	}
+}
+
+func TestRedisBus(t *testing.T) {
+	cache := newTestRedis(t)
+	ctx := context.Background()
+	bus := cache.InvalidationBus("memos-test:invalidations")
+
+	received := make(chan Invalidation, 1)
+	unsubscribe, err := bus.Subscribe(ctx, func(msg Invalidation) { received <- msg })
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+
+	if err := bus.Publish(ctx, Invalidation{Keys: []string{"memo:1"}, Node: "a"}); err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	select {
+	case msg := <-received:
+		if len(msg.Keys) != 1 || msg.Keys[0] != "memo:1" || msg.Node != "a" {
+			t.Errorf("Unexpected invalidation %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Timed out waiting for the invalidation")
+	}
+}
@@ -0,0 +1,110 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// RefreshAhead loads key with loader, caches it for ttl, and then keeps reloading it
+// in the background once threshold of its TTL has elapsed, so that readers of a hot
+// key never see a miss. Readers keep getting the old value until the new one is
+// stored. A failed reload is logged and retried, and the old value is served until
+// it expires. Threshold is a fraction of ttl in (0, 1], for example 0.8.
+// With WithTTLJitter, each reload is scheduled up to that fraction earlier,
+// and with WithMaxTTL, ttl is capped first.
+//
+// The returned stop function ends the background reloads; Close ends them too.
+// Deleting the key does not: the next reload stores it again.
+// If the initial load fails, its error is returned and nothing is registered.
+// A read-only cache registers nothing either, and returns a stop function that does
+// nothing, or ErrReadOnly under ReadOnlyReject; see ReadOnlyPolicy.
+func (c *Cache) RefreshAhead(ctx context.Context, key string, ttl time.Duration, threshold float64, loader func(context.Context) (any, error)) (stop func(), err error) {
+	if ttl <= 0 {
+		return nil, errors.Errorf("refresh-ahead TTL must be positive, got %v", ttl)
+	}
+	if threshold <= 0 || threshold > 1 {
+		return nil, errors.Errorf("refresh-ahead threshold must be in (0, 1], got %v", threshold)
+	}
+	if err := c.validateKey(key); err != nil {
+		return nil, err
+	}
+	if skip, err := c.checkWritable(); skip {
+		if err != nil {
+			return nil, err
+		}
+		return func() {}, nil
+	}
+	ttl = c.capTTL(ttl)
+
+	value, err := c.callLoader(ctx, loader)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.setRefreshed(ctx, key, value, ttl); err != nil {
+		return nil, err
+	}
+
+	c.loadMu.Lock()
+	if err := c.refreshCtx.Err(); err != nil {
+		c.loadMu.Unlock()
+		return nil, errors.Wrap(err, "cache is closed")
+	}
+	refreshCtx, cancel := context.WithCancel(c.refreshCtx)
+	c.refreshWG.Add(1)
+	c.loadMu.Unlock()
+
+	go func() {
+		defer c.refreshWG.Done()
+		c.refreshLoop(refreshCtx, key, ttl, threshold, loader)
+	}()
+	return cancel, nil
+}
+
+// refreshLoop reloads key shortly before each expiry until ctx is canceled.
+func (c *Cache) refreshLoop(ctx context.Context, key string, ttl time.Duration, threshold float64, loader func(context.Context) (any, error)) {
+	refreshAfter := time.Duration(float64(ttl) * threshold)
+	// Retry failed reloads often enough to get a few attempts in before the old value expires.
+	retryAfter := max((ttl-refreshAfter)/4, time.Millisecond)
+
+	timer := time.NewTimer(c.jitterEarlier(refreshAfter))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		value, err := c.callLoader(ctx, loader)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.logger.Warn("failed to refresh cache entry", "key", key, "err", err)
+			timer.Reset(retryAfter)
+			continue
+		}
+		if err := c.setRefreshed(ctx, key, value, ttl); err != nil {
+			return
+		}
+		timer.Reset(c.jitterEarlier(refreshAfter))
+	}
+}
+
+// setRefreshed stores a loaded value with exactly ttl, without jitter, so that
+// the next scheduled reload always lands before it expires.
+func (c *Cache) setRefreshed(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	c.insert(c.newItem(key, value, expirationFor(c.now(), ttl)))
+	return nil
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheRefreshAhead(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+
+	var loads int64
+	loader := func(context.Context) (any, error) {
+		return atomic.AddInt64(&loads, 1), nil
+	}
+	if _, err := cache.RefreshAhead(ctx, "settings", 100*time.Millisecond, 0.5, loader); err != nil {
+		t.Fatalf("RefreshAhead failed: %v", err)
+	}
+
+	// Read well past several TTLs; every read must hit, and the value must move on.
+	deadline := time.Now().Add(350 * time.Millisecond)
+	var last int64
+	for time.Now().Before(deadline) {
+		val, ok := cache.Get(ctx, "settings")
+		if !ok {
+			t.Fatalf("Expected refresh-ahead to avoid misses")
+		}
+		last = val.(int64)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if last < 3 {
+		t.Errorf("Expected the value to be refreshed several times, got %d", last)
+	}
+	if misses := cache.Stats().Misses; misses != 0 {
+		t.Errorf("Expected no misses, got %d", misses)
+	}
+
+	// Close stops the background reloads.
+	cache.Close()
+	stopped := atomic.LoadInt64(&loads)
+	time.Sleep(150 * time.Millisecond)
+	if n := atomic.LoadInt64(&loads); n != stopped {
+		t.Errorf("Expected no reloads after Close, got %d more", n-stopped)
+	}
+}
+
+func TestCacheRefreshAheadStop(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	var loads int64
+	stop, err := cache.RefreshAhead(ctx, "settings", 50*time.Millisecond, 0.5, func(context.Context) (any, error) {
+		return atomic.AddInt64(&loads, 1), nil
+	})
+	if err != nil {
+		t.Fatalf("RefreshAhead failed: %v", err)
+	}
+	stop()
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt64(&loads); n != 1 {
+		t.Errorf("Expected only the initial load after stop, got %d loads", n)
+	}
+	if _, ok := cache.Get(ctx, "settings"); ok {
+		t.Errorf("Expected the value to expire once refreshing stopped")
+	}
+}
+
+func TestCacheRefreshAheadInitialError(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	loadErr := errors.New("database unavailable")
+	if _, err := cache.RefreshAhead(ctx, "settings", time.Minute, 0.8, func(context.Context) (any, error) {
+		return nil, loadErr
+	}); !errors.Is(err, loadErr) {
+		t.Errorf("Expected the loader error, got %v", err)
+	}
+	if _, err := cache.RefreshAhead(ctx, "settings", time.Minute, 1.5, nil); err == nil {
+		t.Errorf("Expected an error for an out of range threshold")
+	}
+}
@@ -0,0 +1,308 @@
+package cache
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultVirtualNodes is the number of ring points per node when RingConfig leaves it unset.
+const defaultVirtualNodes = 160
+
+// RingConfig contains options for configuring a RingCache.
+type RingConfig struct {
+	// VirtualNodes is the number of points each node places on the ring. More points
+	// spread keys more evenly at the cost of a larger ring. Zero means 160.
+	VirtualNodes int
+
+	// Hasher hashes keys and ring points. Nil means a 64-bit FNV-1a hash with extra
+	// mixing so that similar point names still land far apart.
+	Hasher func(key string) uint64
+}
+
+// ringPoint is a position on the hash ring owned by a node.
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// RingCache is a Backend that spreads keys over several backends, such as one
+// RedisCache per Redis node, with a consistent-hash ring. Each node owns many
+// virtual points on the ring and a key belongs to the first point at or after its
+// hash, so adding or removing a node only remaps the keys of that node's points.
+// Batch operations are split per node and sent to the nodes concurrently.
+type RingCache struct {
+	config RingConfig
+
+	mu     sync.RWMutex
+	nodes  map[string]Backend
+	points []ringPoint
+}
+
+var _ Backend = (*RingCache)(nil)
+
+// NewRing creates a ring over the given backends, keyed by node name.
+func NewRing(nodes map[string]Backend, config RingConfig) *RingCache {
+	if config.VirtualNodes <= 0 {
+		config.VirtualNodes = defaultVirtualNodes
+	}
+	if config.Hasher == nil {
+		config.Hasher = ringHash
+	}
+	r := &RingCache{
+		config: config,
+		nodes:  make(map[string]Backend, len(nodes)),
+	}
+	for name, backend := range nodes {
+		r.nodes[name] = backend
+	}
+	r.rebuild()
+	return r
+}
+
+// ringHash is fnv64a followed by a 64-bit finalizer, because FNV alone clusters
+// ring points whose names differ only in their last characters.
+func ringHash(key string) uint64 {
+	h := fnv64a(key)
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// AddNode adds a backend to the ring, replacing any node with the same name.
+// Only the keys that land on the new node's points move to it.
+func (r *RingCache) AddNode(name string, backend Backend) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nodes[name] = backend
+	r.rebuild()
+}
+
+// RemoveNode removes a node from the ring and returns its backend, which the
+// caller is responsible for closing. Its keys move to the next nodes on the ring.
+func (r *RingCache) RemoveNode(name string) (Backend, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	backend, ok := r.nodes[name]
+	if !ok {
+		return nil, false
+	}
+	delete(r.nodes, name)
+	r.rebuild()
+	return backend, true
+}
+
+// Nodes returns the names of the nodes in the ring in ascending order.
+func (r *RingCache) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Sorted(maps.Keys(r.nodes))
+}
+
+// NodeFor returns the name of the node that owns key, or false if the ring is empty.
+func (r *RingCache) NodeFor(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.nodeForLocked(key)
+}
+
+// rebuild recomputes the ring points from the nodes. The caller must hold r.mu for writing.
+func (r *RingCache) rebuild() {
+	points := make([]ringPoint, 0, len(r.nodes)*r.config.VirtualNodes)
+	for name := range r.nodes {
+		for i := 0; i < r.config.VirtualNodes; i++ {
+			points = append(points, ringPoint{hash: r.config.Hasher(name + "#" + strconv.Itoa(i)), node: name})
+		}
+	}
+	// Break hash ties by name so the ring does not depend on map iteration order.
+	slices.SortFunc(points, func(a, b ringPoint) int {
+		if c := cmp.Compare(a.hash, b.hash); c != 0 {
+			return c
+		}
+		return strings.Compare(a.node, b.node)
+	})
+	r.points = points
+}
+
+// nodeForLocked returns the node owning key. The caller must hold r.mu.
+func (r *RingCache) nodeForLocked(key string) (string, bool) {
+	if len(r.points) == 0 {
+		return "", false
+	}
+	h := r.config.Hasher(key)
+	i, _ := slices.BinarySearchFunc(r.points, h, func(p ringPoint, h uint64) int {
+		return cmp.Compare(p.hash, h)
+	})
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.points[i].node, true
+}
+
+// backendFor returns the backend owning key.
+func (r *RingCache) backendFor(key string) (Backend, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.nodeForLocked(key)
+	if !ok {
+		return nil, ErrRingEmpty
+	}
+	return r.nodes[name], nil
+}
+
+// groupByNode buckets keys by the backend that owns them.
+func (r *RingCache) groupByNode(keys []string) (map[string][]string, map[string]Backend, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 {
+		return nil, nil, ErrRingEmpty
+	}
+	groups := make(map[string][]string)
+	backends := make(map[string]Backend)
+	for _, key := range keys {
+		name, _ := r.nodeForLocked(key)
+		groups[name] = append(groups[name], key)
+		backends[name] = r.nodes[name]
+	}
+	return groups, backends, nil
+}
+
+// fanOut calls fn concurrently once per node in groups and returns the first error,
+// annotated with the name of the node that failed.
+func fanOut(groups map[string][]string, backends map[string]Backend, fn func(backend Backend, keys []string) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for name, keys := range groups {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := fn(backends[name], keys); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = errors.Wrapf(err, "ring node %q", name)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// Fetch retrieves a value from the node that owns key.
+func (r *RingCache) Fetch(ctx context.Context, key string) (any, bool, error) {
+	backend, err := r.backendFor(key)
+	if err != nil {
+		return nil, false, err
+	}
+	return backend.Fetch(ctx, key)
+}
+
+// Put stores a value on the node that owns key.
+func (r *RingCache) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	backend, err := r.backendFor(key)
+	if err != nil {
+		return err
+	}
+	return backend.Put(ctx, key, value, ttl)
+}
+
+// Remove deletes a value from the node that owns key.
+func (r *RingCache) Remove(ctx context.Context, key string) error {
+	backend, err := r.backendFor(key)
+	if err != nil {
+		return err
+	}
+	return backend.Remove(ctx, key)
+}
+
+// FetchMulti retrieves several values, with one FetchMulti call per node involved.
+func (r *RingCache) FetchMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	if len(keys) == 0 {
+		return map[string]any{}, nil
+	}
+	groups, backends, err := r.groupByNode(keys)
+	if err != nil {
+		return nil, err
+	}
+	var mu sync.Mutex
+	result := make(map[string]any, len(keys))
+	err = fanOut(groups, backends, func(backend Backend, keys []string) error {
+		values, err := backend.FetchMulti(ctx, keys)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		for key, value := range values {
+			result[key] = value
+		}
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PutMulti stores several values, with one PutMulti call per node involved.
+func (r *RingCache) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	if len(items) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	groups, backends, err := r.groupByNode(keys)
+	if err != nil {
+		return err
+	}
+	return fanOut(groups, backends, func(backend Backend, keys []string) error {
+		batch := make(map[string]any, len(keys))
+		for _, key := range keys {
+			batch[key] = items[key]
+		}
+		return backend.PutMulti(ctx, batch, ttl)
+	})
+}
+
+// RemoveMulti deletes several values, with one RemoveMulti call per node involved.
+func (r *RingCache) RemoveMulti(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	groups, backends, err := r.groupByNode(keys)
+	if err != nil {
+		return err
+	}
+	return fanOut(groups, backends, func(backend Backend, keys []string) error {
+		return backend.RemoveMulti(ctx, keys)
+	})
+}
+
+// Close closes every node in the ring and returns the first error.
+func (r *RingCache) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var firstErr error
+	for _, name := range slices.Sorted(maps.Keys(r.nodes)) {
+		if err := r.nodes[name].Close(); err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "ring node %q", name)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func newTestRing(t *testing.T, n int) (*RingCache, map[string]*Cache) {
+	t.Helper()
+	nodes := make(map[string]Backend, n)
+	caches := make(map[string]*Cache, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("redis-%d", i)
+		c := NewDefault()
+		t.Cleanup(func() { c.Close() })
+		nodes[name] = c
+		caches[name] = c
+	}
+	return NewRing(nodes, RingConfig{}), caches
+}
+
+func TestRingCacheDistribution(t *testing.T) {
+	const keys = 20000
+	ring, _ := newTestRing(t, 4)
+
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		node, ok := ring.NodeFor(fmt.Sprintf("memo:%d", i))
+		if !ok {
+			t.Fatalf("Expected every key to map to a node")
+		}
+		counts[node]++
+	}
+	if len(counts) != 4 {
+		t.Fatalf("Expected keys on all 4 nodes, got %v", counts)
+	}
+	// With 160 virtual nodes each node should stay well within ±25% of its fair share.
+	fair := keys / 4
+	for node, n := range counts {
+		if n < fair*3/4 || n > fair*5/4 {
+			t.Errorf("Expected node %s to own about %d keys, got %d", node, fair, n)
+		}
+	}
+}
+
+func TestRingCacheRemoveNodeRemapsFewKeys(t *testing.T) {
+	const keys = 20000
+	ring, _ := newTestRing(t, 5)
+
+	before := make(map[string]string, keys)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("memo:%d", i)
+		before[key], _ = ring.NodeFor(key)
+	}
+
+	if _, ok := ring.RemoveNode("redis-2"); !ok {
+		t.Fatalf("Expected redis-2 to be removed")
+	}
+	moved := 0
+	for key, node := range before {
+		after, _ := ring.NodeFor(key)
+		if node != "redis-2" && after != node {
+			t.Fatalf("Expected %s to stay on %s, moved to %s", key, node, after)
+		}
+		if after != node {
+			moved++
+		}
+	}
+	// Only the keys of the removed node move, about a fifth of them.
+	if moved > keys*3/10 {
+		t.Errorf("Expected about %d keys to move, got %d", keys/5, moved)
+	}
+
+	readded := NewDefault()
+	defer readded.Close()
+	ring.AddNode("redis-2", readded)
+	for key, node := range before {
+		if after, _ := ring.NodeFor(key); after != node {
+			t.Fatalf("Expected adding redis-2 back to restore %s on %s, got %s", key, node, after)
+		}
+	}
+}
+
+func TestRingCacheBatch(t *testing.T) {
+	ctx := context.Background()
+	ring, caches := newTestRing(t, 3)
+
+	items := make(map[string]any)
+	keys := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("memo:%d", i)
+		items[key] = i
+		keys = append(keys, key)
+	}
+	if err := ring.PutMulti(ctx, items, 0); err != nil {
+		t.Fatalf("PutMulti failed: %v", err)
+	}
+	for key := range items {
+		node, _ := ring.NodeFor(key)
+		if _, ok := caches[node].Get(ctx, key); !ok {
+			t.Errorf("Expected %s to be stored on %s", key, node)
+		}
+	}
+
+	values, err := ring.FetchMulti(ctx, append(keys, "memo:missing"))
+	if err != nil {
+		t.Fatalf("FetchMulti failed: %v", err)
+	}
+	if len(values) != 100 || values["memo:42"] != 42 {
+		t.Errorf("Expected the 100 stored values, got %d", len(values))
+	}
+
+	if err := ring.RemoveMulti(ctx, keys[:50]); err != nil {
+		t.Fatalf("RemoveMulti failed: %v", err)
+	}
+	if values, _ := ring.FetchMulti(ctx, keys); len(values) != 50 {
+		t.Errorf("Expected 50 values left, got %d", len(values))
+	}
+
+	empty := NewRing(nil, RingConfig{})
+	if _, _, err := empty.Fetch(ctx, "memo:1"); err == nil {
+		t.Errorf("Expected an error from a ring without nodes")
+	}
+}
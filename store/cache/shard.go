@@ -0,0 +1,360 @@
+package cache
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// minItemsPerShard keeps each shard large enough for its LRU order to be meaningful.
+const minItemsPerShard = 64
+
+// shard is an independently locked partition of the cache.
+type shard struct {
+	mu    sync.Mutex
+	items map[string]*item
+	lru   lruList
+	// tags indexes the keys of this shard by the tags they carry.
+	tags map[string]map[string]struct{}
+	// volatile counts the items that can stop being live without being removed,
+	// that is those with an expiration and negative entries.
+	volatile int
+	// expiry orders the items that expire by deadline; it is nil unless the
+	// SweepExpiryHeap strategy is selected.
+	expiry *expiryHeap
+}
+
+func newShard() *shard {
+	return &shard{
+		items: make(map[string]*item),
+		tags:  make(map[string]map[string]struct{}),
+	}
+}
+
+// reset drops every item in the shard. The caller must hold s.mu.
+func (s *shard) reset() {
+	s.items = make(map[string]*item)
+	s.lru = lruList{}
+	s.tags = make(map[string]map[string]struct{})
+	s.volatile = 0
+	if s.expiry != nil {
+		s.expiry = &expiryHeap{}
+	}
+}
+
+// liveLen returns the number of items Get would currently return.
+// It only scans the shard if some of its items can expire or scan is set, as it must
+// be while items of a retired generation may remain. The caller must hold s.mu.
+func (s *shard) liveLen(now time.Time, scan bool) int {
+	if s.volatile == 0 && !scan {
+		return len(s.items)
+	}
+	n := 0
+	for _, itm := range s.items {
+		if itm.live(now) {
+			n++
+		}
+	}
+	return n
+}
+
+// indexTags records the tags of an item. The caller must hold s.mu.
+func (s *shard) indexTags(itm *item) {
+	for _, tag := range itm.tags {
+		keys, ok := s.tags[tag]
+		if !ok {
+			keys = make(map[string]struct{})
+			s.tags[tag] = keys
+		}
+		keys[itm.key] = struct{}{}
+	}
+}
+
+// unindexTags forgets the tags of an item, dropping tags left without keys.
+// The caller must hold s.mu.
+func (s *shard) unindexTags(itm *item) {
+	for _, tag := range itm.tags {
+		keys := s.tags[tag]
+		delete(keys, itm.key)
+		if len(keys) == 0 {
+			delete(s.tags, tag)
+		}
+	}
+}
+
+// defaultShardCount scales shards with the available parallelism, but never
+// splits an item-bounded cache into shards smaller than minItemsPerShard.
+// It returns a power of two, rounding down to keep the shards large enough.
+func defaultShardCount(config Config) int {
+	n := runtime.GOMAXPROCS(0) * 4
+	if config.MaxItems > 0 {
+		n = min(n, max(config.MaxItems/minItemsPerShard, 1))
+	}
+	return 1 << (bits.Len(uint(n)) - 1)
+}
+
+// nextPowerOfTwo returns the smallest power of two that is at least n, for n >= 1.
+func nextPowerOfTwo(n int) int {
+	return 1 << bits.Len(uint(n-1))
+}
+
+// shardFor returns the shard that owns key.
+func (c *Cache) shardFor(key string) *shard {
+	return c.shards[c.shardIndex(key)]
+}
+
+// shardIndex returns the index of the shard that owns key.
+// A single-shard cache skips hashing, so small caches such as per-request
+// memoization pay no more than a map behind one mutex.
+func (c *Cache) shardIndex(key string) int {
+	if len(c.shards) == 1 {
+		return 0
+	}
+	if c.config.Hasher != nil {
+		return int(c.config.Hasher(key) & c.shardMask)
+	}
+	return int(fnv64a(key) & c.shardMask)
+}
+
+// fnv64a is an allocation-free FNV-1a hash of key.
+func fnv64a(key string) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= prime64
+	}
+	return h
+}
+
+// groupByShard buckets keys by the index of the shard that owns them.
+func (c *Cache) groupByShard(keys []string) [][]string {
+	groups := make([][]string, len(c.shards))
+	for _, key := range keys {
+		i := c.shardIndex(key)
+		groups[i] = append(groups[i], key)
+	}
+	return groups
+}
+
+// getLocked looks up a live value, lazily evicting it if it has expired.
+// Negative entries are reported as a miss. Expired items are appended to evicted.
+// The caller must hold s.mu.
+func (c *Cache) getLocked(s *shard, key string, now time.Time, evicted []evictedItem) (any, bool, []evictedItem) {
+	value, state, evicted := c.lookupLocked(s, key, now, evicted)
+	if state == NegativeHit {
+		atomic.AddInt64(&c.counters.Load().misses, 1)
+	}
+	return value, state == Hit, evicted
+}
+
+// lookupLocked is like getLocked but tells negative entries apart from misses.
+// It leaves counting a NegativeHit to the caller.
+// The caller must hold s.mu.
+func (c *Cache) lookupLocked(s *shard, key string, now time.Time, evicted []evictedItem) (any, EntryState, []evictedItem) {
+	if admission := c.config.Admission; admission != nil {
+		admission.Record(key)
+	}
+	itm, ok := s.items[key]
+	if !ok || atomic.LoadInt32(&c.disabled) != 0 {
+		atomic.AddInt64(&c.counters.Load().misses, 1)
+		c.publish(CacheEvent{Type: EventGetMiss, Key: key})
+		return nil, Miss, evicted
+	}
+	if itm.expired(now) {
+		atomic.AddInt64(&c.counters.Load().misses, 1)
+		c.publish(CacheEvent{Type: EventGetMiss, Key: key})
+		if !itm.dead(now) {
+			// Still within its grace window; only GetStale may serve it.
+			return nil, Miss, evicted
+		}
+		atomic.AddInt64(&c.counters.Load().evictions, 1)
+		return nil, Miss, c.dropLocked(s, itm, EvictReasonExpired, evicted)
+	}
+	s.lru.moveToFront(itm)
+	if itm.err != nil {
+		// A cached loader error is not a known miss; only GetOrSetWithErrorTTL serves it.
+		atomic.AddInt64(&c.counters.Load().misses, 1)
+		c.publish(CacheEvent{Type: EventGetMiss, Key: key})
+		return nil, Miss, evicted
+	}
+	if itm.negative {
+		c.publish(CacheEvent{Type: EventGetMiss, Key: key})
+		return nil, NegativeHit, evicted
+	}
+	itm.recordAccess(now)
+	c.slideLocked(s, itm, now)
+	atomic.AddInt64(&c.counters.Load().hits, 1)
+	c.publish(CacheEvent{Type: EventGetHit, Key: key})
+	return itm.value, Hit, evicted
+}
+
+// setLocked stores a new item, replacing any existing item with the same key, and,
+// if the cache is over capacity, evicts the least recently used items of the same shard.
+// A new key that would cause an eviction is dropped instead if the admission policy
+// rejects it, and every item is dropped while the cache is disabled, if it is larger
+// than MaxValueBytes or if its tags exceed the tag limits.
+// Evicted items are appended to evicted.
+// The caller must hold s.mu and should call evictOverflow after releasing it.
+func (c *Cache) setLocked(s *shard, itm *item, evicted []evictedItem) []evictedItem {
+	evicted, _ = c.trySetLocked(s, itm, evicted)
+	return evicted
+}
+
+// trySetLocked is setLocked, but returns an error wrapping ErrTagLimit if the item
+// was dropped for exceeding the tag limits, leaving any existing item in place.
+func (c *Cache) trySetLocked(s *shard, itm *item, evicted []evictedItem) ([]evictedItem, error) {
+	if atomic.LoadInt32(&c.disabled) != 0 || c.checkValueSize(itm) != nil {
+		return evicted, nil
+	}
+	old, exists := s.items[itm.key]
+	itm.gen = c.generation.Load()
+	if itm.createdAt.IsZero() {
+		itm.createdAt = c.now()
+	}
+	if itm.ttl == 0 && !itm.expiration.IsZero() {
+		itm.ttl = itm.expiration.Sub(itm.createdAt)
+	}
+	if admission := c.config.Admission; admission != nil {
+		admission.Record(itm.key)
+		if !exists && c.wouldOverflow(itm) {
+			if victim := s.lru.back(); victim != nil && !admission.Admit(itm.key, victim.key) {
+				return evicted, nil
+			}
+		}
+	}
+	if err := c.trackTags(old, itm); err != nil {
+		return evicted, err
+	}
+	if exists {
+		// Replacing keeps the counters balanced and is not an eviction.
+		c.unlinkLocked(s, old)
+		recycleItem(old)
+	}
+	s.items[itm.key] = itm
+	s.lru.pushFront(itm)
+	s.indexTags(itm)
+	s.trackExpiry(itm)
+	c.trackTenant(itm)
+	if itm.volatile() {
+		s.volatile++
+	}
+	c.publish(CacheEvent{Type: EventSet, Key: itm.key})
+	c.logSet(itm)
+	atomic.AddInt64(&c.itemCount, 1)
+	atomic.AddInt64(&c.bytes, itm.size)
+
+	// If we're over the item or byte limit, evict the least recently used ones,
+	// never the item that was just written.
+	for c.overCapacity() && s.lru.back() != itm && c.underEvictionBatch(evicted) {
+		evicted = c.evictLocked(s, evicted)
+	}
+	return evicted, nil
+}
+
+// underEvictionBatch reports whether an operation that has removed the items in
+// evicted may evict more under EvictionBatch.
+func (c *Cache) underEvictionBatch(evicted []evictedItem) bool {
+	return c.config.EvictionBatch <= 0 || len(evicted) < c.config.EvictionBatch
+}
+
+// catchUpEvictions evicts in batches of EvictionBatch until the cache is back
+// within its limits. The janitor runs it to finish what writes left over.
+func (c *Cache) catchUpEvictions() {
+	for c.overCapacity() {
+		evicted := c.evictOverflow(nil, nil)
+		if len(evicted) == 0 {
+			return
+		}
+		c.notifyEvicted(evicted)
+	}
+}
+
+// evictOverflow evicts least recently used items from shards other than from,
+// one per shard in turn, until the cache is back within its limits or the operation
+// has evicted EvictionBatch items.
+// It takes each shard lock on its own, so it must be called without holding any.
+func (c *Cache) evictOverflow(from *shard, evicted []evictedItem) []evictedItem {
+	start := int(atomic.AddUint32(&c.overflowCursor, 1))
+	for c.overCapacity() && c.underEvictionBatch(evicted) {
+		progress := false
+		for i := range c.shards {
+			s := c.shards[(start+i)%len(c.shards)]
+			if s == from {
+				continue
+			}
+			s.mu.Lock()
+			if c.overCapacity() && s.lru.len > 0 && c.underEvictionBatch(evicted) {
+				evicted = c.evictLocked(s, evicted)
+				progress = true
+			}
+			s.mu.Unlock()
+		}
+		if !progress {
+			break
+		}
+	}
+	return evicted
+}
+
+// evictLocked removes the least recently used item of a shard for capacity, or the
+// one picked by fair eviction; see victimLocked.
+// The caller must hold s.mu and ensure the shard is not empty.
+func (c *Cache) evictLocked(s *shard, evicted []evictedItem) []evictedItem {
+	atomic.AddInt64(&c.counters.Load().evictions, 1)
+	return c.dropLocked(s, c.victimLocked(s), EvictReasonCapacity, evicted)
+}
+
+// wouldOverflow reports whether adding itm would take the cache over its item or byte limit.
+func (c *Cache) wouldOverflow(itm *item) bool {
+	if c.config.MaxItems > 0 && atomic.LoadInt64(&c.itemCount)+1 > int64(c.config.MaxItems) {
+		return true
+	}
+	return c.config.MaxBytes > 0 && atomic.LoadInt64(&c.bytes)+itm.size > c.config.MaxBytes
+}
+
+// overCapacity reports whether the cache exceeds its item or byte limit.
+func (c *Cache) overCapacity() bool {
+	if c.config.MaxItems > 0 && atomic.LoadInt64(&c.itemCount) > int64(c.config.MaxItems) {
+		return true
+	}
+	return c.config.MaxBytes > 0 && atomic.LoadInt64(&c.bytes) > c.config.MaxBytes
+}
+
+// deleteLocked removes a key if present. The removed item is appended to evicted.
+// The caller must hold s.mu.
+func (c *Cache) deleteLocked(s *shard, key string, evicted []evictedItem) []evictedItem {
+	itm, ok := s.items[key]
+	if !ok {
+		return evicted
+	}
+	return c.dropLocked(s, itm, EvictReasonDeleted, evicted)
+}
+
+// removeLocked unlinks an item from the shard map, LRU list and tag index,
+// and records the removal in the append-only log. The caller must hold s.mu.
+func (c *Cache) removeLocked(s *shard, itm *item) {
+	c.unlinkLocked(s, itm)
+	c.untrackTags(itm)
+	c.logDelete(itm.key)
+}
+
+// unlinkLocked is removeLocked for an item about to be replaced, which is not logged.
+// The caller must hold s.mu.
+func (c *Cache) unlinkLocked(s *shard, itm *item) {
+	delete(s.items, itm.key)
+	s.lru.remove(itm)
+	s.unindexTags(itm)
+	s.untrackExpiry(itm)
+	c.untrackTenant(itm)
+	if itm.volatile() {
+		s.volatile--
+	}
+	atomic.AddInt64(&c.itemCount, -1)
+	atomic.AddInt64(&c.bytes, -itm.size)
+}
@@ -0,0 +1,222 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShardedCacheCapacity(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(100, WithShards(8))
+	defer cache.Close()
+
+	if len(cache.shards) != 8 {
+		t.Fatalf("Expected 8 shards, got %d", len(cache.shards))
+	}
+	for i := 0; i < 1000; i++ {
+		cache.Set(ctx, fmt.Sprintf("key%d", i), i)
+		if cache.Size() > 100 {
+			t.Fatalf("Cache size %d exceeds limit after %d sets", cache.Size(), i+1)
+		}
+	}
+
+	// The most recent write always survives, whichever shard it lands in.
+	if _, ok := cache.Get(ctx, "key999"); !ok {
+		t.Errorf("Most recently written key should be present")
+	}
+
+	var stored int
+	for _, s := range cache.shards {
+		stored += len(s.items)
+	}
+	if int64(stored) != cache.Size() {
+		t.Errorf("Item count %d does not match stored items %d", cache.Size(), stored)
+	}
+}
+
+func TestDefaultShardCount(t *testing.T) {
+	if n := defaultShardCount(Config{MaxItems: 5}); n != 1 {
+		t.Errorf("Expected a single shard for a tiny cache, got %d", n)
+	}
+	if n := defaultShardCount(Config{}); n < 1 || n&(n-1) != 0 {
+		t.Errorf("Expected a power of two shards, got %d", n)
+	}
+	if n := defaultShardCount(Config{MaxItems: 3 * minItemsPerShard}); n != 2 {
+		t.Errorf("Expected the default to round down to 2 shards, got %d", n)
+	}
+}
+
+func TestShardCountRounding(t *testing.T) {
+	for _, tt := range []struct{ shards, want int }{
+		{1, 1}, {2, 2}, {3, 4}, {5, 8}, {8, 8}, {9, 16}, {100, 128},
+	} {
+		cache := NewDefault(WithShards(tt.shards))
+		if len(cache.shards) != tt.want || cache.shardMask != uint64(tt.want-1) {
+			t.Errorf("WithShards(%d): expected %d shards, got %d with mask %#x", tt.shards, tt.want, len(cache.shards), cache.shardMask)
+		}
+		cache.Close()
+	}
+
+	// A non-positive count falls back to the default.
+	for _, shards := range []int{0, -3} {
+		cache := NewDefault(WithShards(shards))
+		if want := defaultShardCount(cache.config); len(cache.shards) != want {
+			t.Errorf("WithShards(%d): expected the default of %d shards, got %d", shards, want, len(cache.shards))
+		}
+		cache.Close()
+	}
+}
+
+func benchmarkParallel(b *testing.B, cache *Cache) {
+	ctx := context.Background()
+	keys := benchmarkKeys(4096)
+	for _, key := range keys {
+		cache.Set(ctx, key, key)
+	}
+
+	var cursor uint64
+	b.SetParallelism(16)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := atomic.AddUint64(&cursor, 1) * 7919
+		for pb.Next() {
+			key := keys[i%uint64(len(keys))]
+			if i%4 == 0 {
+				cache.Set(ctx, key, key)
+			} else {
+				cache.Get(ctx, key)
+			}
+			i++
+		}
+	})
+}
+
+func TestCacheWithHasher(t *testing.T) {
+	ctx := context.Background()
+	// Route each numeric key to the shard of the same number.
+	cache := NewDefault(WithShards(4), WithHasher(func(key string) uint64 {
+		n, _ := strconv.ParseUint(key, 10, 64)
+		return n
+	}))
+	defer cache.Close()
+
+	for i := 0; i < 8; i++ {
+		cache.Set(ctx, strconv.Itoa(i), i)
+	}
+	for i := 0; i < 8; i++ {
+		if _, ok := cache.shards[i%4].items[strconv.Itoa(i)]; !ok {
+			t.Errorf("Expected key '%d' in shard %d", i, i%4)
+		}
+	}
+}
+
+func TestDefaultHasherDistribution(t *testing.T) {
+	cache := NewDefault(WithShards(16))
+	defer cache.Close()
+
+	// Keys sharing a long prefix and suffix, as produced by our naming schemes.
+	const keys = 16000
+	counts := make([]int, len(cache.shards))
+	for i := 0; i < keys; i++ {
+		counts[cache.shardIndex(fmt.Sprintf("memos:workspace:1:memo:%d:render", i))]++
+	}
+	mean := keys / len(counts)
+	for i, count := range counts {
+		if count < mean/2 || count > mean*2 {
+			t.Errorf("Shard %d holds %d keys against a mean of %d: %v", i, count, mean, counts)
+		}
+	}
+}
+
+func TestSingleShardSkipsHashing(t *testing.T) {
+	ctx := context.Background()
+	hashed := 0
+	cache := NewDefault(WithShards(1), WithHasher(func(key string) uint64 {
+		hashed++
+		return fnv64a(key)
+	}))
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", "value")
+	cache.SetMulti(ctx, map[string]any{"memo:2": 2, "memo:3": 3})
+	if val, ok := cache.Get(ctx, "memo:1"); !ok || val != "value" {
+		t.Errorf("Expected the single-shard cache to behave as usual, got %v", val)
+	}
+	if len(cache.GetMulti(ctx, []string{"memo:1", "memo:2", "memo:3"})) != 3 {
+		t.Errorf("Expected GetMulti to find every key")
+	}
+	if hashed != 0 {
+		t.Errorf("Expected a single-shard cache not to hash keys, hashed %d times", hashed)
+	}
+}
+
+// benchmarkTiny exercises a per-request memoization workload: a handful of keys
+// read and written from a single goroutine.
+func benchmarkTiny(b *testing.B, cache *Cache) {
+	ctx := context.Background()
+	keys := []string{"memo:1", "memo:2", "memo:3", "memo:4"}
+	for _, key := range keys {
+		cache.Set(ctx, key, key)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		key := keys[i%len(keys)]
+		if i%8 == 0 {
+			cache.Set(ctx, key, key)
+		} else {
+			cache.Get(ctx, key)
+		}
+	}
+}
+
+func BenchmarkTinySingleShard(b *testing.B) {
+	cache := NewWithCapacity(0, WithShards(1))
+	defer cache.Close()
+	benchmarkTiny(b, cache)
+}
+
+func BenchmarkTinySharded(b *testing.B) {
+	cache := NewWithCapacity(0)
+	defer cache.Close()
+	benchmarkTiny(b, cache)
+}
+
+func BenchmarkContendedSingleShard(b *testing.B) {
+	cache := NewWithCapacity(0, WithShards(1))
+	defer cache.Close()
+	benchmarkParallel(b, cache)
+}
+
+func BenchmarkContendedSharded(b *testing.B) {
+	cache := NewWithCapacity(0)
+	defer cache.Close()
+	benchmarkParallel(b, cache)
+}
+
+// sinkShard keeps the shard index benchmarks from being optimized away.
+var sinkShard int
+
+func BenchmarkShardIndexMask(b *testing.B) {
+	cache := NewDefault(WithShards(64))
+	defer cache.Close()
+	keys := benchmarkKeys(4096)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sinkShard = cache.shardIndex(keys[i%len(keys)])
+	}
+}
+
+// BenchmarkShardIndexModulo is the modulo selection the mask replaced, for comparison.
+func BenchmarkShardIndexModulo(b *testing.B) {
+	cache := NewDefault(WithShards(64))
+	defer cache.Close()
+	keys := benchmarkKeys(4096)
+	n := uint64(len(cache.shards))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sinkShard = int(fnv64a(keys[i%len(keys)]) % n)
+	}
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// snapshotEntry is the serialized form of one item in a snapshot.
+// Times are absolute, so an entry keeps exactly its remaining TTL across a restart.
+type snapshotEntry struct {
+	Key        string    `json:"key"`
+	Value      []byte    `json:"value"`
+	Expiration time.Time `json:"expiration,omitempty"`
+	StaleUntil time.Time `json:"staleUntil,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+	Version    uint64    `json:"version,omitempty"`
+}
+
+// codec returns the configured Codec, defaulting to JSONCodec, wrapped in an
+// EncryptedCodec if an encryption key is configured.
+func (c *Cache) codec() (Codec, error) {
+	var codec Codec = JSONCodec{}
+	if c.config.Codec != nil {
+		codec = c.config.Codec
+	}
+	if c.config.EncryptionKey == nil {
+		return codec, nil
+	}
+	return NewEncryptedCodec(codec, c.config.EncryptionKey)
+}
+
+// SaveSnapshot writes every live entry to w, so that a restarted process can warm
+// its cache with LoadSnapshot. Values are serialized with the configured Codec;
+// values it cannot encode are logged and left out rather than failing the snapshot.
+// Expired and negative entries are not saved. Entries are written in no particular
+// order; see SaveSortedSnapshot.
+func (c *Cache) SaveSnapshot(w io.Writer) error {
+	return c.saveSnapshot(w, false)
+}
+
+// SaveSortedSnapshot is like SaveSnapshot but writes the entries sorted by key, so
+// that snapshots of the same contents are byte-identical, for golden files and diffs.
+func (c *Cache) SaveSortedSnapshot(w io.Writer) error {
+	return c.saveSnapshot(w, true)
+}
+
+func (c *Cache) saveSnapshot(w io.Writer, sorted bool) error {
+	now := c.now()
+
+	var items []item
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for _, itm := range s.items {
+			if itm.live(now) {
+				items = append(items, item{
+					key:        itm.key,
+					value:      itm.value,
+					expiration: itm.expiration,
+					staleUntil: itm.staleUntil,
+					tags:       itm.tags,
+					version:    itm.version,
+				})
+			}
+		}
+		s.mu.Unlock()
+	}
+
+	if sorted {
+		slices.SortFunc(items, func(a, b item) int { return strings.Compare(a.key, b.key) })
+	}
+
+	codec, err := c.codec()
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	for _, itm := range items {
+		data, err := codec.Marshal(c.decompress(itm.value))
+		if err != nil {
+			c.logger.Warn("failed to encode cache entry for snapshot", "key", itm.key, "err", err)
+			continue
+		}
+		entry := snapshotEntry{
+			Key:        itm.key,
+			Value:      data,
+			Expiration: itm.expiration,
+			StaleUntil: itm.staleUntil,
+			Tags:       itm.tags,
+			Version:    itm.version,
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return errors.Wrap(err, "failed to write cache snapshot")
+		}
+	}
+	return nil
+}
+
+// LoadSnapshot restores entries written by SaveSnapshot, keeping their remaining TTLs.
+// Entries that have expired since the snapshot was taken are skipped, as are values
+// the configured Codec cannot decode. Restored entries replace existing ones with
+// the same key and are subject to the usual capacity limits. With an encryption key,
+// an entry that fails to decrypt stops the load with an error wrapping ErrDecrypt,
+// since it means the snapshot was written with another key or tampered with.
+func (c *Cache) LoadSnapshot(r io.Reader) error {
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	codec, err := c.codec()
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(r)
+	for {
+		var entry snapshotEntry
+		if err := decoder.Decode(&entry); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "failed to read cache snapshot")
+		}
+
+		if err := c.validateKey(entry.Key); err != nil {
+			c.logger.Warn("skipped cache entry with invalid key in snapshot", "key", entry.Key, "err", err)
+			continue
+		}
+		expiration := entry.Expiration
+		if !expiration.IsZero() && c.now().After(expiration) {
+			continue
+		}
+		var value any
+		if err := codec.Unmarshal(entry.Value, &value); err != nil {
+			if errors.Is(err, ErrDecrypt) {
+				return errors.Wrapf(err, "failed to restore key %q from cache snapshot", entry.Key)
+			}
+			c.logger.Warn("failed to decode cache entry from snapshot", "key", entry.Key, "err", err)
+			continue
+		}
+		itm := c.newItem(entry.Key, value, c.capExpiration(expiration))
+		itm.staleUntil = entry.StaleUntil
+		itm.tags = entry.Tags
+		itm.version = entry.Version
+
+		c.insert(itm)
+	}
+}
@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCacheSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "short", "soon", time.Minute)
+	cache.SetWithTTL(ctx, "long", "later", time.Hour)
+	cache.SetWithTTL(ctx, "forever", "always", 0)
+	cache.SetWithTags(ctx, "tagged", "value", "memo:7")
+	cache.SetWithTTL(ctx, "unencodable", make(chan int), time.Hour)
+	cache.SetNotFound(ctx, "missing", time.Hour)
+
+	var buf bytes.Buffer
+	if err := cache.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+
+	// Simulate the restart taking a while.
+	clock.Advance(10 * time.Second)
+	restored := NewDefault(WithClock(clock.Now))
+	defer restored.Close()
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.Size() != 4 {
+		t.Errorf("Expected 4 restored entries, got %d", restored.Size())
+	}
+	for key, want := range map[string]string{"short": "soon", "long": "later", "forever": "always", "tagged": "value"} {
+		if val, ok := restored.Get(ctx, key); !ok || val != want {
+			t.Errorf("Expected '%s' for key '%s', got %v, exists: %v", want, key, val, ok)
+		}
+	}
+	for _, key := range []string{"unencodable", "missing"} {
+		if _, state := restored.Lookup(ctx, key); state != Miss {
+			t.Errorf("Expected key '%s' to be left out of the snapshot, got %v", key, state)
+		}
+	}
+
+	// Remaining TTLs survive the round trip, minus the time spent restarting.
+	for key, ttl := range map[string]time.Duration{"short": time.Minute, "long": time.Hour} {
+		remaining := restored.shardFor(key).items[key].expiration.Sub(clock.Now())
+		if want := ttl - 10*time.Second; remaining != want {
+			t.Errorf("Expected key '%s' to have %v left, got %v", key, want, remaining)
+		}
+	}
+	if !restored.shardFor("forever").items["forever"].expiration.IsZero() {
+		t.Errorf("Expected key 'forever' to keep no expiration")
+	}
+	if removed := restored.InvalidateTag(ctx, "memo:7"); removed != 1 {
+		t.Errorf("Expected tags to survive the round trip, got %d entries invalidated", removed)
+	}
+}
+
+func TestCacheSnapshotSkipsExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "ephemeral", "value", 50*time.Millisecond)
+	cache.SetWithTTL(ctx, "durable", "value", time.Hour)
+
+	var buf bytes.Buffer
+	if err := cache.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	clock.Advance(100 * time.Millisecond)
+
+	restored := NewDefault(WithClock(clock.Now))
+	defer restored.Close()
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if restored.Size() != 1 {
+		t.Errorf("Expected only the durable entry to be restored, got %d entries", restored.Size())
+	}
+	if _, ok := restored.Get(ctx, "durable"); !ok {
+		t.Errorf("Expected key 'durable' to be restored")
+	}
+}
+
+func TestCacheLoadSnapshotMalformed(t *testing.T) {
+	cache := NewDefault()
+	defer cache.Close()
+
+	if err := cache.LoadSnapshot(bytes.NewBufferString("not a snapshot")); err == nil {
+		t.Errorf("Expected an error for a malformed snapshot")
+	}
+}
+
+func TestCacheSortedSnapshotIsReproducible(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	entries := map[string]any{"b": "two", "a": map[string]any{"y": 1, "x": 2}, "c": []string{"three"}}
+
+	snapshot := func(shards int, order []string) []byte {
+		cache := NewDefault(WithClock(clock.Now), WithShards(shards))
+		defer cache.Close()
+		for _, key := range order {
+			cache.SetWithTTL(ctx, key, entries[key], time.Hour)
+		}
+		var buf bytes.Buffer
+		if err := cache.SaveSortedSnapshot(&buf); err != nil {
+			t.Fatalf("SaveSortedSnapshot failed: %v", err)
+		}
+		return buf.Bytes()
+	}
+
+	first := snapshot(1, []string{"a", "b", "c"})
+	second := snapshot(16, []string{"c", "a", "b"})
+	if !bytes.Equal(first, second) {
+		t.Errorf("Expected byte-identical snapshots, got:\n%s\nand:\n%s", first, second)
+	}
+
+	// A restored cache saves the same bytes again.
+	restored := NewDefault(WithClock(clock.Now))
+	defer restored.Close()
+	if err := restored.LoadSnapshot(bytes.NewReader(first)); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	var again bytes.Buffer
+	restored.SaveSortedSnapshot(&again)
+	if !bytes.Equal(first, again.Bytes()) {
+		t.Errorf("Expected the restored cache to save the same snapshot, got:\n%s", again.Bytes())
+	}
+}
+
+func TestCacheSnapshotEncryption(t *testing.T) {
+	ctx := context.Background()
+	key := bytes.Repeat([]byte{7}, 32)
+	cache := NewDefault(WithEncryption(key))
+	defer cache.Close()
+
+	cache.Set(ctx, "session", "token:secret")
+	cache.Set(ctx, "email", "user@example.com")
+	var buf bytes.Buffer
+	if err := cache.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("SaveSnapshot failed: %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret")) || bytes.Contains(buf.Bytes(), []byte("example.com")) {
+		t.Fatalf("Expected the snapshot not to contain plaintext values: %s", buf.String())
+	}
+	snapshot := buf.Bytes()
+
+	restored := NewDefault(WithEncryption(key))
+	defer restored.Close()
+	if err := restored.LoadSnapshot(bytes.NewReader(snapshot)); err != nil {
+		t.Fatalf("LoadSnapshot failed: %v", err)
+	}
+	if val, ok := restored.Get(ctx, "session"); !ok || val != "token:secret" {
+		t.Errorf("Expected the decrypted session, got %v", val)
+	}
+
+	wrongKey := NewDefault(WithEncryption(bytes.Repeat([]byte{8}, 32)))
+	defer wrongKey.Close()
+	if err := wrongKey.LoadSnapshot(bytes.NewReader(snapshot)); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected ErrDecrypt with the wrong key, got %v", err)
+	}
+
+	invalid := NewDefault(WithEncryption([]byte("short")))
+	defer invalid.Close()
+	if err := invalid.SaveSnapshot(&buf); err == nil {
+		t.Errorf("Expected an invalid key to fail the snapshot")
+	}
+}
+
+func TestEncryptedCodecNonce(t *testing.T) {
+	codec, err := NewEncryptedCodec(nil, bytes.Repeat([]byte{1}, 16))
+	if err != nil {
+		t.Fatalf("NewEncryptedCodec failed: %v", err)
+	}
+	a, _ := codec.Marshal("value")
+	b, _ := codec.Marshal("value")
+	if bytes.Equal(a, b) {
+		t.Errorf("Expected a fresh nonce per value")
+	}
+	var value string
+	if err := codec.Unmarshal(b, &value); err != nil || value != "value" {
+		t.Errorf("Expected to decrypt 'value', got %q, err: %v", value, err)
+	}
+	b[len(b)-1] ^= 1
+	if err := codec.Unmarshal(b, &value); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Expected tampered data to fail with ErrDecrypt, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// SetWithGrace adds a value that is fresh for ttl and may then be served stale
+// by GetStale for a further grace period, giving the caller time to refresh it.
+// Get treats the value as a miss once ttl has elapsed.
+// A non-positive ttl stores the value without expiration, and grace is ignored.
+func (c *Cache) SetWithGrace(ctx context.Context, key string, value any, ttl, grace time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	if err := c.validateKey(key); err != nil {
+		return err
+	}
+
+	itm := c.newItem(key, value, c.expiresAt(ttl))
+	if err := c.checkValueSize(itm); err != nil {
+		return err
+	}
+	if ttl > 0 && grace > 0 {
+		itm.staleUntil = itm.expiration.Add(grace)
+	}
+
+	c.insert(itm)
+	return nil
+}
+
+// peekStale returns a value that is past its TTL but still within its grace window,
+// without counting a hit or touching its LRU position.
+func (c *Cache) peekStale(key string) (any, bool) {
+	now := c.now()
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	itm, ok := s.items[key]
+	if !ok || itm.negative || !itm.expired(now) || itm.dead(now) {
+		s.mu.Unlock()
+		return nil, false
+	}
+	value := itm.value
+	s.mu.Unlock()
+	return c.readValue(value), true
+}
+
+// GetStale retrieves a value even if it has expired, as long as it is still within
+// its grace window. fresh reports whether the value is within its TTL; when it is
+// false the caller should refresh the value, for example in the background.
+// Values past their grace window are a miss.
+func (c *Cache) GetStale(ctx context.Context, key string) (value any, fresh bool, ok bool) {
+	if ctx.Err() != nil {
+		return nil, false, false
+	}
+	now := c.now()
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	itm, exists := s.items[key]
+	if !exists || itm.dead(now) || itm.negative {
+		value, ok, evicted := c.getLocked(s, key, now, nil)
+		s.mu.Unlock()
+		c.notifyEvicted(evicted)
+		if !ok {
+			c.notifyMiss(key)
+		}
+		return value, ok, ok
+	}
+	s.lru.moveToFront(itm)
+	itm.recordAccess(now)
+	if !itm.expired(now) {
+		c.slideLocked(s, itm, now)
+	}
+	value, fresh = itm.value, !itm.expired(now)
+	s.mu.Unlock()
+
+	atomic.AddInt64(&c.counters.Load().hits, 1)
+	return c.readValue(value), fresh, true
+}
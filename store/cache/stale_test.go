@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheGetStale(t *testing.T) {
+	ctx := context.Background()
+	config := DefaultConfig()
+	config.CleanupInterval = 0
+	clock := newFakeClock()
+	cache := New(config, WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithGrace(ctx, "render", "<p>memo</p>", 20*time.Millisecond, 40*time.Millisecond)
+
+	// Fresh.
+	if val, fresh, ok := cache.GetStale(ctx, "render"); !ok || !fresh || val != "<p>memo</p>" {
+		t.Errorf("Expected fresh value, got %v, fresh: %v, exists: %v", val, fresh, ok)
+	}
+
+	// Stale but usable: GetStale serves it, Get does not.
+	clock.Advance(30 * time.Millisecond)
+	if val, fresh, ok := cache.GetStale(ctx, "render"); !ok || fresh || val != "<p>memo</p>" {
+		t.Errorf("Expected stale value, got %v, fresh: %v, exists: %v", val, fresh, ok)
+	}
+	if _, ok := cache.Get(ctx, "render"); ok {
+		t.Errorf("Get should treat a stale value as a miss")
+	}
+	cache.cleanup()
+	if _, _, ok := cache.GetStale(ctx, "render"); !ok {
+		t.Errorf("The janitor should keep values within their grace window")
+	}
+
+	// Fully expired.
+	clock.Advance(40 * time.Millisecond)
+	if val, fresh, ok := cache.GetStale(ctx, "render"); ok || fresh || val != nil {
+		t.Errorf("Expected miss past the grace window, got %v, fresh: %v, exists: %v", val, fresh, ok)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected the dead value to be evicted, size is %d", cache.Size())
+	}
+}
+
+func TestCacheGetStaleWithoutGrace(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "key", "value", 10*time.Millisecond)
+	if _, fresh, ok := cache.GetStale(ctx, "key"); !ok || !fresh {
+		t.Errorf("Expected fresh value, fresh: %v, exists: %v", fresh, ok)
+	}
+	clock.Advance(20 * time.Millisecond)
+	if _, _, ok := cache.GetStale(ctx, "key"); ok {
+		t.Errorf("A value without grace should miss once expired")
+	}
+}
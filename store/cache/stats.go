@@ -0,0 +1,29 @@
+package cache
+
+// counters holds the statistics that ResetStats zeroes. They live behind a pointer
+// so that a reset swaps all of them at once: a reader that loads the pointer once
+// sees either every value from before the reset or every value from after it.
+// They are updated with sync/atomic, so every field must be 64-bit to keep them all
+// 8-byte aligned on 32-bit platforms.
+type counters struct {
+	hits      int64
+	misses    int64
+	evictions int64
+	// suppressedLogs counts eviction logs dropped by the EvictionLogRate limit.
+	suppressedLogs int64
+	// loaderCalls, loaderErrors, loaderNanos and loaderLatency back LoaderStats.
+	loaderCalls   int64
+	loaderErrors  int64
+	loaderNanos   int64
+	loaderLatency [len(LoaderLatencyBuckets) + 1]int64
+}
+
+// ResetStats zeroes the hit, miss and eviction counters, the suppressed eviction log
+// count and the loader statistics, for example to compute a hit rate per window.
+// The cached data is left untouched, and so are ItemCount and Bytes, which are live
+// gauges of the cache contents rather than counters. Updates racing with the reset
+// may land on either side of it, but Stats and LoaderStats never observe a partial reset.
+// Exporters that treat the counters as monotonic, such as the otel package, see a reset.
+func (c *Cache) ResetStats() {
+	c.counters.Store(new(counters))
+}
@@ -0,0 +1,178 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SweepStrategy selects how the background janitor finds expired items.
+type SweepStrategy int
+
+const (
+	// SweepFullScan checks every item once per CleanupInterval.
+	SweepFullScan SweepStrategy = iota
+	// SweepSampled checks a bounded random sample of the items that can expire on
+	// each tick, repeating while many of them turn out to be expired, and ticks faster
+	// while the cache holds a lot of expired items. It keeps the pause of each tick
+	// short on large caches at the cost of leaving some expired items unswept for longer.
+	SweepSampled
+	// SweepExpiryHeap keeps the items that can expire in a per-shard min-heap ordered by
+	// deadline, so each tick only visits the items that have expired: O(k log n) for k
+	// expired items instead of a scan of all n. Writes pay O(log n) to keep the heap,
+	// which makes it the best choice for large caches that mostly hold live entries.
+	SweepExpiryHeap
+)
+
+const (
+	// sweepSampleSize is the number of volatile items sampled per shard and round.
+	sweepSampleSize = 20
+	// sweepMaxChecks bounds the number of items a sampled sweep visits per tick.
+	sweepMaxChecks = 2000
+	// sweepRepeatFraction is the fraction of expired samples above which the
+	// sampled sweep runs another round and the next tick comes sooner.
+	sweepRepeatFraction = 0.25
+	// sweepMinIntervalDivisor bounds how much faster than CleanupInterval
+	// the sampled sweep may tick, and sets the default SweepMinInterval.
+	sweepMinIntervalDivisor = 16
+	// sweepQuietFraction is the fraction of expired items below which an adaptive
+	// janitor slows down.
+	sweepQuietFraction = 0.01
+	// sweepJitterFraction is how much an adaptive janitor jitters each delay.
+	sweepJitterFraction = 0.1
+)
+
+// sweep runs one janitor pass with the configured strategy and returns the interval
+// before the next one.
+func (c *Cache) sweep(interval time.Duration) time.Duration {
+	swept, fraction := c.sweepRetired()
+	if c.config.EvictionBatch > 0 {
+		c.catchUpEvictions()
+	}
+	switch c.config.SweepStrategy {
+	case SweepSampled:
+		fraction = c.sweepSampled()
+	case SweepExpiryHeap:
+		fraction = c.sweepExpiryHeap()
+	default:
+		if !swept {
+			fraction = c.cleanup()
+		}
+	}
+	if c.config.DebugChecks {
+		if err := c.Verify(); err != nil {
+			c.logger.Error("cache failed its consistency check", "err", err)
+		}
+	}
+
+	if c.config.SweepMaxInterval > 0 {
+		return c.adaptSweepInterval(interval, fraction)
+	}
+	if c.config.SweepStrategy == SweepSampled && fraction > sweepRepeatFraction {
+		return max(interval/2, c.config.CleanupInterval/sweepMinIntervalDivisor, time.Millisecond)
+	}
+	return c.config.CleanupInterval
+}
+
+// sweepIntervalBounds returns the bounds of the adaptive janitor interval.
+func (c *Cache) sweepIntervalBounds() (time.Duration, time.Duration) {
+	maxInterval := c.config.SweepMaxInterval
+	minInterval := c.config.SweepMinInterval
+	if minInterval <= 0 || minInterval > maxInterval {
+		minInterval = max(maxInterval/sweepMinIntervalDivisor, time.Millisecond)
+	}
+	return minInterval, maxInterval
+}
+
+// initialSweepInterval returns the interval before the first janitor pass.
+func (c *Cache) initialSweepInterval() time.Duration {
+	if c.config.SweepMaxInterval <= 0 {
+		return c.config.CleanupInterval
+	}
+	minInterval, maxInterval := c.sweepIntervalBounds()
+	if c.config.CleanupInterval <= 0 {
+		return maxInterval
+	}
+	return min(max(c.config.CleanupInterval, minInterval), maxInterval)
+}
+
+// adaptSweepInterval halves the janitor interval after a sweep in which more than
+// sweepRepeatFraction of the items checked had expired, and doubles it after one in
+// which fewer than sweepQuietFraction had, within the configured bounds.
+func (c *Cache) adaptSweepInterval(interval time.Duration, fraction float64) time.Duration {
+	minInterval, maxInterval := c.sweepIntervalBounds()
+	switch {
+	case fraction > sweepRepeatFraction:
+		interval /= 2
+	case fraction < sweepQuietFraction:
+		interval *= 2
+	}
+	return min(max(interval, minInterval), maxInterval)
+}
+
+// sweepDelay returns the delay before a janitor pass due after interval, jittered by
+// up to sweepJitterFraction when the interval is adaptive.
+func (c *Cache) sweepDelay(interval time.Duration) time.Duration {
+	if c.config.SweepMaxInterval <= 0 {
+		return interval
+	}
+	delta := float64(interval) * sweepJitterFraction * (2*c.rand.Float64() - 1)
+	return max(interval+time.Duration(delta), time.Millisecond)
+}
+
+// sweepSampled removes expired items found by sampling volatile items shard by shard,
+// in rounds, until a round finds few expired items or the per-tick budget is spent.
+// It returns the fraction of expired items in the last round.
+func (c *Cache) sweepSampled() float64 {
+	now := c.now()
+	budget := sweepMaxChecks
+
+	var evicted []evictedItem
+	var fraction float64
+	for budget > 0 {
+		checked, expired := 0, 0
+		start := c.rand.IntN(len(c.shards))
+		for i := range c.shards {
+			if budget <= 0 {
+				break
+			}
+			s := c.shards[(start+i)%len(c.shards)]
+			s.mu.Lock()
+			if s.volatile == 0 {
+				s.mu.Unlock()
+				continue
+			}
+			// Map iteration starts at a random position, which makes the first
+			// volatile items a random sample.
+			sampled := 0
+			for _, itm := range s.items {
+				if sampled == sweepSampleSize || budget <= 0 {
+					break
+				}
+				budget--
+				if !itm.volatile() {
+					continue
+				}
+				sampled++
+				if itm.dead(now) {
+					evicted = c.dropLocked(s, itm, EvictReasonExpired, evicted)
+					expired++
+				}
+			}
+			s.mu.Unlock()
+			checked += sampled
+		}
+		if checked == 0 {
+			break
+		}
+		fraction = float64(expired) / float64(checked)
+		if fraction <= sweepRepeatFraction {
+			break
+		}
+	}
+
+	if len(evicted) > 0 {
+		atomic.AddInt64(&c.counters.Load().evictions, int64(len(evicted)))
+		c.notifyEvicted(evicted)
+	}
+	return fraction
+}
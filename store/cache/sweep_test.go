@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSweepSampledReclaimsExpired(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{CleanupInterval: 5 * time.Millisecond}, WithClock(clock.Now), WithSweepStrategy(SweepSampled))
+	defer cache.Close()
+
+	const expiring, permanent = 10000, 100
+	for i := 0; i < expiring; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("short:%d", i), i, time.Second)
+	}
+	for i := 0; i < permanent; i++ {
+		cache.Set(ctx, fmt.Sprintf("long:%d", i), i)
+	}
+	clock.Advance(2 * time.Second)
+
+	eventually(t, func() bool { return atomic.LoadInt64(&cache.itemCount) == permanent },
+		"Sampled sweep did not reclaim every expired item")
+	if cache.Len() != permanent {
+		t.Errorf("Expected %d permanent items to survive, got %d", permanent, cache.Len())
+	}
+}
+
+func TestSweepSampledBoundsWork(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{}, WithClock(clock.Now), WithSweepStrategy(SweepSampled))
+	defer cache.Close()
+
+	const n = 10 * sweepMaxChecks
+	for i := 0; i < n; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("key:%d", i), i, time.Second)
+	}
+	clock.Advance(2 * time.Second)
+
+	if fraction := cache.sweepSampled(); fraction <= sweepRepeatFraction {
+		t.Errorf("Expected a high expired fraction, got %v", fraction)
+	}
+	removed := n - atomic.LoadInt64(&cache.itemCount)
+	if removed == 0 || removed > sweepMaxChecks {
+		t.Errorf("Expected between 1 and %d items swept in one tick, got %d", sweepMaxChecks, removed)
+	}
+	if next := cache.sweep(time.Minute); next != 30*time.Second {
+		t.Errorf("Expected the next tick to come sooner, got %v", next)
+	}
+}
+
+func TestSweepIntervalAdapts(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{}, WithClock(clock.Now), WithSweepInterval(time.Second, time.Minute))
+	defer cache.Close()
+
+	interval := cache.initialSweepInterval()
+	if interval != time.Minute {
+		t.Fatalf("Expected the janitor to start at the maximum interval, got %v", interval)
+	}
+
+	// While most entries expire between sweeps, the janitor speeds up down to its minimum.
+	for round := 0; round < 8; round++ {
+		for i := 0; i < 100; i++ {
+			cache.SetWithTTL(ctx, fmt.Sprintf("round:%d:%d", round, i), i, time.Millisecond)
+		}
+		cache.Set(ctx, fmt.Sprintf("keep:%d", round), round)
+		clock.Advance(time.Second)
+		next := cache.sweep(interval)
+		if next > interval || next < time.Second {
+			t.Fatalf("Expected the interval to shorten within bounds after a busy sweep, went from %v to %v", interval, next)
+		}
+		interval = next
+	}
+	if interval != time.Second {
+		t.Errorf("Expected the interval to reach its minimum, got %v", interval)
+	}
+
+	// Once expirations stop, it slows back down to its maximum.
+	for round := 0; round < 8; round++ {
+		next := cache.sweep(interval)
+		if next < interval {
+			t.Fatalf("Expected the interval not to shorten after a quiet sweep, went from %v to %v", interval, next)
+		}
+		interval = next
+	}
+	if interval != time.Minute {
+		t.Errorf("Expected the interval to return to its maximum, got %v", interval)
+	}
+
+	for i := 0; i < 100; i++ {
+		if delay := cache.sweepDelay(time.Minute); delay < 54*time.Second || delay > 66*time.Second {
+			t.Fatalf("Expected a delay within 10%% of the interval, got %v", delay)
+		}
+	}
+}
+
+// checkExpiryHeaps asserts that each shard's expiry heap is ordered and holds
+// exactly the items of the shard that expire.
+func checkExpiryHeaps(t *testing.T, cache *Cache) {
+	t.Helper()
+	for i, s := range cache.shards {
+		s.mu.Lock()
+		h := *s.expiry
+		for j, itm := range h {
+			if itm.heapPos != j+1 || s.items[itm.key] != itm {
+				t.Errorf("Shard %d: heap entry %d (%s) is stale", i, j, itm.key)
+			}
+			if j > 0 && h.Less(j, (j-1)/2) {
+				t.Errorf("Shard %d: heap order violated at %d", i, j)
+			}
+		}
+		expiring := 0
+		for _, itm := range s.items {
+			if !itm.expiration.IsZero() {
+				expiring++
+			}
+		}
+		if expiring != len(h) {
+			t.Errorf("Shard %d: %d expiring items but %d heap entries", i, expiring, len(h))
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestSweepExpiryHeap(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{}, WithClock(clock.Now), WithSweepStrategy(SweepExpiryHeap))
+	defer cache.Close()
+
+	// 1000 keys expiring after 1..10 seconds, plus 100 that never expire.
+	for i := 0; i < 1000; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("key:%d", i), i, time.Duration(i%10+1)*time.Second)
+	}
+	for i := 0; i < 100; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("permanent:%d", i), i, 0)
+	}
+	// Overwrites, Touch and Delete change or drop deadlines.
+	cache.SetWithTTL(ctx, "key:0", 0, 0)           // no longer expires
+	cache.Touch(ctx, "key:1", time.Hour)           // would expire after 2s
+	cache.SetWithTTL(ctx, "key:9", 9, time.Second) // expired after 10s, now 1s
+	cache.Delete(ctx, "key:2")
+	checkExpiryHeaps(t, cache)
+
+	clock.Advance(5*time.Second + time.Millisecond)
+	cache.sweep(time.Minute)
+	checkExpiryHeaps(t, cache)
+	// The 500 keys with TTLs of 1s to 5s are gone, except the two rescued above and the
+	// deleted key:2, and so is key:9.
+	const swept = 500 - 3 + 1
+	if want := int64(1100 - 1 - swept); atomic.LoadInt64(&cache.itemCount) != want {
+		t.Errorf("Expected %d items after the sweep, got %d", want, atomic.LoadInt64(&cache.itemCount))
+	}
+	if _, ok := cache.Get(ctx, "key:0"); !ok {
+		t.Errorf("Expected key:0 to survive without a TTL")
+	}
+	if _, ok := cache.Get(ctx, "key:1"); !ok {
+		t.Errorf("Expected the touched key:1 to survive")
+	}
+	if _, ok := cache.Get(ctx, "key:4"); ok {
+		t.Errorf("Expected key:4 to have been swept")
+	}
+	if _, ok := cache.Get(ctx, "key:15"); !ok {
+		t.Errorf("Expected key:15 to still be live")
+	}
+	if stats := cache.Stats(); stats.Evictions != swept {
+		t.Errorf("Expected %d expiry evictions, got %d", swept, stats.Evictions)
+	}
+
+	cache.Clear(ctx)
+	checkExpiryHeaps(t, cache)
+}
+
+func TestSweepExpiryHeapSlidingExpiration(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{}, WithClock(clock.Now), WithSweepStrategy(SweepExpiryHeap), WithSlidingExpiration(true))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "session", "user:1", time.Minute)
+	cache.SetWithTTL(ctx, "idle", "user:2", time.Minute)
+	clock.Advance(50 * time.Second)
+	cache.Get(ctx, "session")
+	clock.Advance(20 * time.Second)
+	cache.sweep(time.Minute)
+	checkExpiryHeaps(t, cache)
+
+	if _, ok := cache.Get(ctx, "session"); !ok {
+		t.Errorf("Expected the slid session to survive the sweep")
+	}
+	if atomic.LoadInt64(&cache.itemCount) != 1 {
+		t.Errorf("Expected only the idle entry to be swept, got %d items", atomic.LoadInt64(&cache.itemCount))
+	}
+}
+
+func benchmarkSweep(b *testing.B, strategy SweepStrategy) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := New(Config{}, WithClock(clock.Now), WithSweepStrategy(strategy))
+	defer cache.Close()
+
+	// A large cache of long-lived entries with a trickle of expiring ones.
+	for i := 0; i < 100000; i++ {
+		cache.SetWithTTL(ctx, fmt.Sprintf("key:%d", i), i, time.Hour)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := 0; j < 10; j++ {
+			cache.SetWithTTL(ctx, fmt.Sprintf("short:%d", j), j, time.Millisecond)
+		}
+		clock.Advance(time.Millisecond + 1)
+		cache.sweep(time.Minute)
+	}
+}
+
+func BenchmarkSweepFullScan(b *testing.B) {
+	benchmarkSweep(b, SweepFullScan)
+}
+
+func BenchmarkSweepExpiryHeap(b *testing.B) {
+	benchmarkSweep(b, SweepExpiryHeap)
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SetWithTags adds a value to the cache with the default TTL and tags it,
+// so that it can later be removed together with related entries by InvalidateTag.
+// Replacing a value replaces its tags too.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) SetWithTags(ctx context.Context, key string, value any, tags ...string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	if err := c.validateKey(key); err != nil {
+		return err
+	}
+
+	itm := c.newItem(key, value, c.expiresAt(c.config.DefaultTTL))
+	if err := c.checkValueSize(itm); err != nil {
+		return err
+	}
+	// The caller may reuse its slice, which the tag index must not see change.
+	itm.tags = slices.Clone(tags)
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	evicted, err := c.trySetLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
+	return err
+}
+
+// InvalidateTag removes every entry carrying tag and returns how many were removed.
+// Removed entries are reported to the eviction callbacks with EvictReasonDeleted.
+// If ctx is already done, nothing is removed.
+func (c *Cache) InvalidateTag(ctx context.Context, tag string) int {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return 0
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return 0
+	}
+
+	var evicted []evictedItem
+	for _, s := range c.shards {
+		s.mu.Lock()
+		for key := range s.tags[tag] {
+			evicted = c.deleteLocked(s, key, evicted)
+		}
+		s.mu.Unlock()
+	}
+
+	c.notifyEvicted(evicted)
+	return len(evicted)
+}
+
+// tagCounts counts the keys carrying each tag across all shards, for MaxKeysPerTag.
+type tagCounts struct {
+	mu   sync.Mutex
+	keys map[string]int
+}
+
+// trackTags checks the tags of itm, which replaces old if it is not nil, against the tag
+// limits and, if they fit, counts the keys it adds to each tag. old keeps being counted
+// for the tags itm carries too, so that replacing an entry never fails on its own tags.
+// The caller must hold the lock of the item's shard.
+func (c *Cache) trackTags(old, itm *item) error {
+	if limit := c.config.MaxTagsPerEntry; limit > 0 && len(itm.tags) > limit {
+		if n := len(uniqueTagsNotIn(itm.tags, nil)); n > limit {
+			return errors.Wrapf(ErrTagLimit, "key %q has %d tags, limit is %d", itm.key, n, limit)
+		}
+	}
+	if c.tagKeys == nil {
+		return nil
+	}
+	var oldTags []string
+	if old != nil {
+		oldTags = old.tags
+	}
+	added := uniqueTagsNotIn(itm.tags, oldTags)
+	removed := uniqueTagsNotIn(oldTags, itm.tags)
+
+	t := c.tagKeys
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, tag := range added {
+		if t.keys[tag] >= c.config.MaxKeysPerTag {
+			return errors.Wrapf(ErrTagLimit, "tag %q has %d keys, limit is %d", tag, t.keys[tag], c.config.MaxKeysPerTag)
+		}
+	}
+	for _, tag := range added {
+		t.keys[tag]++
+	}
+	t.remove(removed)
+	return nil
+}
+
+// untrackTags uncounts an item that is being removed.
+func (c *Cache) untrackTags(itm *item) {
+	if c.tagKeys == nil || len(itm.tags) == 0 {
+		return
+	}
+	c.tagKeys.mu.Lock()
+	defer c.tagKeys.mu.Unlock()
+	c.tagKeys.remove(uniqueTagsNotIn(itm.tags, nil))
+}
+
+// resetTagCounts forgets every tag, as Clear empties the cache.
+func (c *Cache) resetTagCounts() {
+	if c.tagKeys == nil {
+		return
+	}
+	c.tagKeys.mu.Lock()
+	c.tagKeys.keys = make(map[string]int)
+	c.tagKeys.mu.Unlock()
+}
+
+// remove uncounts a key for each of tags, forgetting tags left without keys.
+// The caller must hold t.mu.
+func (t *tagCounts) remove(tags []string) {
+	for _, tag := range tags {
+		if t.keys[tag]--; t.keys[tag] <= 0 {
+			delete(t.keys, tag)
+		}
+	}
+}
+
+// uniqueTagsNotIn returns the distinct tags of tags that exclude does not contain.
+// It scans slices for the few tags entries usually carry and switches to a set for
+// the many a pathological caller may attach.
+func uniqueTagsNotIn(tags, exclude []string) []string {
+	var result []string
+	if len(tags)+len(exclude) <= 16 {
+		for _, tag := range tags {
+			if !slices.Contains(exclude, tag) && !slices.Contains(result, tag) {
+				result = append(result, tag)
+			}
+		}
+		return result
+	}
+	seen := make(map[string]struct{}, len(tags)+len(exclude))
+	for _, tag := range exclude {
+		seen[tag] = struct{}{}
+	}
+	for _, tag := range tags {
+		if _, ok := seen[tag]; !ok {
+			seen[tag] = struct{}{}
+			result = append(result, tag)
+		}
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCacheInvalidateTag(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	cache.SetWithTags(ctx, "memo:7:render", "<p>hi</p>", "memo:7")
+	cache.SetWithTags(ctx, "memo:7:search", "hi", "memo:7")
+	cache.SetWithTags(ctx, "list:home:1", "page", "memo:7", "memo:8")
+	cache.SetWithTags(ctx, "memo:8:render", "<p>other</p>", "memo:8")
+	cache.Set(ctx, "untagged", "value")
+
+	if removed := cache.InvalidateTag(ctx, "memo:7"); removed != 3 {
+		t.Errorf("Expected 3 entries invalidated, got %d", removed)
+	}
+	for _, key := range []string{"memo:7:render", "memo:7:search", "list:home:1"} {
+		if _, ok := cache.Get(ctx, key); ok {
+			t.Errorf("Key '%s' should have been invalidated", key)
+		}
+	}
+	for _, key := range []string{"memo:8:render", "untagged"} {
+		if _, ok := cache.Get(ctx, key); !ok {
+			t.Errorf("Key '%s' should still be present", key)
+		}
+	}
+
+	// The list page is gone, so only the render entry remains tagged memo:8.
+	if removed := cache.InvalidateTag(ctx, "memo:8"); removed != 1 {
+		t.Errorf("Expected 1 entry invalidated, got %d", removed)
+	}
+	if removed := cache.InvalidateTag(ctx, "memo:7"); removed != 0 {
+		t.Errorf("Expected 0 entries invalidated, got %d", removed)
+	}
+}
+
+func TestCacheSetWithTagsCopiesTags(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithDebugChecks(true))
+	defer cache.Close()
+
+	tags := []string{"memo:1"}
+	cache.SetWithTags(ctx, "memo:1:render", "<p>hi</p>", tags...)
+	tags[0] = "memo:2"
+
+	if removed := cache.InvalidateTag(ctx, "memo:2"); removed != 0 {
+		t.Errorf("Expected reusing the tag slice not to retag the entry, %d removed", removed)
+	}
+	if err := cache.Verify(); err != nil {
+		t.Errorf("Verify failed: %v", err)
+	}
+	if removed := cache.InvalidateTag(ctx, "memo:1"); removed != 1 {
+		t.Errorf("Expected 1 entry invalidated, got %d", removed)
+	}
+}
+
+func TestCacheTagIndexCleanup(t *testing.T) {
+	ctx := context.Background()
+	cache := NewWithCapacity(1, WithShards(1))
+	defer cache.Close()
+
+	cache.SetWithTags(ctx, "key1", "value1", "tag")
+	// Overwriting without tags drops the old tags.
+	cache.Set(ctx, "key1", "value1")
+	cache.SetWithTags(ctx, "key2", "value2", "tag")
+	// Capacity eviction of key2 must clean up the index too.
+	cache.Set(ctx, "key3", "value3")
+
+	if tags := cache.shards[0].tags; len(tags) != 0 {
+		t.Errorf("Expected an empty tag index, got %v", tags)
+	}
+	if removed := cache.InvalidateTag(ctx, "tag"); removed != 0 {
+		t.Errorf("Expected 0 entries invalidated, got %d", removed)
+	}
+}
+
+func TestCacheTagLimits(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithTagLimits(3, 2))
+	defer cache.Close()
+
+	// An entry with too many tags is rejected and leaves the old value in place.
+	cache.SetWithTags(ctx, "memo:1", "old", "a")
+	if err := cache.SetWithTags(ctx, "memo:1", "new", "a", "b", "c"); !errors.Is(err, ErrTagLimit) {
+		t.Errorf("Expected ErrTagLimit for 3 tags, got %v", err)
+	}
+	if value, _ := cache.Get(ctx, "memo:1"); value != "old" {
+		t.Errorf("Expected the rejected write to leave old in place, got %v", value)
+	}
+	// Duplicates count once.
+	if err := cache.SetWithTags(ctx, "memo:1", "new", "a", "b", "a"); err != nil {
+		t.Errorf("Expected 2 distinct tags to fit, got %v", err)
+	}
+
+	// The fourth key of a tag is rejected, across shards, while rewriting a key is not.
+	for i := 2; i <= 3; i++ {
+		if err := cache.SetWithTags(ctx, fmt.Sprintf("memo:%d", i), i, "a"); err != nil {
+			t.Fatalf("Expected memo:%d to fit, got %v", i, err)
+		}
+	}
+	if err := cache.SetWithTags(ctx, "memo:4", 4, "a"); !errors.Is(err, ErrTagLimit) {
+		t.Errorf("Expected ErrTagLimit for the fourth key of a tag, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "memo:4"); ok {
+		t.Errorf("Expected the rejected entry not to be stored")
+	}
+	if err := cache.SetWithTags(ctx, "memo:3", "rewritten", "a"); err != nil {
+		t.Errorf("Expected rewriting a tagged key to fit, got %v", err)
+	}
+	if err := cache.SetWithTags(ctx, "memo:4", 4, "b"); err != nil {
+		t.Errorf("Expected other tags to stay available, got %v", err)
+	}
+
+	// Removing, retagging and expiring entries frees their tags.
+	cache.Delete(ctx, "memo:3")
+	cache.Set(ctx, "memo:2", "untagged")
+	cache.SetWithTags(ctx, "memo:1", "new", "b")
+	for i := 5; i <= 7; i++ {
+		if err := cache.SetWithTags(ctx, fmt.Sprintf("memo:%d", i), i, "a"); err != nil {
+			t.Errorf("Expected memo:%d to fit once the tag was freed, got %v", i, err)
+		}
+	}
+	if removed := cache.InvalidateTag(ctx, "a"); removed != 3 {
+		t.Errorf("Expected 3 entries invalidated, got %d", removed)
+	}
+	cache.Clear(ctx)
+	if n := len(cache.tagKeys.keys); n != 0 {
+		t.Errorf("Expected Clear to reset the tag counts, got %v", cache.tagKeys.keys)
+	}
+}
+
+func TestCacheTagLimitsExpiry(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now), WithTagLimits(1, 0), WithDefaultTTL(time.Minute))
+	defer cache.Close()
+
+	cache.SetWithTags(ctx, "memo:1", 1, "a")
+	clock.Advance(2 * time.Minute)
+	// The expired entry still holds the tag until it is swept.
+	if err := cache.SetWithTags(ctx, "memo:2", 2, "a"); !errors.Is(err, ErrTagLimit) {
+		t.Errorf("Expected ErrTagLimit while the expired entry is indexed, got %v", err)
+	}
+	cache.Get(ctx, "memo:1")
+	if err := cache.SetWithTags(ctx, "memo:2", 2, "a"); err != nil {
+		t.Errorf("Expected the tag to be free once the expired entry is gone, got %v", err)
+	}
+}
@@ -0,0 +1,434 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WriteMode selects how a TieredCache propagates writes to its second tier.
+type WriteMode int
+
+const (
+	// WriteThrough writes to both tiers before returning.
+	WriteThrough WriteMode = iota
+	// WriteBack writes to the first tier and queues the second-tier write,
+	// trading consistency for lower write latency.
+	WriteBack
+)
+
+// SetErrorPolicy selects what a TieredCache does when storing a value in its second
+// tier fails, for example because the value cannot be encoded by a RedisCache codec.
+type SetErrorPolicy int
+
+const (
+	// SetErrorFailClosed returns the error from Put and PutMulti, favoring consistency.
+	// The value stays in the first tier.
+	SetErrorFailClosed SetErrorPolicy = iota
+	// SetErrorFailOpen logs the error and reports success, favoring availability.
+	// The value stays in the first tier.
+	SetErrorFailOpen
+	// SetErrorEvict logs the error, removes the value from the first tier too, and
+	// reports success, so that no replica serves a value the others cannot see.
+	SetErrorEvict
+)
+
+// writeBackQueueSize bounds the number of pending second-tier writes in WriteBack mode.
+const writeBackQueueSize = 1024
+
+// Publisher announces invalidated keys so that peers can drop their local copies.
+type Publisher interface {
+	Publish(ctx context.Context, key string) error
+}
+
+// TieredConfig contains options for configuring a TieredCache.
+type TieredConfig struct {
+	// L1TTL caps the TTL of entries kept in the first tier, bounding how stale
+	// a local copy can get. It is also the TTL of values promoted from L2.
+	// Zero keeps the TTL requested by the caller and promotes without expiration.
+	L1TTL time.Duration
+
+	// WriteMode selects write-through or write-back propagation to the second tier.
+	WriteMode WriteMode
+
+	// Publisher, if set, is notified of every key removed from the second tier.
+	Publisher Publisher
+
+	// Bus, if set, keeps the first tiers of the nodes sharing the second tier coherent:
+	// every key stored or removed through this TieredCache is broadcast once written
+	// to the second tier, and the keys other nodes broadcast are dropped from L1.
+	Bus InvalidationBus
+
+	// NodeID tells the messages of this TieredCache on Bus apart from those of other
+	// nodes, so that it does not drop the values it just stored. It must be unique
+	// in the cluster. Empty means a random ID.
+	NodeID string
+
+	// Logger receives warnings for failed second-tier writes and invalidations.
+	// Nil discards them.
+	Logger *slog.Logger
+
+	// SetErrorPolicy selects how a failed second-tier write of Put or PutMulti is
+	// handled. In WriteBack mode the caller has already returned, so SetErrorFailClosed
+	// can only log the error, like SetErrorFailOpen. An open CircuitBreaker is not a
+	// failure; see CircuitBreaker.
+	SetErrorPolicy SetErrorPolicy
+
+	// DefaultTimeout, if positive, wraps L2 in a TimeoutBackend, so that calls to it
+	// without a deadline of their own time out after DefaultTimeout.
+	DefaultTimeout time.Duration
+
+	// WriteBatching, if set, wraps L2 in a WriteBatcher with this configuration, so
+	// that its writes are sent as batches. Batched writes never fail, so SetErrorPolicy
+	// no longer applies to them. Its Logger defaults to Logger.
+	WriteBatching *WriteBatchConfig
+}
+
+// TieredCache composes a fast local cache (L1) in front of a shared cache (L2).
+// Reads check L1 and then L2, promoting L2 hits into L1; writes and deletes go to both.
+// Wrapping L2 in a CircuitBreaker makes an L2 outage degrade to an L1-only cache.
+// Closing a TieredCache closes both tiers.
+type TieredCache struct {
+	l1     Backend
+	l2     Backend
+	config TieredConfig
+
+	queue     chan func()
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	// mu orders writes against Close: writers hold it for reading while they reach the
+	// second tier or its queue, so that Close never closes the queue under a sender.
+	mu     sync.RWMutex
+	closed bool
+
+	unsubscribe func()
+	// invalidations counts the invalidations received from other nodes, so that a read
+	// of L2 that one raced with is not promoted into L1.
+	invalidations atomic.Uint64
+}
+
+var _ Backend = (*TieredCache)(nil)
+
+// NewTiered creates a two-tier cache over the given backends.
+func NewTiered(l1, l2 Backend, config TieredConfig) *TieredCache {
+	t := &TieredCache{
+		l1:     l1,
+		l2:     l2,
+		config: config,
+	}
+	if t.config.Logger == nil {
+		t.config.Logger = slog.New(slog.DiscardHandler)
+	}
+	if config.DefaultTimeout > 0 {
+		t.l2 = NewTimeoutBackend(t.l2, config.DefaultTimeout)
+	}
+	// The timeout bounds each batch sent, not the wait of a write for its batch.
+	if config.WriteBatching != nil {
+		batching := *config.WriteBatching
+		if batching.Logger == nil {
+			batching.Logger = t.config.Logger
+		}
+		t.l2 = NewWriteBatcher(t.l2, batching)
+	}
+	if config.WriteMode == WriteBack {
+		t.queue = make(chan func(), writeBackQueueSize)
+		t.wg.Add(1)
+		go t.writeBackLoop()
+	}
+	if config.Bus != nil {
+		if t.config.NodeID == "" {
+			t.config.NodeID = rand.Text()
+		}
+		unsubscribe, err := config.Bus.Subscribe(context.Background(), t.applyInvalidation)
+		if err != nil {
+			t.config.Logger.Warn("failed to subscribe to cache invalidations; peers' updates will not reach the first tier", "err", err)
+		} else {
+			t.unsubscribe = unsubscribe
+		}
+	}
+	return t
+}
+
+// Fetch retrieves a value from L1, falling back to L2 and promoting the result into L1.
+func (t *TieredCache) Fetch(ctx context.Context, key string) (any, bool, error) {
+	value, ok, err := t.l1.Fetch(ctx, key)
+	if err != nil || ok {
+		return value, ok, err
+	}
+	epoch := t.invalidations.Load()
+	value, ok, err = t.l2.Fetch(ctx, key)
+	if errors.Is(err, ErrCircuitOpen) {
+		return nil, false, nil
+	}
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if err := t.l1.Put(ctx, key, value, t.config.L1TTL); err != nil {
+		return nil, false, err
+	}
+	if err := t.dropIfInvalidated(ctx, epoch, []string{key}); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// FetchMulti retrieves several values, asking L2 only for the keys L1 does not have.
+func (t *TieredCache) FetchMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	result, err := t.l1.FetchMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	missing := make([]string, 0, len(keys)-len(result))
+	for _, key := range keys {
+		if _, ok := result[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+	epoch := t.invalidations.Load()
+	promoted, err := t.l2.FetchMulti(ctx, missing)
+	if errors.Is(err, ErrCircuitOpen) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := t.l1.PutMulti(ctx, promoted, t.config.L1TTL); err != nil {
+		return nil, err
+	}
+	if t.invalidations.Load() != epoch {
+		keys := make([]string, 0, len(promoted))
+		for key := range promoted {
+			keys = append(keys, key)
+		}
+		if err := t.dropIfInvalidated(ctx, epoch, keys); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range promoted {
+		result[key] = value
+	}
+	return result, nil
+}
+
+// Put stores a value in both tiers.
+func (t *TieredCache) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	if err := t.l1.Put(ctx, key, value, t.l1TTL(ttl)); err != nil {
+		return err
+	}
+	keys := []string{key}
+	return t.writeL2(ctx, t.broadcastAfter(keys, t.applySetErrorPolicy(keys, func(ctx context.Context) error {
+		return t.l2.Put(ctx, key, value, ttl)
+	})))
+}
+
+// PutMulti stores several values in both tiers.
+func (t *TieredCache) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	if err := t.l1.PutMulti(ctx, items, t.l1TTL(ttl)); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	return t.writeL2(ctx, t.broadcastAfter(keys, t.applySetErrorPolicy(keys, func(ctx context.Context) error {
+		return t.l2.PutMulti(ctx, items, ttl)
+	})))
+}
+
+// Remove deletes a value from both tiers and publishes the invalidation.
+func (t *TieredCache) Remove(ctx context.Context, key string) error {
+	return t.RemoveMulti(ctx, []string{key})
+}
+
+// RemoveMulti deletes several values from both tiers and publishes the invalidations.
+func (t *TieredCache) RemoveMulti(ctx context.Context, keys []string) error {
+	if err := t.checkOpen(); err != nil {
+		return err
+	}
+	if err := t.l1.RemoveMulti(ctx, keys); err != nil {
+		return err
+	}
+	return t.writeL2(ctx, func(ctx context.Context) error {
+		// Peers still drop their copies while the second tier is bypassed.
+		if err := t.l2.RemoveMulti(ctx, keys); err != nil && !errors.Is(err, ErrCircuitOpen) {
+			return err
+		}
+		return t.publish(ctx, keys)
+	})
+}
+
+// Close stops applying invalidations from Bus, flushes pending write-back operations
+// and closes both tiers. Writes after Close return ErrClosed.
+func (t *TieredCache) Close() error {
+	var err error
+	t.closeOnce.Do(func() {
+		if t.unsubscribe != nil {
+			t.unsubscribe()
+		}
+		t.mu.Lock()
+		t.closed = true
+		if t.queue != nil {
+			close(t.queue)
+		}
+		t.mu.Unlock()
+		t.wg.Wait()
+		l1Err, l2Err := t.l1.Close(), t.l2.Close()
+		if l1Err != nil {
+			err = l1Err
+		} else {
+			err = l2Err
+		}
+	})
+	return err
+}
+
+// writeL2 runs a second-tier write now in WriteThrough mode, or queues it in WriteBack mode.
+// Queued writes run in order on a single goroutine, detached from the caller's cancellation.
+// It returns ErrClosed once Close has been called.
+func (t *TieredCache) writeL2(ctx context.Context, write func(context.Context) error) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrClosed
+	}
+	write = skipOpenCircuit(write)
+	if t.queue == nil {
+		return write(ctx)
+	}
+	detached := context.WithoutCancel(ctx)
+	select {
+	case t.queue <- func() {
+		if err := write(detached); err != nil {
+			t.config.Logger.Warn("failed to write back to the second cache tier", "err", err)
+		}
+	}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkOpen returns ErrClosed once Close has been called.
+func (t *TieredCache) checkOpen() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// applySetErrorPolicy handles a failure of a second-tier write of keys according to
+// the SetErrorPolicy.
+func (t *TieredCache) applySetErrorPolicy(keys []string, write func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		err := write(ctx)
+		if err == nil || errors.Is(err, ErrCircuitOpen) || t.config.SetErrorPolicy == SetErrorFailClosed {
+			return err
+		}
+		t.config.Logger.Warn("failed to write to the second cache tier", "err", err, "keys", len(keys))
+		if t.config.SetErrorPolicy == SetErrorEvict {
+			if err := t.l1.RemoveMulti(ctx, keys); err != nil {
+				return errors.Wrap(err, "failed to evict from the first cache tier")
+			}
+		}
+		return nil
+	}
+}
+
+// skipOpenCircuit makes a second-tier write succeed without effect while a
+// CircuitBreaker in front of the second tier is open.
+func skipOpenCircuit(write func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if err := write(ctx); !errors.Is(err, ErrCircuitOpen) {
+			return err
+		}
+		return nil
+	}
+}
+
+func (t *TieredCache) writeBackLoop() {
+	defer t.wg.Done()
+	for write := range t.queue {
+		write()
+	}
+}
+
+func (t *TieredCache) publish(ctx context.Context, keys []string) error {
+	if t.config.Publisher != nil {
+		for _, key := range keys {
+			if err := t.config.Publisher.Publish(ctx, key); err != nil {
+				return errors.Wrapf(err, "failed to publish invalidation of key %q", key)
+			}
+		}
+	}
+	return t.broadcast(ctx, keys)
+}
+
+// broadcastAfter broadcasts the invalidation of keys on Bus once a second-tier write
+// of them succeeds, or is skipped by an open CircuitBreaker, so that peers stop serving
+// the values they hold.
+func (t *TieredCache) broadcastAfter(keys []string, write func(context.Context) error) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if err := write(ctx); err != nil && !errors.Is(err, ErrCircuitOpen) {
+			return err
+		}
+		return t.broadcast(ctx, keys)
+	}
+}
+
+// broadcast publishes the invalidation of keys on Bus, if there is one.
+func (t *TieredCache) broadcast(ctx context.Context, keys []string) error {
+	if t.config.Bus == nil || len(keys) == 0 {
+		return nil
+	}
+	if err := t.config.Bus.Publish(ctx, Invalidation{Keys: keys, Node: t.config.NodeID}); err != nil {
+		return errors.Wrapf(err, "failed to broadcast invalidation of %d keys", len(keys))
+	}
+	return nil
+}
+
+// dropIfInvalidated removes keys just promoted into L1 if an invalidation from another
+// node has arrived since epoch, as the values read from L2 may predate it. Checking
+// after the promotion leaves no window: an invalidation counted later removes them itself.
+func (t *TieredCache) dropIfInvalidated(ctx context.Context, epoch uint64, keys []string) error {
+	if t.invalidations.Load() == epoch {
+		return nil
+	}
+	return t.l1.RemoveMulti(ctx, keys)
+}
+
+// applyInvalidation drops the keys another node invalidated from L1. Dropping a key
+// is idempotent and the next read fetches the current value from L2, so duplicate and
+// out-of-order messages are harmless.
+func (t *TieredCache) applyInvalidation(msg Invalidation) {
+	if msg.Node == t.config.NodeID {
+		return
+	}
+	t.invalidations.Add(1)
+	if err := t.l1.RemoveMulti(context.Background(), msg.Keys); err != nil {
+		t.config.Logger.Warn("failed to apply cache invalidation to the first tier", "err", err, "keys", len(msg.Keys))
+	}
+}
+
+// l1TTL caps a requested TTL by L1TTL.
+func (t *TieredCache) l1TTL(ttl time.Duration) time.Duration {
+	if t.config.L1TTL <= 0 || (ttl > 0 && ttl < t.config.L1TTL) {
+		return ttl
+	}
+	return t.config.L1TTL
+}
@@ -0,0 +1,346 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingPublisher struct {
+	mu   sync.Mutex
+	keys []string
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, key string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append(p.keys, key)
+	return nil
+}
+
+// fakeBus delivers every invalidation synchronously to every subscriber, twice if
+// duplicate is set.
+type fakeBus struct {
+	mu        sync.Mutex
+	handlers  map[int]func(Invalidation)
+	next      int
+	duplicate bool
+}
+
+func (b *fakeBus) Publish(_ context.Context, msg Invalidation) error {
+	b.mu.Lock()
+	handlers := make([]func(Invalidation), 0, len(b.handlers))
+	for _, handle := range b.handlers {
+		handlers = append(handlers, handle)
+	}
+	b.mu.Unlock()
+	for _, handle := range handlers {
+		handle(msg)
+		if b.duplicate {
+			handle(msg)
+		}
+	}
+	return nil
+}
+
+func (b *fakeBus) Subscribe(_ context.Context, handle func(Invalidation)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.handlers == nil {
+		b.handlers = make(map[int]func(Invalidation))
+	}
+	id := b.next
+	b.next++
+	b.handlers[id] = handle
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}, nil
+}
+
+func TestTieredCacheInvalidationBus(t *testing.T) {
+	ctx := context.Background()
+	bus := &fakeBus{duplicate: true}
+	shared := NewDefault()
+	l1A, l1B := NewDefault(), NewDefault()
+	nodeA := NewTiered(l1A, shared, TieredConfig{Bus: bus})
+	nodeB := NewTiered(l1B, shared, TieredConfig{Bus: bus})
+	defer nodeA.Close()
+	defer nodeB.Close()
+
+	nodeA.Put(ctx, "memo:1", "v1", time.Minute)
+	if val, ok, err := nodeB.Fetch(ctx, "memo:1"); err != nil || !ok || val != "v1" {
+		t.Fatalf("Expected v1, got %v, exists: %v, err: %v", val, ok, err)
+	}
+	if _, ok := l1B.Get(ctx, "memo:1"); !ok {
+		t.Fatalf("Expected memo:1 to be promoted into the L1 of node B")
+	}
+
+	// An update on node A drops the copy of node B but not its own.
+	if err := nodeA.Put(ctx, "memo:1", "v2", time.Minute); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if _, ok := l1B.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected the update on node A to drop memo:1 from the L1 of node B")
+	}
+	if val, ok := l1A.Get(ctx, "memo:1"); !ok || val != "v2" {
+		t.Errorf("Expected node A to ignore its own invalidation, got %v, exists: %v", val, ok)
+	}
+	if val, _, _ := nodeB.Fetch(ctx, "memo:1"); val != "v2" {
+		t.Errorf("Expected node B to read v2 from L2, got %v", val)
+	}
+
+	// A delete on node A drops the copy of node B too.
+	if err := nodeA.Remove(ctx, "memo:1"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok := l1B.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected the delete on node A to drop memo:1 from the L1 of node B")
+	}
+
+	// A late invalidation of an older write only costs a read of L2.
+	nodeB.PutMulti(ctx, map[string]any{"memo:2": "b"}, time.Minute)
+	bus.Publish(ctx, Invalidation{Keys: []string{"memo:2"}, Node: "stale-node"})
+	if val, ok, err := nodeB.Fetch(ctx, "memo:2"); err != nil || !ok || val != "b" {
+		t.Errorf("Expected b after a late invalidation, got %v, exists: %v, err: %v", val, ok, err)
+	}
+
+	// After Close a node no longer applies invalidations.
+	l1C := NewDefault()
+	nodeC := NewTiered(l1C, NewDefault(), TieredConfig{Bus: bus})
+	l1C.Set(ctx, "memo:3", "c")
+	nodeC.Close()
+	nodeA.Remove(ctx, "memo:3")
+	if _, ok := l1C.Get(ctx, "memo:3"); !ok {
+		t.Errorf("Expected a closed node to stop applying invalidations")
+	}
+}
+
+func TestTieredCachePromotion(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewDefault(), NewDefault()
+	tiered := NewTiered(l1, l2, TieredConfig{L1TTL: time.Minute})
+	defer tiered.Close()
+
+	// A value only present in L2 is promoted into L1 on read.
+	l2.Set(ctx, "memo:1", "shared")
+	if val, ok, err := tiered.Fetch(ctx, "memo:1"); err != nil || !ok || val != "shared" {
+		t.Fatalf("Expected 'shared', got %v, exists: %v, err: %v", val, ok, err)
+	}
+	if val, ok := l1.Get(ctx, "memo:1"); !ok || val != "shared" {
+		t.Errorf("Expected L2 hit to be promoted into L1, got %v, exists: %v", val, ok)
+	}
+
+	l2.Set(ctx, "memo:2", "two")
+	values, err := tiered.FetchMulti(ctx, []string{"memo:1", "memo:2", "memo:3"})
+	if err != nil || len(values) != 2 {
+		t.Errorf("Unexpected FetchMulti result %v, err: %v", values, err)
+	}
+	if _, ok := l1.Get(ctx, "memo:2"); !ok {
+		t.Errorf("Expected FetchMulti to promote L2 hits into L1")
+	}
+}
+
+func TestTieredCachePropagation(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewDefault(), NewDefault()
+	publisher := &recordingPublisher{}
+	tiered := NewTiered(l1, l2, TieredConfig{Publisher: publisher})
+	defer tiered.Close()
+
+	if err := tiered.Put(ctx, "memo:1", "value", time.Minute); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	for name, tier := range map[string]*Cache{"L1": l1, "L2": l2} {
+		if _, ok := tier.Get(ctx, "memo:1"); !ok {
+			t.Errorf("Expected write to reach %s", name)
+		}
+	}
+
+	if err := tiered.Remove(ctx, "memo:1"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	for name, tier := range map[string]*Cache{"L1": l1, "L2": l2} {
+		if _, ok := tier.Get(ctx, "memo:1"); ok {
+			t.Errorf("Expected delete to reach %s", name)
+		}
+	}
+	if len(publisher.keys) != 1 || publisher.keys[0] != "memo:1" {
+		t.Errorf("Expected invalidation of memo:1 to be published, got %v", publisher.keys)
+	}
+}
+
+func TestTieredCacheWriteBack(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewDefault(), NewDefault()
+	tiered := NewTiered(l1, l2, TieredConfig{WriteMode: WriteBack})
+
+	for i := 0; i < 100; i++ {
+		tiered.Put(ctx, "counter", i, 0)
+	}
+	tiered.Put(ctx, "deleted", "value", 0)
+	tiered.Remove(ctx, "deleted")
+	if val, ok := l1.Get(ctx, "counter"); !ok || val != 99 {
+		t.Errorf("Expected L1 to be written synchronously, got %v", val)
+	}
+
+	// Close drains the queue in order before closing the tiers.
+	if err := tiered.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if val, ok := l2.Get(ctx, "counter"); !ok || val != 99 {
+		t.Errorf("Expected the last queued write to win in L2, got %v", val)
+	}
+	if _, ok := l2.Get(ctx, "deleted"); ok {
+		t.Errorf("Expected the queued delete to apply after the queued write")
+	}
+}
+
+func TestTieredCacheWriteBackCloseRace(t *testing.T) {
+	ctx := context.Background()
+	tiered := NewTiered(NewDefault(), NewDefault(), TieredConfig{WriteMode: WriteBack})
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				key := fmt.Sprintf("memo:%d:%d", w, i)
+				err := tiered.Put(ctx, key, i, 0)
+				if err == nil {
+					err = tiered.Remove(ctx, key)
+				}
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := tiered.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	wg.Wait()
+
+	if err := tiered.Put(ctx, "late", 1, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+	if err := tiered.PutMulti(ctx, map[string]any{"late": 1}, 0); !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed from PutMulti after Close, got %v", err)
+	}
+}
+
+// encodingBackend stores values encoded with a codec, like RedisCache, so that
+// values the codec cannot encode fail to be stored.
+type encodingBackend struct {
+	*Cache
+	codec Codec
+}
+
+func (b encodingBackend) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	data, err := b.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.Cache.Put(ctx, key, data, ttl)
+}
+
+func (b encodingBackend) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	for key, value := range items {
+		if err := b.Put(ctx, key, value, ttl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rejectingCodec is a JSONCodec that fails to encode values of type draftMemo only.
+type rejectingCodec struct {
+	JSONCodec
+}
+
+type draftMemo struct {
+	Content string
+}
+
+func (c rejectingCodec) Marshal(value any) ([]byte, error) {
+	if _, ok := value.(draftMemo); ok {
+		return nil, errors.New("drafts cannot be encoded")
+	}
+	return c.JSONCodec.Marshal(value)
+}
+
+func TestTieredCacheSetErrorPolicy(t *testing.T) {
+	ctx := context.Background()
+	unencodable := draftMemo{Content: "draft"}
+
+	tests := []struct {
+		policy  SetErrorPolicy
+		wantErr bool
+		keepsL1 bool
+	}{
+		{SetErrorFailClosed, true, true},
+		{SetErrorFailOpen, false, true},
+		{SetErrorEvict, false, false},
+	}
+	for _, tt := range tests {
+		handler := &captureHandler{}
+		l1, l2 := NewDefault(), encodingBackend{NewDefault(), rejectingCodec{}}
+		tiered := NewTiered(l1, l2, TieredConfig{SetErrorPolicy: tt.policy, Logger: slog.New(handler)})
+
+		for _, key := range []string{"memo:1", "memo:2"} {
+			if err := tiered.Put(ctx, key, "value", 0); err != nil {
+				t.Errorf("Policy %d: expected an encodable value to be stored, got %v", tt.policy, err)
+			}
+		}
+		// The failed write replaces memo:2 in L1, or removes it from L1 under SetErrorEvict.
+		err := tiered.Put(ctx, "memo:2", unencodable, 0)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Policy %d: expected error %v, got %v", tt.policy, tt.wantErr, err)
+		}
+		err = tiered.PutMulti(ctx, map[string]any{"memo:3": unencodable}, 0)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Policy %d: expected PutMulti error %v, got %v", tt.policy, tt.wantErr, err)
+		}
+		for _, key := range []string{"memo:2", "memo:3"} {
+			value, ok := l1.Get(ctx, key)
+			if ok != tt.keepsL1 || ok && value != unencodable {
+				t.Errorf("Policy %d: expected %s in L1 to be %v, got %v", tt.policy, key, tt.keepsL1, value)
+			}
+		}
+		if _, ok := l1.Get(ctx, "memo:1"); !ok {
+			t.Errorf("Policy %d: expected the stored value to stay in L1", tt.policy)
+		}
+		// Failures that do not reach the caller are logged through the configured logger.
+		if warnings := handler.messages(slog.LevelWarn); (len(warnings) > 0) == tt.wantErr {
+			t.Errorf("Policy %d: expected warnings only for swallowed errors, got %v", tt.policy, warnings)
+		}
+		tiered.Close()
+	}
+}
+
+func TestTieredCacheSetErrorPolicyWriteBack(t *testing.T) {
+	ctx := context.Background()
+	l1, l2 := NewDefault(), encodingBackend{NewDefault(), JSONCodec{}}
+	tiered := NewTiered(l1, l2, TieredConfig{WriteMode: WriteBack, SetErrorPolicy: SetErrorEvict})
+
+	if err := tiered.Put(ctx, "memo:1", make(chan int), 0); err != nil {
+		t.Errorf("Expected a write-back Put to succeed, got %v", err)
+	}
+	// Close drains the queue, which evicts the value L2 rejected.
+	tiered.Close()
+	if _, ok := l1.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected the rejected value to be evicted from L1")
+	}
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// TimeoutBackend is a Backend that bounds every call to another backend, such as
+// a RedisCache, by a default timeout when the caller's context has no deadline of
+// its own, so a hung connection cannot stall a request indefinitely.
+// Contexts that already carry a deadline are passed through unchanged.
+type TimeoutBackend struct {
+	backend Backend
+	timeout time.Duration
+}
+
+var _ Backend = (*TimeoutBackend)(nil)
+
+// NewTimeoutBackend wraps backend so that calls without a deadline time out after d.
+// A non-positive d leaves calls unbounded.
+func NewTimeoutBackend(backend Backend, d time.Duration) *TimeoutBackend {
+	return &TimeoutBackend{backend: backend, timeout: d}
+}
+
+// bound returns ctx with the default timeout applied if it has no deadline.
+func (t *TimeoutBackend) bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || t.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, t.timeout)
+}
+
+// Fetch retrieves a value from the backend.
+func (t *TimeoutBackend) Fetch(ctx context.Context, key string) (any, bool, error) {
+	ctx, cancel := t.bound(ctx)
+	defer cancel()
+	return t.backend.Fetch(ctx, key)
+}
+
+// Put stores a value in the backend.
+func (t *TimeoutBackend) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	ctx, cancel := t.bound(ctx)
+	defer cancel()
+	return t.backend.Put(ctx, key, value, ttl)
+}
+
+// Remove deletes a value from the backend.
+func (t *TimeoutBackend) Remove(ctx context.Context, key string) error {
+	ctx, cancel := t.bound(ctx)
+	defer cancel()
+	return t.backend.Remove(ctx, key)
+}
+
+// FetchMulti retrieves several values from the backend.
+func (t *TimeoutBackend) FetchMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	ctx, cancel := t.bound(ctx)
+	defer cancel()
+	return t.backend.FetchMulti(ctx, keys)
+}
+
+// PutMulti stores several values in the backend.
+func (t *TimeoutBackend) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	ctx, cancel := t.bound(ctx)
+	defer cancel()
+	return t.backend.PutMulti(ctx, items, ttl)
+}
+
+// RemoveMulti deletes several values from the backend.
+func (t *TimeoutBackend) RemoveMulti(ctx context.Context, keys []string) error {
+	ctx, cancel := t.bound(ctx)
+	defer cancel()
+	return t.backend.RemoveMulti(ctx, keys)
+}
+
+// Close closes the backend.
+func (t *TimeoutBackend) Close() error {
+	return t.backend.Close()
+}
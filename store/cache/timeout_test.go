@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTimeoutBackend(t *testing.T) {
+	const timeout = 20 * time.Millisecond
+	t.Run("wrapper", func(t *testing.T) {
+		testDefaultTimeout(t, NewTimeoutBackend(&slowBackend{Backend: NewDefault()}, timeout), timeout)
+	})
+	t.Run("TieredConfig", func(t *testing.T) {
+		tiered := NewTiered(NewDefault(), &slowBackend{Backend: NewDefault()}, TieredConfig{DefaultTimeout: timeout})
+		testDefaultTimeout(t, tiered, timeout)
+	})
+}
+
+// testDefaultTimeout checks that the calls of backend, whose network is hung, return
+// after timeout unless the caller sets a deadline.
+func testDefaultTimeout(t *testing.T, backend Backend, timeout time.Duration) {
+	defer backend.Close()
+	ctx := context.Background()
+
+	calls := map[string]func() error{
+		"Fetch": func() error {
+			_, _, err := backend.Fetch(ctx, "key")
+			return err
+		},
+		"Put":    func() error { return backend.Put(ctx, "key", "value", 0) },
+		"Remove": func() error { return backend.Remove(ctx, "key") },
+	}
+	for name, call := range calls {
+		start := time.Now()
+		err := call()
+		elapsed := time.Since(start)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: expected context.DeadlineExceeded, got %v", name, err)
+		}
+		if elapsed < timeout || elapsed > 10*timeout {
+			t.Errorf("%s: expected to return after about %v, took %v", name, timeout, elapsed)
+		}
+	}
+
+	// A deadline set by the caller takes precedence.
+	short, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	backend.Fetch(short, "key")
+	if elapsed := time.Since(start); elapsed >= timeout {
+		t.Errorf("Expected the caller's shorter deadline to apply, took %v", elapsed)
+	}
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// NoExpiration is the remaining lifetime GetWithTTL reports for values stored without a TTL.
+const NoExpiration time.Duration = -1
+
+// GetWithTTL retrieves a value from the cache along with how long it remains valid,
+// for example to derive a Cache-Control max-age. Values stored without a TTL report
+// NoExpiration. A value with no time left is a miss. Reading does not extend the TTL
+// unless sliding expiration is on, in which case the extended TTL is reported.
+// If ctx is already done, GetWithTTL reports a miss without touching the cache or its counters.
+func (c *Cache) GetWithTTL(ctx context.Context, key string) (value any, remaining time.Duration, ok bool) {
+	if ctx.Err() != nil {
+		return nil, 0, false
+	}
+	now := c.now()
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	if itm, exists := s.items[key]; exists && itm.expiration.Equal(now) {
+		// Not yet expired, but with no time left to report.
+		s.mu.Unlock()
+		atomic.AddInt64(&c.counters.Load().misses, 1)
+		c.notifyMiss(key)
+		return nil, 0, false
+	}
+	value, ok, evicted := c.getLocked(s, key, now, nil)
+	if ok {
+		remaining = NoExpiration
+		if expiration := s.items[key].expiration; !expiration.IsZero() {
+			remaining = expiration.Sub(now)
+		}
+	}
+	s.mu.Unlock()
+
+	c.notifyEvicted(evicted)
+	if !ok {
+		c.notifyMiss(key)
+	}
+	return c.readValue(value), remaining, ok
+}
+
+// Touch extends the life of a live value to ttl from now without rewriting it,
+// for keep-alive style entries such as sessions, and reports whether the key held
+// a live value. A non-positive ttl makes the value never expire. A grace window set
+// by SetWithGrace keeps its length.
+// If ctx is already done, the cache is left unchanged and false is returned.
+func (c *Cache) Touch(ctx context.Context, key string, ttl time.Duration) bool {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return false
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return false
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return c.touchLocked(s, key, ttl, c.now())
+}
+
+// TouchMulti is like Touch for several keys, taking each shard lock at most once.
+// It returns the number of keys that held a live value.
+func (c *Cache) TouchMulti(ctx context.Context, keys []string, ttl time.Duration) int {
+	if ctx.Err() != nil || c.checkOpen() != nil {
+		return 0
+	}
+	if skip, _ := c.checkWritable(); skip {
+		return 0
+	}
+	now := c.now()
+
+	touched := 0
+	for i, group := range c.groupByShard(keys) {
+		if len(group) == 0 {
+			continue
+		}
+		s := c.shards[i]
+		s.mu.Lock()
+		for _, key := range group {
+			if c.touchLocked(s, key, ttl, now) {
+				touched++
+			}
+		}
+		s.mu.Unlock()
+	}
+	return touched
+}
+
+// touchLocked extends the life of a live value to ttl from now and reports whether
+// key held one. The caller must hold s.mu.
+func (c *Cache) touchLocked(s *shard, key string, ttl time.Duration, now time.Time) bool {
+	itm, ok := s.items[key]
+	if !ok || !itm.live(now) {
+		return false
+	}
+
+	if itm.volatile() {
+		s.volatile--
+	}
+	itm.ttl = ttl
+	itm.setExpiration(c.expiresAt(ttl))
+	s.retrackExpiry(itm)
+	if itm.volatile() {
+		s.volatile++
+	}
+	s.lru.moveToFront(itm)
+	c.logSet(itm)
+	return true
+}
+
+// slideLocked restarts the TTL of an item that was just read, if sliding expiration
+// is on. With MaxTTL set, the item still expires at most MaxTTL after it was written.
+// The caller must hold s.mu.
+func (c *Cache) slideLocked(s *shard, itm *item, now time.Time) {
+	if !c.config.SlidingExpiration || itm.ttl <= 0 || itm.expiration.IsZero() {
+		return
+	}
+	expiration := now.Add(itm.ttl)
+	if maxTTL := c.config.MaxTTL; maxTTL > 0 {
+		if limit := itm.createdAt.Add(maxTTL); expiration.After(limit) {
+			expiration = limit
+		}
+	}
+	if expiration.After(itm.expiration) {
+		itm.setExpiration(expiration)
+		s.retrackExpiry(itm)
+	}
+}
+
+// expiresAt returns the expiration time for an entry written now with the given
+// nominal TTL, after applying the configured jitter and cap.
+// A non-positive TTL yields the zero time, meaning no expiration, unless MaxTTL is set.
+func (c *Cache) expiresAt(ttl time.Duration) time.Time {
+	return expirationFor(c.now(), c.capTTL(c.jitter(ttl)))
+}
+
+// capTTL clamps ttl to MaxTTL, if set, treating a non-positive ttl as unbounded.
+func (c *Cache) capTTL(ttl time.Duration) time.Duration {
+	if maxTTL := c.config.MaxTTL; maxTTL > 0 && (ttl <= 0 || ttl > maxTTL) {
+		return maxTTL
+	}
+	return ttl
+}
+
+// capExpiration clamps an absolute expiration to MaxTTL from now, if set.
+func (c *Cache) capExpiration(expiration time.Time) time.Time {
+	if c.config.MaxTTL <= 0 {
+		return expiration
+	}
+	if limit := c.now().Add(c.config.MaxTTL); expiration.IsZero() || expiration.After(limit) {
+		return limit
+	}
+	return expiration
+}
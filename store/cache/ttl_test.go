@@ -0,0 +1,248 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheGetWithTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "render", "<p>memo</p>", time.Minute)
+	cache.SetWithTTL(ctx, "forever", "value", 0)
+
+	clock.Advance(20 * time.Second)
+	if val, remaining, ok := cache.GetWithTTL(ctx, "render"); !ok || val != "<p>memo</p>" || remaining != 40*time.Second {
+		t.Errorf("Expected 40s left, got %v, remaining: %v, exists: %v", val, remaining, ok)
+	}
+	// Reading does not reset the TTL.
+	clock.Advance(20 * time.Second)
+	if _, remaining, _ := cache.GetWithTTL(ctx, "render"); remaining != 20*time.Second {
+		t.Errorf("Expected 20s left, got %v", remaining)
+	}
+
+	if val, remaining, ok := cache.GetWithTTL(ctx, "forever"); !ok || val != "value" || remaining != NoExpiration {
+		t.Errorf("Expected NoExpiration, got %v, remaining: %v, exists: %v", val, remaining, ok)
+	}
+
+	// No time left is a miss, even at the exact instant of expiry.
+	clock.Advance(20 * time.Second)
+	if val, remaining, ok := cache.GetWithTTL(ctx, "render"); ok || val != nil || remaining != 0 {
+		t.Errorf("Expected miss at expiry, got %v, remaining: %v, exists: %v", val, remaining, ok)
+	}
+	clock.Advance(time.Nanosecond)
+	if _, _, ok := cache.GetWithTTL(ctx, "render"); ok {
+		t.Errorf("Expected miss after expiry")
+	}
+	if _, _, ok := cache.GetWithTTL(ctx, "missing"); ok {
+		t.Errorf("Expected miss for a missing key")
+	}
+}
+
+func TestCacheDefaultTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now), WithDefaultTTL(time.Minute))
+	defer cache.Close()
+
+	cache.Set(ctx, "default", "value")
+	cache.SetWithTTL(ctx, "override", "value", time.Hour)
+	cache.SetWithTTL(ctx, "forever", "value", 0)
+	for key, want := range map[string]time.Duration{"default": time.Minute, "override": time.Hour, "forever": NoExpiration} {
+		if _, remaining, ok := cache.GetWithTTL(ctx, key); !ok || remaining != want {
+			t.Errorf("Expected key '%s' to have TTL %v, got %v (exists: %v)", key, want, remaining, ok)
+		}
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, ok := cache.Get(ctx, "default"); ok {
+		t.Errorf("Expected key 'default' to expire after the default TTL")
+	}
+	if got := cache.GetMulti(ctx, []string{"override", "forever"}); len(got) != 2 {
+		t.Errorf("Expected explicit TTLs to override the default, got %v", got)
+	}
+
+	// MaxTTL caps the default too.
+	capped := NewDefault(WithClock(clock.Now), WithDefaultTTL(time.Hour), WithMaxTTL(time.Minute))
+	defer capped.Close()
+	capped.Set(ctx, "default", "value")
+	if _, remaining, _ := capped.GetWithTTL(ctx, "default"); remaining != time.Minute {
+		t.Errorf("Expected the default TTL to be capped at 1m, got %v", remaining)
+	}
+}
+
+func TestCacheMaxTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now), WithMaxTTL(time.Hour))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "session", "value", 5*365*24*time.Hour)
+	cache.SetWithTTL(ctx, "forever", "value", 0)
+	cache.SetWithTTL(ctx, "short", "value", time.Minute)
+	if _, remaining, _ := cache.GetWithTTL(ctx, "short"); remaining != time.Minute {
+		t.Errorf("Expected a TTL below the cap to be kept, got %v", remaining)
+	}
+
+	clock.Advance(time.Hour)
+	for _, key := range []string{"session", "forever"} {
+		if _, remaining, ok := cache.GetWithTTL(ctx, key); ok {
+			t.Errorf("Expected key '%s' to expire at the cap, %v left", key, remaining)
+		}
+	}
+}
+
+func TestCacheTouch(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "session", "user:1", time.Minute)
+	clock.Advance(50 * time.Second)
+	if !cache.Touch(ctx, "session", time.Minute) {
+		t.Fatalf("Expected Touch to find the session")
+	}
+	clock.Advance(50 * time.Second)
+	if val, remaining, ok := cache.GetWithTTL(ctx, "session"); !ok || val != "user:1" || remaining != 10*time.Second {
+		t.Errorf("Expected the session to be extended, got %v, remaining: %v, exists: %v", val, remaining, ok)
+	}
+
+	// Dropping the TTL makes the value permanent and keeps it counted.
+	if !cache.Touch(ctx, "session", 0) {
+		t.Fatalf("Expected Touch to find the session")
+	}
+	clock.Advance(time.Hour)
+	if cache.Len() != 1 {
+		t.Errorf("Expected the touched session to stay live, got %d entries", cache.Len())
+	}
+
+	cache.SetWithTTL(ctx, "expired", "value", time.Second)
+	clock.Advance(2 * time.Second)
+	if cache.Touch(ctx, "expired", time.Minute) {
+		t.Errorf("Expected Touch to leave an expired key alone")
+	}
+	if _, ok := cache.Get(ctx, "expired"); ok {
+		t.Errorf("Expected the expired key to stay expired")
+	}
+	if cache.Touch(ctx, "missing", time.Minute) {
+		t.Errorf("Expected Touch to report a missing key")
+	}
+}
+
+func TestCacheSlidingExpiration(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now), WithSlidingExpiration(true))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "active", "session", time.Minute)
+	cache.SetWithTTL(ctx, "idle", "session", time.Minute)
+	cache.SetWithTTL(ctx, "forever", "session", 0)
+
+	// Reading the active session every 40s keeps it alive well past its TTL.
+	for i := 0; i < 5; i++ {
+		clock.Advance(40 * time.Second)
+		if _, ok := cache.Get(ctx, "active"); !ok {
+			t.Fatalf("Expected the active session to survive read %d", i)
+		}
+	}
+	if _, ok := cache.Get(ctx, "idle"); ok {
+		t.Errorf("Expected the idle session to expire")
+	}
+	if _, remaining, _ := cache.GetWithTTL(ctx, "active"); remaining != time.Minute {
+		t.Errorf("Expected a read to restart the full TTL, got %v left", remaining)
+	}
+	if _, remaining, ok := cache.GetWithTTL(ctx, "forever"); !ok || remaining != NoExpiration {
+		t.Errorf("Sliding should not give an expiry to values without one, got %v", remaining)
+	}
+
+	clock.Advance(time.Minute + time.Second)
+	if _, ok := cache.Get(ctx, "active"); ok {
+		t.Errorf("Expected the session to expire once reads stop")
+	}
+}
+
+func TestCacheSlidingExpirationMaxTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now), WithSlidingExpiration(true), WithMaxTTL(2*time.Minute))
+	defer cache.Close()
+
+	cache.SetWithTTL(ctx, "session", "value", time.Minute)
+	for i := 0; i < 2; i++ {
+		clock.Advance(40 * time.Second)
+		cache.Get(ctx, "session")
+	}
+	if _, remaining, ok := cache.GetWithTTL(ctx, "session"); !ok || remaining != 40*time.Second {
+		t.Errorf("Expected sliding to stop at MaxTTL from the write, 40s left, got %v", remaining)
+	}
+	clock.Advance(41 * time.Second)
+	if _, ok := cache.Get(ctx, "session"); ok {
+		t.Errorf("Expected reads not to extend the session past MaxTTL from its write")
+	}
+}
+
+func TestCacheTouchMulti(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.SetWithTTL(ctx, key, key, time.Minute)
+	}
+	if touched := cache.TouchMulti(ctx, []string{"a", "b", "missing"}, time.Hour); touched != 2 {
+		t.Errorf("Expected 2 keys touched, got %d", touched)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if got := cache.GetMulti(ctx, []string{"a", "b", "c"}); len(got) != 2 || got["c"] != nil {
+		t.Errorf("Expected only the touched keys to survive, got %v", got)
+	}
+}
+
+func TestCacheSetWithDeadline(t *testing.T) {
+	ctx := context.Background()
+	clock := newFakeClock()
+	cache := NewDefault(WithClock(clock.Now))
+	defer cache.Close()
+
+	deadline := clock.Now().Add(90 * time.Second)
+	if err := cache.SetWithDeadline(ctx, "token", "secret", deadline); err != nil {
+		t.Fatalf("SetWithDeadline failed: %v", err)
+	}
+	clock.Advance(30 * time.Second)
+	if val, remaining, ok := cache.GetWithTTL(ctx, "token"); !ok || val != "secret" || remaining != time.Minute {
+		t.Errorf("Expected the token to expire at its deadline, got %v, remaining: %v, exists: %v", val, remaining, ok)
+	}
+	if info, _ := cache.Inspect(ctx, "token"); !info.ExpiresAt.Equal(deadline) {
+		t.Errorf("Expected ExpiresAt %v, got %v", deadline, info.ExpiresAt)
+	}
+
+	// Touch turns the deadline back into a relative TTL.
+	if !cache.Touch(ctx, "token", 5*time.Minute) {
+		t.Fatalf("Expected Touch to find the token")
+	}
+	clock.Advance(2 * time.Minute)
+	if _, remaining, ok := cache.GetWithTTL(ctx, "token"); !ok || remaining != 3*time.Minute {
+		t.Errorf("Expected Touch to extend past the deadline, remaining: %v, exists: %v", remaining, ok)
+	}
+	clock.Advance(3*time.Minute + time.Second)
+	if _, ok := cache.Get(ctx, "token"); ok {
+		t.Errorf("Expected the token to expire")
+	}
+
+	// A deadline in the past stores nothing and drops the previous value.
+	cache.Set(ctx, "stale", "old")
+	if err := cache.SetWithDeadline(ctx, "stale", "new", clock.Now().Add(-time.Second)); err != nil {
+		t.Fatalf("SetWithDeadline failed: %v", err)
+	}
+	if _, ok := cache.Get(ctx, "stale"); ok || cache.Len() != 0 {
+		t.Errorf("Expected a past deadline to leave nothing behind, got %d entries", cache.Len())
+	}
+}
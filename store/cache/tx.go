@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// TxBuffer queues cache writes made during a store transaction and applies them only
+// once the transaction commits, so that a rollback never leaves the cache holding a
+// value the store does not. Get one from Cache.BeginTx when beginning the transaction,
+// queue writes on it instead of the cache, and finish it with Commit, or CommitWith
+// to commit the transaction too, or Rollback. Reads go to the cache, which keeps
+// serving the committed state until then. A TxBuffer is safe for concurrent use but
+// cannot be reused; writes queued after it is finished are dropped.
+//
+// TxBuffer is not tied to any transaction itself. The Store runs its memo writes
+// without transactions, and invalidates its caches after each write succeeds, so it
+// does not use one; it is for callers that run their own *sql.Tx against the database
+// of the Store.
+type TxBuffer struct {
+	cache *Cache
+
+	mu   sync.Mutex
+	ops  []txOp
+	done bool
+}
+
+// txOp is a queued cache write. It returns the key it failed to write, if any, so that
+// Commit can drop the value the cache still holds for it.
+type txOp func(ctx context.Context, c *Cache) (failedKey string, err error)
+
+// BeginTx returns an empty TxBuffer for a transaction that writes to the cache.
+func (c *Cache) BeginTx() *TxBuffer {
+	return &TxBuffer{cache: c}
+}
+
+// Set queues adding a value with the default TTL.
+func (b *TxBuffer) Set(key string, value any) {
+	b.queue(func(ctx context.Context, c *Cache) (string, error) {
+		return key, c.Set(ctx, key, value)
+	})
+}
+
+// SetWithTTL queues adding a value with a custom TTL.
+func (b *TxBuffer) SetWithTTL(key string, value any, ttl time.Duration) {
+	b.queue(func(ctx context.Context, c *Cache) (string, error) {
+		return key, c.SetWithTTL(ctx, key, value, ttl)
+	})
+}
+
+// SetWithTags queues adding a tagged value with the default TTL.
+func (b *TxBuffer) SetWithTags(key string, value any, tags ...string) {
+	b.queue(func(ctx context.Context, c *Cache) (string, error) {
+		return key, c.SetWithTags(ctx, key, value, tags...)
+	})
+}
+
+// Delete queues removing a value.
+func (b *TxBuffer) Delete(key string) {
+	b.queue(func(ctx context.Context, c *Cache) (string, error) {
+		return "", c.Delete(ctx, key)
+	})
+}
+
+// InvalidateTag queues removing every entry carrying tag.
+func (b *TxBuffer) InvalidateTag(tag string) {
+	b.queue(func(ctx context.Context, c *Cache) (string, error) {
+		c.InvalidateTag(ctx, tag)
+		return "", nil
+	})
+}
+
+// Len returns the number of queued writes.
+func (b *TxBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.ops)
+}
+
+func (b *TxBuffer) queue(op txOp) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.done {
+		b.ops = append(b.ops, op)
+	}
+}
+
+// finish takes the queued writes and marks the buffer finished. It reports false if
+// it was finished already.
+func (b *TxBuffer) finish() ([]txOp, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		return nil, false
+	}
+	ops := b.ops
+	b.ops, b.done = nil, true
+	return ops, true
+}
+
+// Commit applies the queued writes in order; call it once the transaction has committed.
+// A write that fails, for example with ErrValueTooLarge, removes the key instead, so that
+// the cache does not keep serving the value from before the transaction, and Commit
+// returns the first such error after applying the rest. Writes are applied with a context
+// detached from the cancellation of ctx, as the transaction cannot be undone anymore.
+// Commit does nothing on a finished buffer.
+func (b *TxBuffer) Commit(ctx context.Context) error {
+	ops, ok := b.finish()
+	if !ok {
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+	var firstErr error
+	for _, op := range ops {
+		key, err := op(ctx, b.cache)
+		if err == nil {
+			continue
+		}
+		if key != "" {
+			b.cache.Delete(ctx, key)
+		}
+		if firstErr == nil {
+			firstErr = errors.Wrap(err, "failed to apply cache write of committed transaction")
+		}
+	}
+	return firstErr
+}
+
+// CommitWith calls commit, typically the Commit method of a *sql.Tx, and applies the
+// queued writes if it succeeds or discards them if it fails, returning its error.
+func (b *TxBuffer) CommitWith(ctx context.Context, commit func() error) error {
+	if err := commit(); err != nil {
+		b.Rollback()
+		return err
+	}
+	return b.Commit(ctx)
+}
+
+// Rollback discards the queued writes; call it when the transaction rolls back.
+// It is safe to defer, as it does nothing once the buffer is finished.
+func (b *TxBuffer) Rollback() {
+	b.finish()
+}
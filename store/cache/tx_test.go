@@ -0,0 +1,117 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// txStore is an in-memory store whose writes only become visible once their
+// transaction commits.
+type txStore struct {
+	rows map[string]string
+}
+
+type storeTx struct {
+	store   *txStore
+	pending map[string]string
+	fail    bool
+}
+
+func (s *txStore) begin() *storeTx {
+	return &storeTx{store: s, pending: make(map[string]string)}
+}
+
+func (tx *storeTx) update(key, value string) {
+	tx.pending[key] = value
+}
+
+func (tx *storeTx) commit() error {
+	if tx.fail {
+		return errors.New("serialization failure")
+	}
+	for key, value := range tx.pending {
+		tx.store.rows[key] = value
+	}
+	return nil
+}
+
+func TestTxBuffer(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+	store := &txStore{rows: map[string]string{"memo:1": "old", "memo:2": "old"}}
+	cache.Set(ctx, "memo:1", "old")
+	cache.SetWithTags(ctx, "memo:2", "old", "memo")
+
+	updateMemo := func(fail, rollback bool) error {
+		tx := store.begin()
+		buffer := cache.BeginTx()
+		defer buffer.Rollback()
+
+		tx.update("memo:1", "new")
+		buffer.Set("memo:1", "new")
+		buffer.InvalidateTag("memo")
+		if buffer.Len() != 2 {
+			t.Errorf("Expected 2 queued writes, got %d", buffer.Len())
+		}
+		if value, _ := cache.Get(ctx, "memo:1"); value != "old" {
+			t.Errorf("Expected the cache to keep the committed value during the transaction, got %v", value)
+		}
+		if rollback {
+			return errors.New("validation failed")
+		}
+		tx.fail = fail
+		return buffer.CommitWith(ctx, tx.commit)
+	}
+
+	// A rolled back transaction leaves the cache untouched.
+	if err := updateMemo(false, true); err == nil {
+		t.Fatalf("Expected the transaction to fail")
+	}
+	if err := updateMemo(true, false); err == nil {
+		t.Fatalf("Expected the commit to fail")
+	}
+	for _, key := range []string{"memo:1", "memo:2"} {
+		if value, _ := cache.Get(ctx, key); value != "old" || store.rows[key] != "old" {
+			t.Errorf("Expected %s to stay old after rollbacks, cache has %v, store has %v", key, value, store.rows[key])
+		}
+	}
+
+	// A committed transaction updates the cache.
+	if err := updateMemo(false, false); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if value, _ := cache.Get(ctx, "memo:1"); value != "new" || store.rows["memo:1"] != "new" {
+		t.Errorf("Expected memo:1 to be new, cache has %v, store has %v", value, store.rows["memo:1"])
+	}
+	if _, ok := cache.Get(ctx, "memo:2"); ok {
+		t.Errorf("Expected the committed tag invalidation to remove memo:2")
+	}
+}
+
+func TestTxBufferFailedWriteDropsKey(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithMaxValueBytes(64))
+	defer cache.Close()
+	cache.Set(ctx, "memo:1", "old")
+
+	buffer := cache.BeginTx()
+	buffer.Set("memo:1", string(make([]byte, 128)))
+	buffer.Delete("memo:2")
+	if err := buffer.Commit(ctx); !errors.Is(err, ErrValueTooLarge) {
+		t.Fatalf("Expected ErrValueTooLarge, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected the failed write to drop the value from before the transaction")
+	}
+
+	// A finished buffer drops later writes.
+	buffer.Set("memo:1", "late")
+	if err := buffer.Commit(ctx); err != nil || buffer.Len() != 0 {
+		t.Errorf("Expected a finished buffer to do nothing, got %v with %d queued", err, buffer.Len())
+	}
+	if _, ok := cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected the write queued after Commit to be dropped")
+	}
+}
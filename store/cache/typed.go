@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Typed is a cache that only stores values of type V, so callers never need
+// a type assertion to recover what they stored. It shares its storage,
+// eviction and expiry behavior with Cache.
+type Typed[V any] struct {
+	cache *Cache
+}
+
+// NewTyped creates a new typed memory cache with default configuration and the given options.
+func NewTyped[V any](opts ...Option) *Typed[V] {
+	return &Typed[V]{cache: NewDefault(opts...)}
+}
+
+// Set adds a value to the cache with the default TTL.
+func (t *Typed[V]) Set(ctx context.Context, key string, value V) error {
+	return t.cache.Set(ctx, key, value)
+}
+
+// SetWithTTL adds a value to the cache with a custom TTL.
+// A non-positive TTL stores the value without expiration.
+func (t *Typed[V]) SetWithTTL(ctx context.Context, key string, value V, ttl time.Duration) error {
+	return t.cache.SetWithTTL(ctx, key, value, ttl)
+}
+
+// Get retrieves a value from the cache.
+func (t *Typed[V]) Get(ctx context.Context, key string) (V, bool) {
+	var zero V
+	value, ok := t.cache.Get(ctx, key)
+	if !ok {
+		return zero, false
+	}
+	if value == nil {
+		// A stored nil is a hit, even for interface types that cannot be asserted from nil.
+		return zero, true
+	}
+	typed, ok := value.(V)
+	if !ok {
+		return zero, false
+	}
+	return typed, true
+}
+
+// Delete removes a value from the cache.
+func (t *Typed[V]) Delete(ctx context.Context, key string) error {
+	return t.cache.Delete(ctx, key)
+}
+
+// Clear removes all values from the cache.
+func (t *Typed[V]) Clear(ctx context.Context) error {
+	return t.cache.Clear(ctx)
+}
+
+// Size returns the number of live items in the cache.
+func (t *Typed[V]) Size() int64 {
+	return t.cache.Size()
+}
+
+// Stats returns a snapshot of the cache counters.
+func (t *Typed[V]) Stats() Stats {
+	return t.cache.Stats()
+}
+
+// Close stops the cache cleanup goroutine.
+func (t *Typed[V]) Close() error {
+	return t.cache.Close()
+}
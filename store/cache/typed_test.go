@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"testing"
+)
+
+type testMemo struct {
+	ID      int32
+	Content string
+}
+
+func TestTypedCachePointer(t *testing.T) {
+	ctx := context.Background()
+	cache := NewTyped[*testMemo]()
+	defer cache.Close()
+
+	cache.Set(ctx, "memo:1", &testMemo{ID: 1, Content: "hello"})
+
+	memo, ok := cache.Get(ctx, "memo:1")
+	if !ok {
+		t.Fatalf("Key 'memo:1' should be in the cache")
+	}
+	if memo.ID != 1 || memo.Content != "hello" {
+		t.Errorf("Unexpected memo %+v", memo)
+	}
+
+	memo, ok = cache.Get(ctx, "memo:2")
+	if ok || memo != nil {
+		t.Errorf("Expected miss with nil memo, got %+v, exists: %v", memo, ok)
+	}
+}
+
+func TestTypedCacheString(t *testing.T) {
+	ctx := context.Background()
+	cache := NewTyped[string]()
+	defer cache.Close()
+
+	cache.Set(ctx, "greeting", "hello")
+	if val, ok := cache.Get(ctx, "greeting"); !ok || val != "hello" {
+		t.Errorf("Expected 'hello', got %q, exists: %v", val, ok)
+	}
+
+	cache.Delete(ctx, "greeting")
+	if val, ok := cache.Get(ctx, "greeting"); ok || val != "" {
+		t.Errorf("Expected miss with zero value, got %q, exists: %v", val, ok)
+	}
+	if cache.Size() != 0 {
+		t.Errorf("Expected size 0, got %d", cache.Size())
+	}
+}
+
+func TestTypedCacheNil(t *testing.T) {
+	ctx := context.Background()
+	cache := NewTyped[error]()
+	defer cache.Close()
+
+	cache.Set(ctx, "lookup", nil)
+	if err, ok := cache.Get(ctx, "lookup"); !ok || err != nil {
+		t.Errorf("Expected a stored nil to be a hit, got %v, exists: %v", err, ok)
+	}
+	if _, ok := cache.Get(ctx, "missing"); ok {
+		t.Errorf("Expected a miss for a missing key")
+	}
+}
@@ -0,0 +1,186 @@
+package cache
+
+import (
+	"slices"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Verify checks the internal bookkeeping of the cache against its contents and returns
+// an error wrapping ErrInconsistent that describes the first discrepancy it finds, or
+// nil if there is none. It checks the item and byte counters, the LRU lists, the tag
+// index and tag counts, the tenant usage and the expiry heaps. It is meant for tests
+// and debugging: it only runs with WithDebugChecks, returning ErrDebugChecksDisabled
+// otherwise, and holds every shard lock while it walks the whole cache.
+func (c *Cache) Verify() error {
+	if !c.config.DebugChecks {
+		return ErrDebugChecksDisabled
+	}
+
+	// Counters change under the lock of the shard being written, so holding every lock
+	// at once, in the same order as clear, gives a consistent view.
+	for _, s := range c.shards {
+		s.mu.Lock()
+	}
+	defer func() {
+		for _, s := range c.shards {
+			s.mu.Unlock()
+		}
+	}()
+
+	var items, bytes int64
+	tagKeys := make(map[string]int)
+	tenants := make(map[string]int64)
+	for i, s := range c.shards {
+		if err := c.verifyShardLocked(i, s); err != nil {
+			return err
+		}
+		for _, itm := range s.items {
+			items++
+			bytes += itm.size
+			for _, tag := range uniqueTagsNotIn(itm.tags, nil) {
+				tagKeys[tag]++
+			}
+			if c.tenants != nil {
+				tenants[itm.tenant] += c.tenantCost(itm)
+			}
+		}
+	}
+
+	if n := atomic.LoadInt64(&c.itemCount); n != items {
+		return errors.Wrapf(ErrInconsistent, "item count is %d, but the shards hold %d items", n, items)
+	}
+	if n := atomic.LoadInt64(&c.bytes); n != bytes {
+		return errors.Wrapf(ErrInconsistent, "byte count is %d, but the items add up to %d bytes", n, bytes)
+	}
+	if c.tagKeys != nil {
+		if err := c.verifyTagCounts(tagKeys); err != nil {
+			return err
+		}
+	}
+	if c.tenants != nil {
+		if err := c.verifyTenants(tenants); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyShardLocked checks the LRU list, tag index, volatile count and expiry heap of
+// shard i against its items. The caller must hold s.mu.
+func (c *Cache) verifyShardLocked(i int, s *shard) error {
+	linked := 0
+	var prev *item
+	for itm := s.lru.head; itm != nil; itm = itm.next {
+		linked++
+		if linked > len(s.items) {
+			return errors.Wrapf(ErrInconsistent, "shard %d: LRU list is longer than the %d items or has a cycle", i, len(s.items))
+		}
+		if itm.prev != prev {
+			return errors.Wrapf(ErrInconsistent, "shard %d: key %q is not linked back to its predecessor in the LRU list", i, itm.key)
+		}
+		if s.items[itm.key] != itm {
+			return errors.Wrapf(ErrInconsistent, "shard %d: LRU list holds key %q, which is not stored", i, itm.key)
+		}
+		prev = itm
+	}
+	if s.lru.tail != prev {
+		return errors.Wrapf(ErrInconsistent, "shard %d: LRU tail is not the last item of the list", i)
+	}
+	if linked != len(s.items) || s.lru.len != len(s.items) {
+		return errors.Wrapf(ErrInconsistent, "shard %d: LRU list links %d items and has length %d, but the shard holds %d",
+			i, linked, s.lru.len, len(s.items))
+	}
+
+	volatile := 0
+	for key, itm := range s.items {
+		if itm.key != key {
+			return errors.Wrapf(ErrInconsistent, "shard %d: key %q maps to the item of key %q", i, key, itm.key)
+		}
+		if c.shardFor(key) != s {
+			return errors.Wrapf(ErrInconsistent, "shard %d: key %q belongs to another shard", i, key)
+		}
+		if itm.volatile() {
+			volatile++
+		}
+		for _, tag := range itm.tags {
+			if _, ok := s.tags[tag][key]; !ok {
+				return errors.Wrapf(ErrInconsistent, "shard %d: key %q carries tag %q but is missing from its index", i, key, tag)
+			}
+		}
+		if s.expiry != nil && itm.expiration.IsZero() != (itm.heapPos == 0) {
+			return errors.Wrapf(ErrInconsistent, "shard %d: key %q with expiration %v has expiry heap position %d",
+				i, key, itm.expiration, itm.heapPos)
+		}
+	}
+	if volatile != s.volatile {
+		return errors.Wrapf(ErrInconsistent, "shard %d: volatile count is %d, but %d items can expire", i, s.volatile, volatile)
+	}
+
+	for tag, keys := range s.tags {
+		if len(keys) == 0 {
+			return errors.Wrapf(ErrInconsistent, "shard %d: tag %q is indexed without keys", i, tag)
+		}
+		for key := range keys {
+			itm, ok := s.items[key]
+			if !ok {
+				return errors.Wrapf(ErrInconsistent, "shard %d: tag %q indexes key %q, which is not stored", i, tag, key)
+			}
+			if !slices.Contains(itm.tags, tag) {
+				return errors.Wrapf(ErrInconsistent, "shard %d: tag %q indexes key %q, which does not carry it", i, tag, key)
+			}
+		}
+	}
+
+	if s.expiry == nil {
+		return nil
+	}
+	h := *s.expiry
+	for j, itm := range h {
+		if itm.heapPos != j+1 {
+			return errors.Wrapf(ErrInconsistent, "shard %d: key %q is at expiry heap index %d but records position %d", i, itm.key, j, itm.heapPos)
+		}
+		if s.items[itm.key] != itm {
+			return errors.Wrapf(ErrInconsistent, "shard %d: expiry heap holds key %q, which is not stored", i, itm.key)
+		}
+		if j > 0 && h.Less(j, (j-1)/2) {
+			return errors.Wrapf(ErrInconsistent, "shard %d: key %q dies before its parent in the expiry heap", i, itm.key)
+		}
+	}
+	return nil
+}
+
+// verifyTagCounts checks the counts of MaxKeysPerTag against the keys carrying each tag.
+func (c *Cache) verifyTagCounts(want map[string]int) error {
+	c.tagKeys.mu.Lock()
+	defer c.tagKeys.mu.Unlock()
+	for tag, n := range c.tagKeys.keys {
+		if want[tag] != n {
+			return errors.Wrapf(ErrInconsistent, "tag %q is counted for %d keys, but %d carry it", tag, n, want[tag])
+		}
+	}
+	for tag, n := range want {
+		if _, ok := c.tagKeys.keys[tag]; !ok {
+			return errors.Wrapf(ErrInconsistent, "tag %q is not counted, but %d keys carry it", tag, n)
+		}
+	}
+	return nil
+}
+
+// verifyTenants checks the usage of fair eviction against the items of each tenant.
+func (c *Cache) verifyTenants(want map[string]int64) error {
+	c.tenants.mu.Lock()
+	defer c.tenants.mu.Unlock()
+	for tenant, usage := range want {
+		if usage > 0 && c.tenants.usage[tenant] != usage {
+			return errors.Wrapf(ErrInconsistent, "tenant %q is charged %d, but its items add up to %d", tenant, c.tenants.usage[tenant], usage)
+		}
+	}
+	for tenant, usage := range c.tenants.usage {
+		if want[tenant] != usage {
+			return errors.Wrapf(ErrInconsistent, "tenant %q is charged %d, but its items add up to %d", tenant, usage, want[tenant])
+		}
+	}
+	return nil
+}
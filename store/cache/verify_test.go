@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheVerify(t *testing.T) {
+	ctx := context.Background()
+
+	plain := NewDefault()
+	defer plain.Close()
+	if err := plain.Verify(); !errors.Is(err, ErrDebugChecksDisabled) {
+		t.Fatalf("Expected ErrDebugChecksDisabled without debug checks, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(c *Cache)
+		want    string
+	}{
+		{"item count", func(c *Cache) { atomic.AddInt64(&c.itemCount, 1) }, "item count"},
+		{"byte count", func(c *Cache) { atomic.AddInt64(&c.bytes, -1) }, "byte count"},
+		{"LRU list", func(c *Cache) {
+			s := c.shardFor("memo:1")
+			s.lru.remove(s.items["memo:1"])
+		}, "LRU list"},
+		{"dangling tag", func(c *Cache) {
+			s := c.shardFor("memo:1")
+			s.tags["workspace"]["missing"] = struct{}{}
+		}, "not stored"},
+		{"expiry heap", func(c *Cache) {
+			s := c.shardFor("memo:1")
+			s.untrackExpiry(s.items["memo:1"])
+		}, "expiry heap"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewDefault(WithDebugChecks(true), WithShards(1), WithSweepStrategy(SweepExpiryHeap), WithTagLimits(10, 0))
+			defer cache.Close()
+			for i := 0; i < 10; i++ {
+				cache.SetWithTags(ctx, fmt.Sprintf("memo:%d", i), i, "workspace")
+			}
+			if err := cache.Verify(); err != nil {
+				t.Fatalf("Expected a consistent cache, got %v", err)
+			}
+
+			tt.corrupt(cache)
+			err := cache.Verify()
+			if !errors.Is(err, ErrInconsistent) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an inconsistency about %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestCacheVerifyUnderChurn(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+	ctx := context.Background()
+	cache := New(Config{MaxItems: 200, CleanupInterval: time.Millisecond},
+		WithDebugChecks(true),
+		WithSweepStrategy(SweepExpiryHeap),
+		WithTagLimits(1000, 4),
+		WithFairEviction(func(key string) string { return key[:2] }, nil),
+	)
+	defer cache.Close()
+
+	const workers, ops = 8, 3000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewPCG(uint64(w), 0))
+			for i := 0; i < ops; i++ {
+				key := fmt.Sprintf("t%d:%d", r.IntN(4), r.IntN(500))
+				tag := fmt.Sprintf("tag%d", r.IntN(8))
+				switch r.IntN(10) {
+				case 0:
+					cache.SetWithTTL(ctx, key, i, time.Duration(r.IntN(3))*time.Millisecond)
+				case 1:
+					cache.SetWithTags(ctx, key, i, tag)
+				case 2:
+					cache.Delete(ctx, key)
+				case 3:
+					cache.InvalidateTag(ctx, tag)
+				case 4:
+					cache.SetWithGrace(ctx, key, i, time.Millisecond, time.Millisecond)
+				case 5:
+					cache.Touch(ctx, key, time.Duration(r.IntN(3))*time.Millisecond)
+				case 6:
+					cache.SetNotFound(ctx, key, time.Millisecond)
+				case 7:
+					cache.DeletePrefix(ctx, key[:3])
+				default:
+					cache.Set(ctx, key, i)
+					cache.Get(ctx, key)
+				}
+			}
+		}(w)
+	}
+
+	done := make(chan struct{})
+	verified := make(chan error, 1)
+	go func() {
+		for {
+			if err := cache.Verify(); err != nil {
+				verified <- err
+				return
+			}
+			select {
+			case <-done:
+				verified <- nil
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	wg.Wait()
+	close(done)
+
+	if err := <-verified; err != nil {
+		t.Fatalf("Expected the cache to stay consistent under churn, got %v", err)
+	}
+	if err := cache.Verify(); err != nil {
+		t.Fatalf("Expected the cache to be consistent after churn, got %v", err)
+	}
+}
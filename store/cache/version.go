@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+)
+
+// SetWithVersion adds a value with the default TTL that carries version, such as the
+// update timestamp or revision of a memo, so that GetIfNewerThan can skip sending it
+// to a client that holds that version already. Versions must increase with every
+// update of a key: a write carrying a lower version than the live value is ignored,
+// so that a delayed writer cannot roll the entry back. Any other write stores the key
+// unversioned, as version zero.
+// If ctx is already done, the cache is left unchanged and ctx.Err() is returned.
+func (c *Cache) SetWithVersion(ctx context.Context, key string, value any, version uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := c.checkOpen(); err != nil {
+		return err
+	}
+	if skip, err := c.checkWritable(); skip {
+		return err
+	}
+	if err := c.validateKey(key); err != nil {
+		return err
+	}
+	itm := c.newItem(key, value, c.expiresAt(c.config.DefaultTTL))
+	if err := c.checkValueSize(itm); err != nil {
+		return err
+	}
+	itm.version = version
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	if existing, ok := s.items[key]; ok && existing.live(c.now()) && existing.version > version {
+		s.mu.Unlock()
+		return nil
+	}
+	evicted := c.setLocked(s, itm, nil)
+	s.mu.Unlock()
+
+	evicted = c.evictOverflow(s, evicted)
+	c.notifyEvicted(evicted)
+	return nil
+}
+
+// GetIfNewerThan retrieves a value like Get, but reports a hit only if the value was
+// stored by SetWithVersion with a version greater than version, so that a client that
+// is up to date is not sent the same data again. A value that is not newer still counts
+// as a hit in the statistics, since the cache held it; only a missing key calls OnMiss.
+// Unversioned values are never newer.
+func (c *Cache) GetIfNewerThan(ctx context.Context, key string, version uint64) (any, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+
+	s := c.shardFor(key)
+	s.mu.Lock()
+	value, ok, evicted := c.getLocked(s, key, c.now(), nil)
+	newer := ok && s.items[key].version > version
+	s.mu.Unlock()
+
+	c.notifyEvicted(evicted)
+	if !ok {
+		c.notifyMiss(key)
+	}
+	if !newer {
+		return nil, false
+	}
+	return c.readValue(value), true
+}
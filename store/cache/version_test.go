@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestCacheGetIfNewerThan(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault()
+	defer cache.Close()
+
+	if _, ok := cache.GetIfNewerThan(ctx, "memo", 0); ok {
+		t.Fatalf("Expected a miss for an absent key")
+	}
+
+	if err := cache.SetWithVersion(ctx, "memo", "v5", 5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, ok := cache.GetIfNewerThan(ctx, "memo", 4); !ok || value != "v5" {
+		t.Fatalf("Expected the newer value, got %v, %v", value, ok)
+	}
+	for _, version := range []uint64{5, 6} {
+		if value, ok := cache.GetIfNewerThan(ctx, "memo", version); ok {
+			t.Fatalf("Expected a miss against version %d, got %v", version, value)
+		}
+	}
+
+	// An older write does not roll the entry back; an equal one replaces it.
+	if err := cache.SetWithVersion(ctx, "memo", "v3", 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, _ := cache.Get(ctx, "memo"); value != "v5" {
+		t.Fatalf("Expected the older write to be ignored, got %v", value)
+	}
+	if err := cache.SetWithVersion(ctx, "memo", "v5 again", 5); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, _ := cache.Get(ctx, "memo"); value != "v5 again" {
+		t.Fatalf("Expected an equal version to overwrite, got %v", value)
+	}
+
+	// A plain write leaves the entry unversioned, so it is never newer.
+	cache.Set(ctx, "memo", "plain")
+	if value, ok := cache.GetIfNewerThan(ctx, "memo", 0); ok {
+		t.Fatalf("Expected an unversioned value to never be newer, got %v", value)
+	}
+}
+
+func TestCacheVersionSnapshot(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	cache := NewDefault()
+	cache.SetWithVersion(ctx, "memo", "v2", 2)
+	if err := cache.SaveSnapshot(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cache.Close()
+
+	restored := NewDefault()
+	defer restored.Close()
+	if err := restored.LoadSnapshot(&buf); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if value, ok := restored.GetIfNewerThan(ctx, "memo", 1); !ok || value != "v2" {
+		t.Fatalf("Expected the version to survive the snapshot, got %v, %v", value, ok)
+	}
+	if _, ok := restored.GetIfNewerThan(ctx, "memo", 2); ok {
+		t.Fatalf("Expected a miss against the restored version")
+	}
+}
@@ -0,0 +1,25 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Warm runs the Warmup function configured with WithWarmup, if any, and returns its
+// error so startup can decide whether to proceed without a primed cache. Constructors
+// do not call it, since they cannot report an error. Keys stored before a failure stay
+// in the cache. If ctx is already done, ctx.Err() is returned without running Warmup.
+func (c *Cache) Warm(ctx context.Context) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c.config.Warmup == nil {
+		return nil
+	}
+	defer c.recoverPanic("warmup", &err)
+	if err := c.config.Warmup(ctx, c); err != nil {
+		return errors.Wrap(err, "cache warmup")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCacheWarm(t *testing.T) {
+	ctx := context.Background()
+	cache := NewDefault(WithWarmup(func(ctx context.Context, c *Cache) error {
+		return c.SetMulti(ctx, map[string]any{
+			"workspace:setting": "general",
+			"user:current":      "steven",
+		})
+	}))
+	defer cache.Close()
+
+	if err := cache.Warm(ctx); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	for _, key := range []string{"workspace:setting", "user:current"} {
+		if _, ok := cache.Get(ctx, key); !ok {
+			t.Errorf("Expected key '%s' to be preloaded", key)
+		}
+	}
+
+	// Without a warmup function, Warm does nothing.
+	plain := NewDefault()
+	defer plain.Close()
+	if err := plain.Warm(ctx); err != nil {
+		t.Errorf("Expected no error without a warmup function, got %v", err)
+	}
+}
+
+func TestCacheWarmError(t *testing.T) {
+	ctx := context.Background()
+	warmErr := errors.New("database unavailable")
+	cache := NewDefault(WithWarmup(func(ctx context.Context, c *Cache) error {
+		c.Set(ctx, "partial", 1)
+		return warmErr
+	}))
+	defer cache.Close()
+
+	if err := cache.Warm(ctx); !errors.Is(err, warmErr) {
+		t.Errorf("Expected the warmup error, got %v", err)
+	}
+	if _, ok := cache.Get(ctx, "partial"); !ok {
+		t.Errorf("Keys stored before the failure should stay cached")
+	}
+}
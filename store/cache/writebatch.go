@@ -0,0 +1,350 @@
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// WriteBatcher is a Backend that buffers writes to a network backend, such as a
+// RedisCache, and sends them as batches through PutMulti and RemoveMulti, so that
+// warming a cache costs a few round trips rather than one per key. A batch is sent
+// once its first write is MaxDelay old or it holds MaxBatch keys, whichever comes
+// first; a key written twice in that time is sent once, with its latest write.
+//
+// Reads see buffered writes before asking the backend. A buffered value is returned
+// as it was written, whereas the backend may return it decoded by its Codec, such as
+// a map in place of a struct; a buffered write whose TTL has passed reads as a miss.
+// Writes never fail on their own, as they are only buffered: a batch the backend
+// rejects is logged and dropped, which costs at most a miss later. Close sends the
+// buffered writes before closing the backend, so none are lost.
+type WriteBatcher struct {
+	backend  Backend
+	maxDelay time.Duration
+	maxBatch int
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]batchedWrite
+	// inflight is the batch being sent, which reads still serve until it is written.
+	inflight map[string]batchedWrite
+	closed   bool
+	// armed reports whether timer runs for the batch in pending.
+	armed bool
+
+	// flushMu lets a single batch be sent at a time, so that a key written in two
+	// batches ends up with the value of the later one.
+	flushMu sync.Mutex
+	timer   *time.Timer
+	kick    chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+	once    sync.Once
+}
+
+// batchedWrite is a buffered Put or, if remove is set, Remove.
+type batchedWrite struct {
+	value any
+	ttl   time.Duration
+	// expires is when a Put with a TTL stops being served, zero if it has none.
+	expires time.Time
+	remove  bool
+}
+
+// newBatchedPut buffers storing value for ttl, starting now.
+func newBatchedPut(value any, ttl time.Duration) batchedWrite {
+	return batchedWrite{value: value, ttl: ttl, expires: expirationFor(time.Now(), ttl)}
+}
+
+// expired reports whether the TTL of a buffered Put has passed by now.
+func (w batchedWrite) expired(now time.Time) bool {
+	return !w.expires.IsZero() && !now.Before(w.expires)
+}
+
+// WriteBatchConfig contains options for configuring a WriteBatcher.
+type WriteBatchConfig struct {
+	// MaxDelay is how long the first write of a batch waits for more before the batch
+	// is sent. Zero means 10 milliseconds.
+	MaxDelay time.Duration
+
+	// MaxBatch is the number of keys that makes a batch be sent right away.
+	// Zero means 100.
+	MaxBatch int
+
+	// Logger receives warnings for the batches the backend rejects. Nil discards them.
+	Logger *slog.Logger
+}
+
+var _ Backend = (*WriteBatcher)(nil)
+
+// NewWriteBatcher wraps backend to batch its writes.
+func NewWriteBatcher(backend Backend, config WriteBatchConfig) *WriteBatcher {
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = 10 * time.Millisecond
+	}
+	if config.MaxBatch <= 0 {
+		config.MaxBatch = 100
+	}
+	if config.Logger == nil {
+		config.Logger = slog.New(slog.DiscardHandler)
+	}
+	b := &WriteBatcher{
+		backend:  backend,
+		maxDelay: config.MaxDelay,
+		maxBatch: config.MaxBatch,
+		logger:   config.Logger,
+		pending:  make(map[string]batchedWrite),
+		kick:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	b.timer = time.AfterFunc(b.maxDelay, b.signal)
+	b.timer.Stop()
+	b.wg.Add(1)
+	go b.flushLoop()
+	return b
+}
+
+// Fetch retrieves a value, from the buffered writes if the key has one.
+func (b *WriteBatcher) Fetch(ctx context.Context, key string) (any, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, false, err
+	}
+	if w, ok := b.buffered(key); ok {
+		if w.remove || w.expired(time.Now()) {
+			return nil, false, nil
+		}
+		return w.value, true, nil
+	}
+	return b.backend.Fetch(ctx, key)
+}
+
+// Put buffers storing a value.
+func (b *WriteBatcher) Put(ctx context.Context, key string, value any, ttl time.Duration) error {
+	return b.buffer(ctx, func() {
+		b.pending[key] = newBatchedPut(value, ttl)
+	})
+}
+
+// Remove buffers deleting a value.
+func (b *WriteBatcher) Remove(ctx context.Context, key string) error {
+	return b.buffer(ctx, func() {
+		b.pending[key] = batchedWrite{remove: true}
+	})
+}
+
+// FetchMulti retrieves several values, asking the backend only for the keys without
+// a buffered write.
+func (b *WriteBatcher) FetchMulti(ctx context.Context, keys []string) (map[string]any, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	result := make(map[string]any, len(keys))
+	var rest []string
+	now := time.Now()
+	for _, key := range keys {
+		w, ok := b.buffered(key)
+		switch {
+		case !ok:
+			rest = append(rest, key)
+		case !w.remove && !w.expired(now):
+			result[key] = w.value
+		}
+	}
+	if len(rest) == 0 {
+		return result, nil
+	}
+	values, err := b.backend.FetchMulti(ctx, rest)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		result[key] = value
+	}
+	return result, nil
+}
+
+// PutMulti buffers storing several values.
+func (b *WriteBatcher) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	return b.buffer(ctx, func() {
+		for key, value := range items {
+			b.pending[key] = newBatchedPut(value, ttl)
+		}
+	})
+}
+
+// RemoveMulti buffers deleting several values.
+func (b *WriteBatcher) RemoveMulti(ctx context.Context, keys []string) error {
+	return b.buffer(ctx, func() {
+		for _, key := range keys {
+			b.pending[key] = batchedWrite{remove: true}
+		}
+	})
+}
+
+// Flush sends the buffered writes now and returns the error of the batch that failed,
+// if any, instead of only logging it.
+func (b *WriteBatcher) Flush(ctx context.Context) error {
+	return b.flush(ctx)
+}
+
+// Close sends the buffered writes, then closes the backend. Writes after Close
+// return ErrClosed.
+func (b *WriteBatcher) Close() error {
+	var err error
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+		close(b.done)
+		b.wg.Wait()
+		b.timer.Stop()
+
+		flushErr := b.flush(context.Background())
+		closeErr := b.backend.Close()
+		if flushErr != nil {
+			err = flushErr
+		} else {
+			err = closeErr
+		}
+	})
+	return err
+}
+
+// buffered returns the latest buffered write of key, if any.
+func (b *WriteBatcher) buffered(key string) (batchedWrite, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if w, ok := b.pending[key]; ok {
+		return w, true
+	}
+	w, ok := b.inflight[key]
+	return w, ok
+}
+
+// buffer calls add to record writes in b.pending, then schedules sending them.
+func (b *WriteBatcher) buffer(ctx context.Context, add func()) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return ErrClosed
+	}
+	add()
+	full := b.scheduleLocked()
+	b.mu.Unlock()
+	if full {
+		b.signal()
+	}
+	return nil
+}
+
+// scheduleLocked starts the delay of a batch on its first write and reports whether
+// the batch is full. The caller must hold b.mu.
+func (b *WriteBatcher) scheduleLocked() (full bool) {
+	if len(b.pending) >= b.maxBatch {
+		return true
+	}
+	// Resetting the timer on every write would let a steady stream of writes postpone
+	// the batch forever, so only the first write of a batch starts it.
+	if !b.armed {
+		b.armed = true
+		b.timer.Reset(b.maxDelay)
+	}
+	return false
+}
+
+// signal asks the flush loop to send the buffered writes.
+func (b *WriteBatcher) signal() {
+	select {
+	case b.kick <- struct{}{}:
+	default: // A flush is pending already
+	}
+}
+
+func (b *WriteBatcher) flushLoop() {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.kick:
+			if err := b.flush(context.Background()); err != nil {
+				b.logger.Warn("failed to flush batched cache writes", "err", err)
+			}
+		case <-b.done:
+			return
+		}
+	}
+}
+
+// flush sends the buffered writes in batches of at most maxBatch keys, with a PutMulti
+// per TTL and a RemoveMulti in each. Every batch is attempted; the first error is
+// returned.
+func (b *WriteBatcher) flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	b.timer.Stop()
+	b.armed = false
+	batch := b.pending
+	if len(batch) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	b.pending = make(map[string]batchedWrite)
+	b.inflight = batch
+	b.mu.Unlock()
+
+	var firstErr error
+	keys := make([]string, 0, len(batch))
+	for key := range batch {
+		keys = append(keys, key)
+	}
+	for start := 0; start < len(keys); start += b.maxBatch {
+		end := min(start+b.maxBatch, len(keys))
+		if err := b.write(ctx, batch, keys[start:end]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	b.mu.Lock()
+	b.inflight = nil
+	b.mu.Unlock()
+	return firstErr
+}
+
+// write sends the writes of keys to the backend.
+func (b *WriteBatcher) write(ctx context.Context, batch map[string]batchedWrite, keys []string) error {
+	puts := make(map[time.Duration]map[string]any)
+	var removes []string
+	now := time.Now()
+	for _, key := range keys {
+		w := batch[key]
+		// A Put that expired while buffered still replaces what the backend held before.
+		if w.remove || w.expired(now) {
+			removes = append(removes, key)
+			continue
+		}
+		if puts[w.ttl] == nil {
+			puts[w.ttl] = make(map[string]any)
+		}
+		puts[w.ttl][key] = w.value
+	}
+
+	var firstErr error
+	for ttl, items := range puts {
+		if err := b.backend.PutMulti(ctx, items, ttl); err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "failed to write batch of %d keys", len(items))
+		}
+	}
+	if len(removes) > 0 {
+		if err := b.backend.RemoveMulti(ctx, removes); err != nil && firstErr == nil {
+			firstErr = errors.Wrapf(err, "failed to remove batch of %d keys", len(removes))
+		}
+	}
+	return firstErr
+}
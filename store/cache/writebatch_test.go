@@ -0,0 +1,214 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder is a Backend that records the size of every write it receives.
+type batchRecorder struct {
+	*Cache
+
+	mu      sync.Mutex
+	batches []int
+	closed  bool
+}
+
+func (r *batchRecorder) PutMulti(ctx context.Context, items map[string]any, ttl time.Duration) error {
+	r.mu.Lock()
+	r.batches = append(r.batches, len(items))
+	r.mu.Unlock()
+	return r.Cache.PutMulti(ctx, items, ttl)
+}
+
+func (r *batchRecorder) RemoveMulti(ctx context.Context, keys []string) error {
+	r.mu.Lock()
+	r.batches = append(r.batches, len(keys))
+	r.mu.Unlock()
+	return r.Cache.RemoveMulti(ctx, keys)
+}
+
+// Close keeps the cache open so that tests can check what reached it.
+func (r *batchRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	return nil
+}
+
+func (r *batchRecorder) recorded() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int(nil), r.batches...)
+}
+
+func TestWriteBatcher(t *testing.T) {
+	ctx := context.Background()
+	backend := &batchRecorder{Cache: NewDefault()}
+	defer backend.Cache.Close()
+	// The delay never elapses, so only full batches are sent before Close.
+	batcher := NewWriteBatcher(backend, WriteBatchConfig{MaxDelay: time.Hour, MaxBatch: 100})
+
+	for i := 0; i < 250; i++ {
+		if err := batcher.Put(ctx, fmt.Sprintf("memo:%d", i), i, 0); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	batcher.Remove(ctx, "memo:0")
+
+	// Reads see their own writes, whether sent or not.
+	for i := 1; i < 250; i++ {
+		key := fmt.Sprintf("memo:%d", i)
+		if value, ok, err := batcher.Fetch(ctx, key); err != nil || !ok || value != i {
+			t.Fatalf("Expected %s to be %d, got %v, %v, %v", key, i, value, ok, err)
+		}
+	}
+	if _, ok, _ := batcher.Fetch(ctx, "memo:0"); ok {
+		t.Errorf("Expected the buffered delete to hide memo:0")
+	}
+	values, err := batcher.FetchMulti(ctx, []string{"memo:0", "memo:1", "memo:249"})
+	if err != nil || len(values) != 2 || values["memo:249"] != 249 {
+		t.Errorf("Expected FetchMulti to see the buffered writes, got %v, %v", values, err)
+	}
+
+	if err := batcher.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !backend.closed {
+		t.Errorf("Expected Close to close the backend")
+	}
+	batches := backend.recorded()
+	sent := 0
+	for _, n := range batches {
+		if n > 100 {
+			t.Errorf("Expected batches of at most 100 keys, got %v", batches)
+		}
+		sent += n
+	}
+	if len(batches) > 4 || sent != 250 {
+		t.Errorf("Expected 250 writes in a few batches, got %v", batches)
+	}
+
+	// Close drained the buffer into the backend.
+	for i := 1; i < 250; i++ {
+		if value, ok := backend.Get(ctx, fmt.Sprintf("memo:%d", i)); !ok || value != i {
+			t.Fatalf("Expected memo:%d to reach the backend, got %v, %v", i, value, ok)
+		}
+	}
+	if _, ok := backend.Get(ctx, "memo:0"); ok {
+		t.Errorf("Expected the delete to reach the backend")
+	}
+	if err := batcher.Put(ctx, "late", 1, 0); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestWriteBatcherDelay(t *testing.T) {
+	ctx := context.Background()
+	backend := &batchRecorder{Cache: NewDefault()}
+	defer backend.Cache.Close()
+	batcher := NewWriteBatcher(backend, WriteBatchConfig{MaxDelay: 10 * time.Millisecond, MaxBatch: 100})
+	defer batcher.Close()
+
+	batcher.Put(ctx, "a", 1, 0)
+	batcher.Put(ctx, "b", 2, 0)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(backend.recorded()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the batch to be sent once the delay elapsed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if batches := backend.recorded(); len(batches) != 1 || batches[0] != 2 {
+		t.Errorf("Expected a single batch of 2 keys, got %v", batches)
+	}
+	if value, ok := backend.Get(ctx, "b"); !ok || value != 2 {
+		t.Errorf("Expected b to reach the backend, got %v, %v", value, ok)
+	}
+}
+
+func TestWriteBatcherLogsFailedBatches(t *testing.T) {
+	ctx := context.Background()
+	handler := &captureHandler{}
+	// A closed cache rejects every write with ErrClosed.
+	backend := NewDefault()
+	backend.Close()
+	batcher := NewWriteBatcher(backend, WriteBatchConfig{MaxDelay: time.Millisecond, Logger: slog.New(handler)})
+	defer batcher.Close()
+
+	if err := batcher.Put(ctx, "memo:1", "value", 0); err != nil {
+		t.Fatalf("Expected the write to be buffered, got %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(handler.messages(slog.LevelWarn)) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the rejected batch to be logged through the configured logger")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWriteBatcherExpiredWrites(t *testing.T) {
+	ctx := context.Background()
+	backend := &batchRecorder{Cache: NewDefault()}
+	defer backend.Cache.Close()
+	batcher := NewWriteBatcher(backend, WriteBatchConfig{MaxDelay: time.Hour})
+
+	backend.Cache.Set(ctx, "memo:1", "old")
+	batcher.Put(ctx, "memo:1", "new", time.Millisecond)
+	batcher.Put(ctx, "memo:2", "new", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	// A buffered write past its TTL is a miss, and does not fall back to the backend.
+	if value, ok, err := batcher.Fetch(ctx, "memo:1"); err != nil || ok {
+		t.Errorf("Expected an expired buffered write to miss, got %v, %v, err: %v", value, ok, err)
+	}
+	values, err := batcher.FetchMulti(ctx, []string{"memo:1", "memo:2"})
+	if err != nil || len(values) != 0 {
+		t.Errorf("Expected expired buffered writes to miss, got %v, err: %v", values, err)
+	}
+
+	if err := batcher.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if value, ok := backend.Cache.Get(ctx, "memo:1"); ok {
+		t.Errorf("Expected the expired write to remove the old value, got %v", value)
+	}
+}
+
+func TestTieredCacheWriteBatching(t *testing.T) {
+	ctx := context.Background()
+	backend := &batchRecorder{Cache: NewDefault()}
+	defer backend.Cache.Close()
+	tiered := NewTiered(NewDefault(), backend, TieredConfig{
+		WriteBatching: &WriteBatchConfig{MaxDelay: time.Hour, MaxBatch: 10},
+	})
+
+	for i := 0; i < 25; i++ {
+		if err := tiered.Put(ctx, fmt.Sprintf("memo:%d", i), i, 0); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := tiered.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	batches := backend.recorded()
+	sent := 0
+	for _, n := range batches {
+		if n > 10 {
+			t.Errorf("Expected batches of at most 10 keys, got %v", batches)
+		}
+		sent += n
+	}
+	if sent != 25 {
+		t.Errorf("Expected 25 writes to reach L2 in batches, got %v", batches)
+	}
+	if !backend.closed {
+		t.Errorf("Expected Close to close L2")
+	}
+}
@@ -107,33 +107,67 @@ func (s *Store) CreateMemo(ctx context.Context, create *Memo) (*Memo, error) {
 	if !base.UIDMatcher.MatchString(create.UID) {
 		return nil, errors.New("invalid uid")
 	}
-	return s.driver.CreateMemo(ctx, create)
+	memo, err := s.driver.CreateMemo(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+	s.tagCache.MemoCreated(ctx, memo)
+	return memo, nil
 }
 
 func (s *Store) ListMemos(ctx context.Context, find *FindMemo) ([]*Memo, error) {
 	return s.driver.ListMemos(ctx, find)
 }
 
+// GetMemo returns the first memo matching find. A lookup by ID alone is served from
+// the memo cache, which hands out copies, so callers may modify the memo.
 func (s *Store) GetMemo(ctx context.Context, find *FindMemo) (*Memo, error) {
-	list, err := s.ListMemos(ctx, find)
-	if err != nil {
-		return nil, err
-	}
-	if len(list) == 0 {
-		return nil, nil
-	}
-
-	memo := list[0]
-	return memo, nil
+	return s.memos.GetMemo(ctx, find)
 }
 
 func (s *Store) UpdateMemo(ctx context.Context, update *UpdateMemo) error {
 	if update.UID != nil && !base.UIDMatcher.MatchString(*update.UID) {
 		return errors.New("invalid uid")
 	}
-	return s.driver.UpdateMemo(ctx, update)
+	// Only the payload and row status of a memo decide the tags it is counted for.
+	var old *Memo
+	if update.Payload != nil || update.RowStatus != nil {
+		var err error
+		if old, err = s.GetMemo(ctx, &FindMemo{ID: &update.ID, ExcludeContent: true}); err != nil {
+			return err
+		}
+	}
+	if err := s.memos.UpdateMemo(ctx, update); err != nil {
+		return err
+	}
+	if old != nil {
+		updated := *old
+		if update.RowStatus != nil {
+			updated.RowStatus = *update.RowStatus
+		}
+		if update.Payload != nil {
+			updated.Payload = update.Payload
+		}
+		s.tagCache.MemoUpdated(ctx, old, &updated)
+	}
+	return nil
 }
 
 func (s *Store) DeleteMemo(ctx context.Context, delete *DeleteMemo) error {
-	return s.driver.DeleteMemo(ctx, delete)
+	memo, err := s.GetMemo(ctx, &FindMemo{ID: &delete.ID, ExcludeContent: true})
+	if err != nil {
+		return err
+	}
+	if err := s.memos.DeleteMemo(ctx, delete); err != nil {
+		return err
+	}
+	if memo != nil {
+		s.tagCache.MemoDeleted(ctx, memo)
+	}
+	return nil
+}
+
+// GetTagCount returns the number of memos of the user carrying each tag.
+func (s *Store) GetTagCount(ctx context.Context, userID int32) (map[string]int32, error) {
+	return s.tagCache.GetTagCount(ctx, userID)
 }
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/protobuf/proto"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store/cache"
+)
+
+// MemoStore is the subset of Store that CachedMemoStore wraps.
+type MemoStore interface {
+	GetMemo(ctx context.Context, find *FindMemo) (*Memo, error)
+	UpdateMemo(ctx context.Context, update *UpdateMemo) error
+	DeleteMemo(ctx context.Context, delete *DeleteMemo) error
+}
+
+// CachedMemoStore caches GetMemo lookups by ID in front of a MemoStore.
+// Updating or deleting a memo invalidates every cache entry tagged with
+// MemoCacheTag for it, including list caches that other callers tag the same way.
+// Every caller gets its own copy of a cached memo, so mutating it is safe.
+type CachedMemoStore struct {
+	MemoStore
+
+	cache *cache.Cache
+
+	// mu orders caching a memo against invalidating it. versions counts the changes
+	// seen per memo ID, and epoch those that may have changed any memo, so that a
+	// lookup racing with a change does not cache the memo it read before the change.
+	mu       sync.Mutex
+	versions map[int32]uint64
+	epoch    uint64
+}
+
+// NewCachedMemoStore creates a CachedMemoStore that keeps memos in memoCache.
+func NewCachedMemoStore(memoStore MemoStore, memoCache *cache.Cache) *CachedMemoStore {
+	return &CachedMemoStore{
+		MemoStore: memoStore,
+		cache:     memoCache,
+		versions:  make(map[int32]uint64),
+	}
+}
+
+// GetMemo returns the memo from the cache when find only selects it by ID,
+// and otherwise falls through to the underlying store, caching what it finds.
+func (s *CachedMemoStore) GetMemo(ctx context.Context, find *FindMemo) (*Memo, error) {
+	cacheable := isMemoIDLookup(find)
+	if cacheable {
+		if cache, ok := s.cache.Get(ctx, getMemoCacheKey(*find.ID)); ok {
+			memo, ok := cache.(*Memo)
+			if ok {
+				return cloneMemo(memo), nil
+			}
+		}
+	}
+
+	var version, epoch uint64
+	if cacheable {
+		s.mu.Lock()
+		version, epoch = s.versions[*find.ID], s.epoch
+		s.mu.Unlock()
+	}
+
+	memo, err := s.MemoStore.GetMemo(ctx, find)
+	if err != nil {
+		return nil, err
+	}
+	if cacheable && memo != nil {
+		s.mu.Lock()
+		if s.versions[memo.ID] == version && s.epoch == epoch {
+			s.cache.SetWithTags(ctx, getMemoCacheKey(memo.ID), cloneMemo(memo), MemoCacheTag(memo.ID))
+		}
+		s.mu.Unlock()
+	}
+	return memo, nil
+}
+
+func (s *CachedMemoStore) UpdateMemo(ctx context.Context, update *UpdateMemo) error {
+	if err := s.MemoStore.UpdateMemo(ctx, update); err != nil {
+		return err
+	}
+	s.invalidate(ctx, update.ID)
+	return nil
+}
+
+func (s *CachedMemoStore) DeleteMemo(ctx context.Context, delete *DeleteMemo) error {
+	if err := s.MemoStore.DeleteMemo(ctx, delete); err != nil {
+		return err
+	}
+	s.invalidate(ctx, delete.ID)
+	return nil
+}
+
+// invalidate drops the cache entries of a memo that changed and makes lookups that
+// read it before the change skip caching what they read.
+func (s *CachedMemoStore) invalidate(ctx context.Context, id int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.versions[id]++
+	s.cache.InvalidateTag(ctx, MemoCacheTag(id))
+}
+
+// invalidateAll drops every cached memo, for changes that may affect any of them.
+func (s *CachedMemoStore) invalidateAll(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.epoch++
+	s.cache.Clear(ctx)
+}
+
+// driverMemoStore reads and writes the memos of a Store through its driver,
+// uncached. It is the MemoStore that the CachedMemoStore of the Store wraps.
+type driverMemoStore struct {
+	store *Store
+}
+
+func (s driverMemoStore) GetMemo(ctx context.Context, find *FindMemo) (*Memo, error) {
+	list, err := s.store.ListMemos(ctx, find)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	memo := list[0]
+	return memo, nil
+}
+
+func (s driverMemoStore) UpdateMemo(ctx context.Context, update *UpdateMemo) error {
+	return s.store.driver.UpdateMemo(ctx, update)
+}
+
+func (s driverMemoStore) DeleteMemo(ctx context.Context, delete *DeleteMemo) error {
+	return s.store.driver.DeleteMemo(ctx, delete)
+}
+
+// cloneMemo returns a copy of memo that shares no mutable state with it.
+func cloneMemo(memo *Memo) *Memo {
+	clone := *memo
+	if memo.Payload != nil {
+		clone.Payload = proto.Clone(memo.Payload).(*storepb.MemoPayload)
+	}
+	if memo.ParentID != nil {
+		parentID := *memo.ParentID
+		clone.ParentID = &parentID
+	}
+	return &clone
+}
+
+// isMemoIDLookup reports whether find selects a memo by ID alone, so that
+// its result is the same for every caller and can be cached by ID.
+func isMemoIDLookup(find *FindMemo) bool {
+	return find.ID != nil &&
+		find.UID == nil &&
+		find.RowStatus == nil &&
+		find.CreatorID == nil &&
+		len(find.VisibilityList) == 0 &&
+		!find.ExcludeContent &&
+		!find.ExcludeComments &&
+		len(find.Filters) == 0 &&
+		find.Limit == nil &&
+		find.Offset == nil
+}
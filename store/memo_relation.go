@@ -33,7 +33,13 @@ type DeleteMemoRelation struct {
 }
 
 func (s *Store) UpsertMemoRelation(ctx context.Context, create *MemoRelation) (*MemoRelation, error) {
-	return s.driver.UpsertMemoRelation(ctx, create)
+	relation, err := s.driver.UpsertMemoRelation(ctx, create)
+	if err != nil {
+		return nil, err
+	}
+	// A comment relation sets the ParentID of the memo.
+	s.memos.invalidate(ctx, create.MemoID)
+	return relation, nil
 }
 
 func (s *Store) ListMemoRelations(ctx context.Context, find *FindMemoRelation) ([]*MemoRelation, error) {
@@ -41,5 +47,16 @@ func (s *Store) ListMemoRelations(ctx context.Context, find *FindMemoRelation) (
 }
 
 func (s *Store) DeleteMemoRelation(ctx context.Context, delete *DeleteMemoRelation) error {
-	return s.driver.DeleteMemoRelation(ctx, delete)
+	if err := s.driver.DeleteMemoRelation(ctx, delete); err != nil {
+		return err
+	}
+	// Deleting a comment relation clears the ParentID of its memo, which is unknown
+	// unless the relations are selected by memo.
+	switch {
+	case delete.MemoID != nil:
+		s.memos.invalidate(ctx, *delete.MemoID)
+	case delete.Type == nil || *delete.Type == MemoRelationComment:
+		s.memos.invalidateAll(ctx)
+	}
+	return nil
 }
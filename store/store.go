@@ -19,6 +19,13 @@ type Store struct {
 	workspaceSettingCache *cache.Cache // cache for workspace settings
 	userCache             *cache.Cache // cache for users
 	userSettingCache      *cache.Cache // cache for user settings
+	tagCountCache         *cache.Cache // cache for the tag counts of tagCache
+	memoCache             *cache.Cache // cache for the memos of memos
+
+	// tagCache keeps the tag counts of each user up to date as memos change.
+	tagCache *TagCache
+	// memos caches memo lookups by ID in front of the driver.
+	memos *CachedMemoStore
 }
 
 // New creates a new instance of Store.
@@ -38,7 +45,11 @@ func New(driver Driver, profile *profile.Profile) *Store {
 		workspaceSettingCache: cache.New(cacheConfig),
 		userCache:             cache.New(cacheConfig),
 		userSettingCache:      cache.New(cacheConfig),
+		tagCountCache:         cache.New(cacheConfig),
+		memoCache:             cache.New(cacheConfig),
 	}
+	store.tagCache = NewTagCache(store, store.tagCountCache)
+	store.memos = NewCachedMemoStore(driverMemoStore{store}, store.memoCache)
 
 	return store
 }
@@ -52,6 +63,8 @@ func (s *Store) Close() error {
 	s.workspaceSettingCache.Close()
 	s.userCache.Close()
 	s.userSettingCache.Close()
+	s.tagCountCache.Close()
+	s.memoCache.Close()
 
 	return s.driver.Close()
 }
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"context"
+	"maps"
+	"sync"
+
+	"github.com/usememos/memos/store/cache"
+)
+
+// MemoLister is the subset of Store that TagCache recomputes tag counts from.
+type MemoLister interface {
+	ListMemos(ctx context.Context, find *FindMemo) ([]*Memo, error)
+}
+
+// TagCache caches the tag→count map of each user's memos, such as the sidebar tag
+// list, and keeps it up to date incrementally as memos are created, updated and
+// deleted instead of recounting every memo. A miss recomputes the counts from the
+// MemoLister, and so does the next read after an update that would leave a count
+// negative, which means the cached counts had drifted from the store.
+// Only memos in the Normal row status are counted.
+type TagCache struct {
+	lister MemoLister
+	cache  *cache.Cache
+
+	// mu serializes updates of the cached counts. versions counts the changes seen
+	// per user, so that a recompute racing with a change does not store stale counts.
+	mu       sync.Mutex
+	versions map[int32]uint64
+}
+
+// NewTagCache creates a TagCache that keeps tag counts in tagCache.
+func NewTagCache(lister MemoLister, tagCache *cache.Cache) *TagCache {
+	return &TagCache{
+		lister:   lister,
+		cache:    tagCache,
+		versions: make(map[int32]uint64),
+	}
+}
+
+// GetTagCount returns the number of memos of the user carrying each tag.
+// The returned map belongs to the caller.
+func (c *TagCache) GetTagCount(ctx context.Context, userID int32) (map[string]int32, error) {
+	if counts, ok := c.cache.Get(ctx, getTagCountCacheKey(userID)); ok {
+		if counts, ok := counts.(map[string]int32); ok {
+			return maps.Clone(counts), nil
+		}
+	}
+
+	c.mu.Lock()
+	version := c.versions[userID]
+	c.mu.Unlock()
+
+	normalStatus := Normal
+	memos, err := c.lister.ListMemos(ctx, &FindMemo{
+		CreatorID:      &userID,
+		RowStatus:      &normalStatus,
+		ExcludeContent: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int32)
+	for _, memo := range memos {
+		for _, tag := range memoTags(memo) {
+			counts[tag]++
+		}
+	}
+
+	c.mu.Lock()
+	if c.versions[userID] == version {
+		c.cache.Set(ctx, getTagCountCacheKey(userID), counts)
+	}
+	c.mu.Unlock()
+	return maps.Clone(counts), nil
+}
+
+// MemoCreated counts the tags of a memo that was just created.
+func (c *TagCache) MemoCreated(ctx context.Context, memo *Memo) {
+	c.MemoUpdated(ctx, nil, memo)
+}
+
+// MemoUpdated adjusts the counts for a memo that changed from old to updated,
+// including changes of its tags or row status. Either memo may be nil.
+func (c *TagCache) MemoUpdated(ctx context.Context, old, updated *Memo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old != nil {
+		c.applyLocked(ctx, old.CreatorID, memoTags(old), -1)
+	}
+	if updated != nil {
+		c.applyLocked(ctx, updated.CreatorID, memoTags(updated), 1)
+	}
+}
+
+// MemoDeleted uncounts the tags of a memo that was just deleted.
+func (c *TagCache) MemoDeleted(ctx context.Context, memo *Memo) {
+	c.MemoUpdated(ctx, memo, nil)
+}
+
+// applyLocked adds delta to the cached count of each tag of a user. Counts are
+// copied on write, since callers of GetTagCount may still hold the old map.
+// The caller must hold c.mu.
+func (c *TagCache) applyLocked(ctx context.Context, userID int32, tags []string, delta int32) {
+	c.versions[userID]++
+	if len(tags) == 0 {
+		return
+	}
+	key := getTagCountCacheKey(userID)
+	cached, ok := c.cache.Get(ctx, key)
+	if !ok {
+		return
+	}
+	counts, ok := cached.(map[string]int32)
+	if !ok {
+		c.cache.Delete(ctx, key)
+		return
+	}
+	counts = maps.Clone(counts)
+	for _, tag := range tags {
+		count := counts[tag] + delta
+		switch {
+		case count < 0:
+			// The cached counts drifted from the store; recompute on the next read.
+			c.cache.Delete(ctx, key)
+			return
+		case count == 0:
+			delete(counts, tag)
+		default:
+			counts[tag] = count
+		}
+	}
+	c.cache.Set(ctx, key, counts)
+}
+
+// memoTags returns the tags a memo contributes to the counts, counting each tag once.
+func memoTags(memo *Memo) []string {
+	if memo.RowStatus != Normal || memo.Payload == nil {
+		return nil
+	}
+	tags := make([]string, 0, len(memo.Payload.Tags))
+	seen := make(map[string]bool, len(memo.Payload.Tags))
+	for _, tag := range memo.Payload.Tags {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
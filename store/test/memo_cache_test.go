@@ -0,0 +1,245 @@
+package teststore
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/usememos/memos/store"
+	"github.com/usememos/memos/store/cache"
+)
+
+// memoStoreStub is an in-memory MemoStore that counts reads.
+type memoStoreStub struct {
+	mu    sync.Mutex
+	memos map[int32]*store.Memo
+	reads int
+	// afterRead, if set, runs once a read has copied the memo, before it returns.
+	afterRead func()
+}
+
+func (s *memoStoreStub) GetMemo(_ context.Context, find *store.FindMemo) (*store.Memo, error) {
+	s.mu.Lock()
+	s.reads++
+	memo, ok := s.memos[*find.ID]
+	var clone store.Memo
+	if ok {
+		clone = *memo
+	}
+	afterRead := s.afterRead
+	s.mu.Unlock()
+
+	if afterRead != nil {
+		afterRead()
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &clone, nil
+}
+
+func (s *memoStoreStub) UpdateMemo(_ context.Context, update *store.UpdateMemo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if update.Content != nil {
+		s.memos[update.ID].Content = *update.Content
+	}
+	return nil
+}
+
+func (s *memoStoreStub) DeleteMemo(_ context.Context, del *store.DeleteMemo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.memos, del.ID)
+	return nil
+}
+
+func TestCachedMemoStore(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoStoreStub{memos: map[int32]*store.Memo{7: {ID: 7, Content: "v1"}}}
+	memoCache := cache.NewDefault()
+	defer memoCache.Close()
+	cached := store.NewCachedMemoStore(stub, memoCache)
+
+	id := int32(7)
+	memo, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+	require.NoError(t, err)
+	require.Equal(t, "v1", memo.Content)
+	memo, err = cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+	require.NoError(t, err)
+	require.Equal(t, "v1", memo.Content)
+	require.Equal(t, 1, stub.reads)
+
+	// A list page tagged with the memo is invalidated along with it.
+	memoCache.SetWithTags(ctx, "memos:page:1", []*store.Memo{memo}, store.MemoCacheTag(id))
+
+	content := "v2"
+	require.NoError(t, cached.UpdateMemo(ctx, &store.UpdateMemo{ID: id, Content: &content}))
+	memo, err = cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+	require.NoError(t, err)
+	require.Equal(t, "v2", memo.Content)
+	require.Equal(t, 2, stub.reads)
+	_, ok := memoCache.Get(ctx, "memos:page:1")
+	require.False(t, ok)
+
+	require.NoError(t, cached.DeleteMemo(ctx, &store.DeleteMemo{ID: id}))
+	memo, err = cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+	require.NoError(t, err)
+	require.Nil(t, memo)
+	require.Equal(t, 3, stub.reads)
+}
+
+func TestCachedMemoStoreFilteredLookup(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoStoreStub{memos: map[int32]*store.Memo{7: {ID: 7, Content: "v1"}}}
+	memoCache := cache.NewDefault()
+	defer memoCache.Close()
+	cached := store.NewCachedMemoStore(stub, memoCache)
+
+	// Lookups narrowed by other fields always reach the underlying store.
+	id := int32(7)
+	for i := 0; i < 2; i++ {
+		_, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id, ExcludeContent: true})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, stub.reads)
+}
+
+func TestCachedMemoStoreReturnsCopies(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoStoreStub{memos: map[int32]*store.Memo{7: {ID: 7, Content: "v1"}}}
+	memoCache := cache.NewDefault()
+	defer memoCache.Close()
+	cached := store.NewCachedMemoStore(stub, memoCache)
+
+	id := int32(7)
+	for i := 0; i < 2; i++ {
+		memo, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+		require.NoError(t, err)
+		require.Equal(t, "v1", memo.Content)
+		memo.Content = "mutated by caller"
+	}
+	require.Equal(t, 1, stub.reads)
+}
+
+func TestCachedMemoStoreUpdateDuringRead(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoStoreStub{memos: map[int32]*store.Memo{7: {ID: 7, Content: "v1"}}}
+	memoCache := cache.NewDefault()
+	defer memoCache.Close()
+	cached := store.NewCachedMemoStore(stub, memoCache)
+	id := int32(7)
+
+	// The update lands after the read copied v1 but before it is cached.
+	read, update := make(chan struct{}), make(chan struct{})
+	stub.afterRead = func() {
+		close(read)
+		<-update
+	}
+	done := make(chan *store.Memo)
+	go func() {
+		memo, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+		if err != nil {
+			t.Errorf("GetMemo failed: %v", err)
+		}
+		done <- memo
+	}()
+	<-read
+	stub.mu.Lock()
+	stub.afterRead = nil
+	stub.mu.Unlock()
+	content := "v2"
+	require.NoError(t, cached.UpdateMemo(ctx, &store.UpdateMemo{ID: id, Content: &content}))
+	close(update)
+	require.Equal(t, "v1", (<-done).Content)
+
+	memo, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+	require.NoError(t, err)
+	require.Equal(t, "v2", memo.Content)
+}
+
+func TestCachedMemoStoreConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoStoreStub{memos: map[int32]*store.Memo{7: {ID: 7, Content: "0"}}}
+	memoCache := cache.NewDefault()
+	defer memoCache.Close()
+	cached := store.NewCachedMemoStore(stub, memoCache)
+	id := int32(7)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id}); err != nil {
+					t.Errorf("GetMemo failed: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 200; i++ {
+		content := strconv.Itoa(i)
+		require.NoError(t, cached.UpdateMemo(ctx, &store.UpdateMemo{ID: id, Content: &content}))
+		// Once an update returns, no read may serve the content from before it.
+		memo, err := cached.GetMemo(ctx, &store.FindMemo{ID: &id})
+		require.NoError(t, err)
+		require.Equal(t, content, memo.Content)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestStoreGetMemoCached(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	createMemo := func(uid string) *store.Memo {
+		memo, err := ts.CreateMemo(ctx, &store.Memo{UID: uid, CreatorID: user.ID, Content: uid, Visibility: store.Public})
+		require.NoError(t, err)
+		return memo
+	}
+	getMemo := func(id int32) *store.Memo {
+		memo, err := ts.GetMemo(ctx, &store.FindMemo{ID: &id})
+		require.NoError(t, err)
+		return memo
+	}
+	parent, memo := createMemo("parent"), createMemo("comment")
+
+	// The first lookup caches the memo; mutating a copy does not reach the cache.
+	getMemo(memo.ID).Content = "mutated"
+	require.Equal(t, "comment", getMemo(memo.ID).Content)
+	// A write behind the store's back goes unseen, since lookups are served from the cache.
+	behind := "written to the driver"
+	require.NoError(t, ts.GetDriver().UpdateMemo(ctx, &store.UpdateMemo{ID: memo.ID, Content: &behind}))
+	require.Equal(t, "comment", getMemo(memo.ID).Content)
+
+	content := "edited"
+	require.NoError(t, ts.UpdateMemo(ctx, &store.UpdateMemo{ID: memo.ID, Content: &content}))
+	require.Equal(t, "edited", getMemo(memo.ID).Content)
+
+	// ParentID is composed from the relations, which invalidate the memo too.
+	_, err = ts.UpsertMemoRelation(ctx, &store.MemoRelation{MemoID: memo.ID, RelatedMemoID: parent.ID, Type: store.MemoRelationComment})
+	require.NoError(t, err)
+	require.NotNil(t, getMemo(memo.ID).ParentID)
+	require.Equal(t, parent.ID, *getMemo(memo.ID).ParentID)
+	commentType := store.MemoRelationComment
+	require.NoError(t, ts.DeleteMemoRelation(ctx, &store.DeleteMemoRelation{RelatedMemoID: &parent.ID, Type: &commentType}))
+	require.Nil(t, getMemo(memo.ID).ParentID)
+
+	require.NoError(t, ts.DeleteMemo(ctx, &store.DeleteMemo{ID: memo.ID}))
+	require.Nil(t, getMemo(memo.ID))
+}
@@ -0,0 +1,177 @@
+package teststore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	storepb "github.com/usememos/memos/proto/gen/store"
+	"github.com/usememos/memos/store"
+	"github.com/usememos/memos/store/cache"
+)
+
+// memoListerStub is an in-memory MemoLister that counts listings.
+type memoListerStub struct {
+	memos map[int32]*store.Memo
+	lists int
+}
+
+func (s *memoListerStub) ListMemos(_ context.Context, find *store.FindMemo) ([]*store.Memo, error) {
+	s.lists++
+	var memos []*store.Memo
+	for _, memo := range s.memos {
+		if *find.CreatorID == memo.CreatorID && *find.RowStatus == memo.RowStatus {
+			memos = append(memos, memo)
+		}
+	}
+	return memos, nil
+}
+
+// recount computes the tag counts of a user from scratch.
+func (s *memoListerStub) recount(userID int32) map[string]int32 {
+	counts := map[string]int32{}
+	for _, memo := range s.memos {
+		if memo.CreatorID == userID && memo.RowStatus == store.Normal && memo.Payload != nil {
+			for _, tag := range memo.Payload.Tags {
+				counts[tag]++
+			}
+		}
+	}
+	return counts
+}
+
+func newTaggedMemo(id, creatorID int32, tags ...string) *store.Memo {
+	return &store.Memo{
+		ID:        id,
+		CreatorID: creatorID,
+		RowStatus: store.Normal,
+		Payload:   &storepb.MemoPayload{Tags: tags},
+	}
+}
+
+func TestTagCacheIncrementalUpdates(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoListerStub{memos: map[int32]*store.Memo{
+		1: newTaggedMemo(1, 100, "work", "ideas"),
+		2: newTaggedMemo(2, 100, "work"),
+		3: newTaggedMemo(3, 200, "personal"),
+	}}
+	tagCache := cache.NewDefault()
+	defer tagCache.Close()
+	tags := store.NewTagCache(stub, tagCache)
+
+	counts, err := tags.GetTagCount(ctx, 100)
+	require.NoError(t, err)
+	require.Equal(t, stub.recount(100), counts)
+	require.Equal(t, 1, stub.lists)
+
+	// Create.
+	stub.memos[4] = newTaggedMemo(4, 100, "ideas", "reading")
+	tags.MemoCreated(ctx, stub.memos[4])
+	// Edit tags.
+	old := stub.memos[1]
+	stub.memos[1] = newTaggedMemo(1, 100, "work", "reading")
+	tags.MemoUpdated(ctx, old, stub.memos[1])
+	// Archive.
+	old = stub.memos[2]
+	archived := *old
+	archived.RowStatus = store.Archived
+	stub.memos[2] = &archived
+	tags.MemoUpdated(ctx, old, &archived)
+	// Delete.
+	deleted := stub.memos[4]
+	delete(stub.memos, 4)
+	tags.MemoDeleted(ctx, deleted)
+
+	counts, err = tags.GetTagCount(ctx, 100)
+	require.NoError(t, err)
+	require.Equal(t, stub.recount(100), counts)
+	require.Equal(t, map[string]int32{"work": 1, "reading": 1}, counts)
+	require.Equal(t, 1, stub.lists, "updates should not trigger a recompute")
+
+	// Other users are unaffected.
+	counts, err = tags.GetTagCount(ctx, 200)
+	require.NoError(t, err)
+	require.Equal(t, stub.recount(200), counts)
+}
+
+func TestTagCacheRecomputesOnDrift(t *testing.T) {
+	ctx := context.Background()
+	stub := &memoListerStub{memos: map[int32]*store.Memo{1: newTaggedMemo(1, 100, "work")}}
+	tagCache := cache.NewDefault()
+	defer tagCache.Close()
+	tags := store.NewTagCache(stub, tagCache)
+
+	_, err := tags.GetTagCount(ctx, 100)
+	require.NoError(t, err)
+
+	// Deleting a memo the cache never counted would drive a count negative.
+	tags.MemoDeleted(ctx, newTaggedMemo(2, 100, "travel"))
+
+	counts, err := tags.GetTagCount(ctx, 100)
+	require.NoError(t, err)
+	require.Equal(t, stub.recount(100), counts)
+	require.Equal(t, 2, stub.lists)
+
+	// Callers own the returned map.
+	counts["work"] = 42
+	counts, err = tags.GetTagCount(ctx, 100)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), counts["work"])
+}
+
+// recountStore computes the tag counts of a user from the memos in the store.
+func recountStore(ctx context.Context, t *testing.T, ts *store.Store, userID int32) map[string]int32 {
+	normalStatus := store.Normal
+	memos, err := ts.ListMemos(ctx, &store.FindMemo{CreatorID: &userID, RowStatus: &normalStatus})
+	require.NoError(t, err)
+	counts := map[string]int32{}
+	for _, memo := range memos {
+		for _, tag := range memo.Payload.GetTags() {
+			counts[tag]++
+		}
+	}
+	return counts
+}
+
+func TestStoreTagCount(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestingStore(ctx, t)
+	defer ts.Close()
+	user, err := createTestingHostUser(ctx, ts)
+	require.NoError(t, err)
+
+	createMemo := func(uid string, tags ...string) *store.Memo {
+		memo, err := ts.CreateMemo(ctx, &store.Memo{
+			UID:        uid,
+			CreatorID:  user.ID,
+			Content:    "content",
+			Visibility: store.Public,
+			Payload:    &storepb.MemoPayload{Tags: tags},
+		})
+		require.NoError(t, err)
+		return memo
+	}
+	first := createMemo("first", "work", "ideas")
+	second := createMemo("second", "work")
+
+	// Cache the counts, so that every later read sees the store's updates to them.
+	counts, err := ts.GetTagCount(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, map[string]int32{"work": 2, "ideas": 1}, counts)
+
+	third := createMemo("third", "ideas", "reading")
+	require.NoError(t, ts.UpdateMemo(ctx, &store.UpdateMemo{
+		ID:      first.ID,
+		Payload: &storepb.MemoPayload{Tags: []string{"work", "reading"}},
+	}))
+	archived := store.Archived
+	require.NoError(t, ts.UpdateMemo(ctx, &store.UpdateMemo{ID: second.ID, RowStatus: &archived}))
+	require.NoError(t, ts.DeleteMemo(ctx, &store.DeleteMemo{ID: third.ID}))
+
+	counts, err = ts.GetTagCount(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, recountStore(ctx, t, ts, user.ID), counts)
+	require.Equal(t, map[string]int32{"work": 1, "reading": 1}, counts)
+}